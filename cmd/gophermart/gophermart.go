@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
 	"os"
@@ -19,17 +21,31 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	accrual2 "github.com/sviatilnik/gophermart/internal/application/accrual"
 	"github.com/sviatilnik/gophermart/internal/application/auth"
+	"github.com/sviatilnik/gophermart/internal/application/ingestion"
+	"github.com/sviatilnik/gophermart/internal/application/leader"
 	"github.com/sviatilnik/gophermart/internal/application/order"
+	"github.com/sviatilnik/gophermart/internal/application/outbox"
+	"github.com/sviatilnik/gophermart/internal/application/reconciliation"
 	"github.com/sviatilnik/gophermart/internal/application/wallet"
+	"github.com/sviatilnik/gophermart/internal/application/webhook"
+	accrualDomain "github.com/sviatilnik/gophermart/internal/domain/accrual"
+	orderDomain "github.com/sviatilnik/gophermart/internal/domain/order"
 	configInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/config"
 	"github.com/sviatilnik/gophermart/internal/infrastructure/events"
 	"github.com/sviatilnik/gophermart/internal/infrastructure/http/handlers"
 	middlewareInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/http/middleware"
 	accrual4 "github.com/sviatilnik/gophermart/internal/infrastructure/persistence/accrual"
 	authInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/persistence/auth"
+	ingestionInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/persistence/ingestion"
+	leaderInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/persistence/leader"
 	orderInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/persistence/order"
+	outboxInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/persistence/outbox"
+	reconciliationInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/persistence/reconciliation"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/transaction"
 	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/user"
 	walletInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/persistence/wallet"
+	webhookInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/persistence/webhook"
+	accrualService "github.com/sviatilnik/gophermart/internal/infrastructure/services/accrual"
 	"github.com/sviatilnik/gophermart/internal/infrastructure/services/jwt"
 	"go.uber.org/zap"
 )
@@ -37,6 +53,9 @@ import (
 func main() {
 	logger := getLogger()
 	conf := getConfig()
+	if err := conf.Validate(); err != nil {
+		logger.Fatal(err)
+	}
 
 	db := createDBConnection(logger, conf)
 
@@ -45,6 +64,7 @@ func main() {
 		middlewareInfrastructure.GZIPCompress,
 		middleware.Logger,
 		middleware.RequestID,
+		middlewareInfrastructure.Trace,
 		middleware.RealIP,
 	)
 
@@ -54,12 +74,24 @@ func main() {
 	)
 	defer stop()
 
-	eventBus := events.NewInMemoryEventBus(logger)
+	eventRegistry := events.NewDomainRegistry()
+	eventBus := events.NewOutboxBus(db, events.NewPostgresOutboxRepository(db), eventRegistry, logger)
+	eventBus.Use(
+		events.Logging(),
+		events.Metrics(events.NewHandlerMetrics("event_handlers")),
+		events.Retry(events.RetryPolicy{
+			Attempts: conf.EventHandlerRetryAttempts,
+			Backoff:  conf.EventHandlerRetryBackoff,
+		}),
+		events.Timeout(conf.EventHandlerTimeout),
+		events.Recover(),
+	)
+	transactor := transaction.NewPostgresTransactor(db)
 
 	tokenGenerator := jwt.NewJWTGenerator(conf.AccessTokenSecret)
 	refreshTokenRepo := authInfrastructure.NewRefreshTokenPostgresRepository(db)
 	userRepo := user.NewPostgresUserRepository(db)
-	regService := auth.NewRegistrationService(userRepo, eventBus)
+	regService := auth.NewRegistrationService(userRepo, eventBus, transactor)
 	authService := auth.NewAuthService(userRepo, refreshTokenRepo, tokenGenerator)
 	r.Post("/api/user/register", handlers.NewRegistrationHandler(regService, authService, logger).Register)
 
@@ -67,35 +99,246 @@ func main() {
 	r.Post("/api/user/login", authHandler.Login)
 	r.Post("/api/user/login/refresh", authHandler.LoginByRefreshToken)
 
+	accRepo := accrual4.NewPostgresRepository(db)
+
+	var (
+		rewardRules []accrualDomain.RewardRule
+		promotions  []accrualDomain.Promotion
+		tiers       []accrualDomain.Tier
+	)
+	err := errors.Join(
+		decodeJSONSetting(conf.AccrualRewardRules, &rewardRules),
+		decodeJSONSetting(conf.AccrualPromotions, &promotions),
+		decodeJSONSetting(conf.AccrualTiers, &tiers),
+	)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	calculator := accrualDomain.NewCalculator(rewardRules, promotions, tiers, accRepo)
+
+	orderRepo := orderInfrastructure.NewOrderPostgresRepository(db)
+	// схемы проверки номеров по источникам, например "merchant-a=length:12-16,prefix:42,luhn"
+	numberSchemes, err := orderDomain.ParseNumberSchemes(conf.OrderNumberSchemes)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	merchantKeys, err := handlers.ParseMerchantKeys(conf.OrderMerchantKeys)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	orderService := order.NewOrderService(orderRepo, userRepo, accRepo, eventBus, transactor, numberSchemes, conf.OrderBatchMaxSize)
+	if err = order.RegisterEventHandlers(eventBus, orderService); err != nil {
+		logger.Fatal(err)
+	}
+
+	walletRepo := walletInfrastructure.NewWalletPostgresRepository(db)
+	walletService := wallet.NewWalletService(walletRepo, eventBus, transactor, numberSchemes)
+	if err = wallet.RegisterEventHandlers(eventBus, walletService); err != nil {
+		logger.Fatal(err)
+	}
+
+	webhookRepo := webhookInfrastructure.NewPostgresRepository(db)
+	webhookService := webhook.NewService(webhookRepo, eventRegistry)
+	if err = webhook.RegisterEventHandlers(eventBus, webhookService); err != nil {
+		logger.Fatal(err)
+	}
+
 	r.Group(func(authRouter chi.Router) {
 		authRouter.Use(middlewareInfrastructure.NewAuthMiddleware(jwt.NewVerifier(conf.AccessTokenSecret)).Handle)
 
-		accRepo := accrual4.NewPostgresRepository(db)
-		orderRepo := orderInfrastructure.NewOrderPostgresRepository(db)
-		orderService := order.NewOrderService(orderRepo, userRepo, accRepo)
-		orderHandler := handlers.NewOrderHandler(orderService)
-		order.RegisterEventHandlers(eventBus, orderService)
-
+		orderHandler := handlers.NewOrderHandler(orderService, merchantKeys, conf.OrderBatchMaxSize)
 		authRouter.Post("/api/user/orders", orderHandler.Create)
+		authRouter.Post("/api/user/orders/batch", orderHandler.CreateBatch)
 		authRouter.Get("/api/user/orders", orderHandler.GetList)
+		authRouter.Post("/api/user/orders/preview", handlers.NewAccrualPreviewHandler(accrual2.NewPreviewService(calculator)).Preview)
+		authRouter.Get("/api/user/orders/{number}", orderHandler.Get)
+		authRouter.Post("/api/user/orders/{number}/cancel", orderHandler.Cancel)
 
-		walletRepo := walletInfrastructure.NewWalletPostgresRepository(db)
-		walletService := wallet.NewWalletService(walletRepo, eventBus)
 		walletHandler := handlers.NewWalletHandler(walletService)
-		wallet.RegisterEventHandlers(eventBus, walletService)
-
 		authRouter.Get("/api/user/balance", walletHandler.Balance)
 		authRouter.Post("/api/user/balance/withdraw", walletHandler.Withdraw)
 		authRouter.Get("/api/user/withdrawals", walletHandler.Withdrawals)
+	})
+
+	reviewRepo := accrual4.NewReviewPostgresRepository(db)
+	anomalyDetector := accrualDomain.NewAnomalyDetector(
+		accrualDomain.NewTransitionRule(),
+		accrualDomain.NewMaxAmountRule(conf.AnomalyMaxAmount),
+		accrualDomain.NewCustomerHistoryRule(accRepo, conf.AnomalyCustomerFactor, conf.AnomalyMinHistorySize),
+		accrualDomain.NewProviderHistoryRule(accRepo, conf.AnomalyProviderFactor, conf.AnomalyMinHistorySize),
+	)
+
+	accrualClient, err := accrualService.NewClient(accrualService.ClientConfig{
+		BaseURL:      conf.AccrualSystemAddress,
+		APIKeyHeader: conf.AccrualAPIKeyHeader,
+		APIKey:       conf.AccrualAPIKey,
+		HMACKeyID:    conf.AccrualHMACKeyID,
+		HMACSecret:   conf.AccrualHMACSecret,
+		CertFile:     conf.AccrualTLSCertFile,
+		KeyFile:      conf.AccrualTLSKeyFile,
+		CAFile:       conf.AccrualTLSCAFile,
+	})
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	accrual := accrual2.NewService(
+		conf.AccrualSystemAddress,
+		accrualClient,
+		conf.AccrualRegisterOrders,
+		accRepo,
+		reviewRepo,
+		anomalyDetector,
+		calculator,
+		orderService,
+		eventBus,
+		transactor,
+		logger)
+	if err = accrual2.RegisterEventHandlers(eventBus, accrual); err != nil {
+		logger.Fatal(err)
+	}
+
+	// событие без схемы нельзя ни записать в outbox, ни доставить, поэтому не стартуем вовсе
+	if err = eventRegistry.Check(append(events.PublishedEvents(), eventBus.Events()...)...); err != nil {
+		logger.Fatal(err)
+	}
 
-		accrual := accrual2.NewService(
-			conf.AccrualSystemAddress,
-			accRepo,
+	reconciliationService := reconciliation.NewService(
+		reconciliationInfrastructure.NewPostgresRepository(db),
+		orderService,
+		walletService,
+		transactor,
+		conf.ReconcileStuckAfter,
+		conf.ReconcileAutoRepair,
+		logger)
+
+	// фоновые задачи выполняются только на реплике-лидере
+	elector, err := leader.NewElector(
+		leaderInfrastructure.NewPostgresRepository(db),
+		"gophermart-jobs",
+		conf.InstanceID,
+		conf.LeaderLeaseTTL,
+		conf.LeaderRenewInterval,
+		logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	elector.Register("accrual-poller", accrual.GetAccruals)
+	elector.Register("reconciliation", func(ctx context.Context) {
+		reconciliationService.Start(ctx, conf.ReconcileInterval)
+	})
+
+	if conf.IngestDir != "" {
+		ingestionService := ingestion.NewService(
+			conf.IngestDir,
+			ingestionInfrastructure.NewPostgresRepository(db),
+			userRepo,
 			orderService,
-			eventBus,
 			logger)
+		elector.Register("order-ingestion", func(ctx context.Context) {
+			ingestionService.Start(ctx, conf.IngestInterval)
+		})
+	}
 
-		go accrual.GetAccruals(ctx)
+	dispatcher := events.NewDispatcher(
+		events.NewPostgresOutboxRepository(db),
+		eventBus,
+		eventRegistry,
+		events.DispatcherConfig{
+			BatchSize:    conf.OutboxBatchSize,
+			Partitions:   conf.OutboxPartitions,
+			QueueSize:    conf.OutboxQueueSize,
+			PollInterval: conf.OutboxPollInterval,
+			Lease:        time.Minute,
+			BaseBackoff:  time.Second,
+			MaxBackoff:   conf.OutboxMaxBackoff,
+		},
+		logger)
+
+	// внешние приёмники получают сырой поток событий, например для хранилища аналитики
+	sinks := make([]events.EventSink, 0)
+	if conf.EventSinkDir != "" {
+		fileSink, err := events.NewFileSink("events", conf.EventSinkDir, int64(conf.EventSinkMaxFileSize))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		sinks = append(sinks, fileSink)
+	}
+	if conf.EventSinkStdout {
+		sinks = append(sinks, events.NewStdoutSink())
+	}
+	for _, sink := range sinks {
+		defer sink.Close()
+		dispatcher.AddSink(events.NewSinkExporter(
+			sink,
+			events.NewPostgresOutboxRepository(db),
+			events.NewPostgresSinkCheckpoints(db),
+			eventRegistry,
+			events.SinkExporterConfig{BatchSize: conf.OutboxBatchSize},
+			logger))
+	}
+	elector.Register("outbox-dispatcher", dispatcher.Run)
+
+	deliverer := webhook.NewDeliverer(
+		webhookRepo,
+		&http.Client{Timeout: conf.WebhookTimeout},
+		webhook.DelivererConfig{
+			BatchSize:    50,
+			PollInterval: conf.WebhookPollInterval,
+			Lease:        conf.WebhookTimeout + time.Minute,
+			MaxAttempts:  conf.WebhookMaxAttempts,
+			BaseBackoff:  10 * time.Second,
+			MaxBackoff:   conf.WebhookMaxBackoff,
+			DisableAfter: conf.WebhookDisableAfter,
+		},
+		logger)
+	elector.Register("webhook-deliverer", deliverer.Run)
+
+	electorDone := make(chan struct{})
+	go func() {
+		defer close(electorDone)
+		elector.Run(ctx)
+	}()
+
+	r.Get("/api/status/leader", handlers.NewStatusHandler(elector).Leader)
+
+	r.Group(func(adminRouter chi.Router) {
+		adminRouter.Use(middlewareInfrastructure.NewAdminMiddleware(conf.AdminToken).Handle)
+
+		reconciliationHandler := handlers.NewReconciliationHandler(reconciliationService)
+		adminRouter.Get("/api/admin/reconciliation/discrepancies", reconciliationHandler.List)
+		adminRouter.Post("/api/admin/reconciliation/discrepancies/{id}/repair", reconciliationHandler.Repair)
+		adminRouter.Post("/api/admin/reconciliation/run", reconciliationHandler.Run)
+
+		adminOrderHandler := handlers.NewOrderHandler(orderService, nil, conf.OrderBatchMaxSize)
+		adminRouter.Post("/api/admin/orders/{number}/cancel", adminOrderHandler.ForceCancel)
+		adminRouter.Get("/api/admin/orders/{number}/history", adminOrderHandler.History)
+
+		reviewHandler := handlers.NewAccrualReviewHandler(accrual2.NewReviewService(reviewRepo, accRepo, eventBus, transactor))
+		adminRouter.Get("/api/admin/accrual/reviews", reviewHandler.List)
+		adminRouter.Post("/api/admin/accrual/reviews/{id}/approve", reviewHandler.Approve)
+		adminRouter.Post("/api/admin/accrual/reviews/{id}/reject", reviewHandler.Reject)
+
+		adminRouter.Get("/api/admin/debug/vars", expvar.Handler().ServeHTTP)
+
+		deadLetterHandler := handlers.NewDeadLetterHandler(outbox.NewDeadLetterService(outboxInfrastructure.NewDeadLetterPostgresRepository(db)))
+		adminRouter.Get("/api/admin/outbox/dead-letters", deadLetterHandler.List)
+		adminRouter.Post("/api/admin/outbox/dead-letters/replay", deadLetterHandler.ReplayBulk)
+		adminRouter.Post("/api/admin/outbox/dead-letters/discard", deadLetterHandler.DiscardBulk)
+		adminRouter.Get("/api/admin/outbox/dead-letters/{id}", deadLetterHandler.Get)
+		adminRouter.Put("/api/admin/outbox/dead-letters/{id}/headers", deadLetterHandler.UpdateHeaders)
+		adminRouter.Post("/api/admin/outbox/dead-letters/{id}/replay", deadLetterHandler.Replay)
+		adminRouter.Post("/api/admin/outbox/dead-letters/{id}/discard", deadLetterHandler.Discard)
+
+		webhookHandler := handlers.NewWebhookHandler(webhookService)
+		adminRouter.Post("/api/admin/webhooks", webhookHandler.Create)
+		adminRouter.Get("/api/admin/webhooks", webhookHandler.List)
+		adminRouter.Get("/api/admin/webhooks/{id}", webhookHandler.Get)
+		adminRouter.Delete("/api/admin/webhooks/{id}", webhookHandler.Delete)
+		adminRouter.Post("/api/admin/webhooks/{id}/enable", webhookHandler.Enable)
+		adminRouter.Get("/api/admin/webhooks/{id}/deliveries", webhookHandler.Deliveries)
 	})
 
 	server := &http.Server{
@@ -122,6 +365,12 @@ func main() {
 		logger.Fatal(err.Error())
 	}
 
+	// дожидаемся остановки фоновых задач и освобождения аренды лидера
+	select {
+	case <-electorDone:
+	case <-shutdownCtx.Done():
+	}
+
 }
 
 func getConfig() configInfrastructure.Config {
@@ -176,3 +425,12 @@ func createDBConnection(logger *zap.SugaredLogger, conf configInfrastructure.Con
 
 	return db
 }
+
+// decodeJSONSetting разбирает настройку в формате JSON; пустая настройка оставляет значение как есть
+func decodeJSONSetting(value string, target interface{}) error {
+	if value == "" {
+		return nil
+	}
+
+	return json.Unmarshal([]byte(value), target)
+}
@@ -0,0 +1,175 @@
+// outboxctl - разбор событий outbox, которые не удалось доставить подписчикам.
+//
+//	outboxctl [-d dsn] list [-type T] [-from RFC3339] [-to RFC3339] [-error S] [-limit N] [-offset N]
+//	outboxctl [-d dsn] show ID
+//	outboxctl [-d dsn] set-headers ID '{"key":"value"}'
+//	outboxctl [-d dsn] replay  [-type T] [-from RFC3339] [-to RFC3339] [-error S] [ID...]
+//	outboxctl [-d dsn] discard [-type T] [-from RFC3339] [-to RFC3339] [-error S] [ID...]
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/sviatilnik/gophermart/internal/application/outbox"
+	configInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/config"
+	outboxInfrastructure "github.com/sviatilnik/gophermart/internal/infrastructure/persistence/outbox"
+)
+
+var errUsage = errors.New("usage: outboxctl [-d dsn] list|show|set-headers|replay|discard [args]")
+
+func main() {
+	conf := configInfrastructure.NewConfig(
+		configInfrastructure.NewDefaultProvider(),
+		configInfrastructure.NewEnvProvider(configInfrastructure.NewOSEnvGetter()),
+	)
+
+	dsn := flag.String("d", conf.DatabaseDSN, "Адрес подключения к базе данных")
+	flag.Parse()
+
+	db, err := sql.Open("pgx", *dsn)
+	if err != nil {
+		fail(err)
+	}
+	defer db.Close()
+
+	service := outbox.NewDeadLetterService(outboxInfrastructure.NewDeadLetterPostgresRepository(db))
+
+	err = run(context.Background(), service, flag.Args())
+	if err != nil {
+		fail(err)
+	}
+}
+
+func run(ctx context.Context, service *outbox.DeadLetterService, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "list":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		filter := filterFlags(flags)
+		limit := flags.Int("limit", 100, "Сколько событий показать")
+		offset := flags.Int("offset", 0, "Сколько событий пропустить")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+
+		deadLetters, err := service.List(ctx, *filter, *limit, *offset)
+		if err != nil {
+			return err
+		}
+
+		return printJSON(deadLetters)
+	case "show":
+		if len(args) != 1 {
+			return errUsage
+		}
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return err
+		}
+
+		deadLetter, err := service.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return printJSON(deadLetter)
+	case "set-headers":
+		if len(args) != 2 {
+			return errUsage
+		}
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return err
+		}
+
+		var headers map[string]any
+		err = json.Unmarshal([]byte(args[1]), &headers)
+		if err != nil {
+			return err
+		}
+
+		deadLetter, err := service.UpdateHeaders(ctx, id, headers)
+		if err != nil {
+			return err
+		}
+
+		return printJSON(deadLetter)
+	case "replay", "discard":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		filter := filterFlags(flags)
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+
+		for _, arg := range flags.Args() {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return err
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+
+		apply := service.Replay
+		if command == "discard" {
+			apply = service.Discard
+		}
+
+		result, err := apply(ctx, *filter)
+		if err != nil {
+			return err
+		}
+
+		return printJSON(result)
+	default:
+		return errUsage
+	}
+}
+
+func filterFlags(flags *flag.FlagSet) *outbox.DeadLetterFilterDTO {
+	filter := &outbox.DeadLetterFilterDTO{}
+	flags.StringVar(&filter.EventType, "type", "", "Тип события")
+	flags.StringVar(&filter.Error, "error", "", "Подстрока в истории ошибок")
+	flags.Func("from", "Начало периода (RFC3339)", timeFlag(&filter.From))
+	flags.Func("to", "Конец периода, не включается (RFC3339)", timeFlag(&filter.To))
+
+	return filter
+}
+
+func timeFlag(target **time.Time) func(string) error {
+	return func(value string) error {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return err
+		}
+
+		*target = &t
+		return nil
+	}
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
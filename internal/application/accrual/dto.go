@@ -0,0 +1,52 @@
+package accrual
+
+import "time"
+
+// ReviewDTO - DTO начисления, ожидающего ручной проверки
+type ReviewDTO struct {
+	ID            string     `json:"id"`
+	OrderNumber   string     `json:"order"`
+	CustomerID    string     `json:"customer_id"`
+	ProviderState string     `json:"provider_status"`
+	Amount        float64    `json:"accrual"`
+	Reasons       []string   `json:"reasons"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DecidedAt     *time.Time `json:"decided_at,omitempty"`
+	DecidedBy     string     `json:"decided_by,omitempty"`
+	Comment       string     `json:"comment,omitempty"`
+}
+
+// ReviewDecisionDTO - DTO решения по начислению
+type ReviewDecisionDTO struct {
+	Reviewer string `json:"reviewer"`
+	Comment  string `json:"comment"`
+}
+
+// PreviewItemDTO - DTO позиции чека для предварительного расчёта
+type PreviewItemDTO struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+// PreviewRequestDTO - DTO запроса предварительного расчёта начисления
+type PreviewRequestDTO struct {
+	Items []PreviewItemDTO `json:"items"`
+}
+
+// PreviewItemResultDTO - DTO расчёта по одной позиции
+type PreviewItemResultDTO struct {
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	Rule      string  `json:"rule,omitempty"`
+	Base      float64 `json:"base"`
+	Promotion string  `json:"promotion,omitempty"`
+	Accrual   float64 `json:"accrual"`
+}
+
+// PreviewDTO - DTO ожидаемого начисления
+type PreviewDTO struct {
+	Accrual float64                 `json:"accrual"`
+	Tier    string                  `json:"tier,omitempty"`
+	Items   []*PreviewItemResultDTO `json:"items"`
+}
@@ -0,0 +1,14 @@
+package accrual
+
+import (
+	"errors"
+
+	accrualDomain "github.com/sviatilnik/gophermart/internal/domain/accrual"
+)
+
+var (
+	ErrEmptyPreview         = errors.New("no items to preview")
+	ErrInvalidPreviewItem   = errors.New("preview item must have a name and non-negative price")
+	ErrReviewNotFound       = accrualDomain.ErrReviewNotFound
+	ErrReviewAlreadyDecided = accrualDomain.ErrReviewAlreadyDecided
+)
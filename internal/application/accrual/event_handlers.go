@@ -0,0 +1,49 @@
+package accrual
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/application/order"
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+	orderDomain "github.com/sviatilnik/gophermart/internal/domain/order"
+	"go.uber.org/zap"
+)
+
+func RegisterEventHandlers(bus events.Bus, accrualService *Service) error {
+	uploaded := bus.Subscribe("order.uploaded", "accrual.enqueue", func(ctx context.Context, e events.Event, logger *zap.SugaredLogger) error {
+		event := e.(*orderDomain.Uploaded)
+
+		// без регистрации заказ всё равно проверяем: система начислений может знать его из другого источника
+		err := accrualService.RegisterOrder(ctx, event.OrderNumber, event.Items)
+		if err != nil {
+			logger.Warnw("accrual: order registration failed", "order", event.OrderNumber, "error", err)
+		}
+
+		enqueueUploaded(accrualService, event, logger)
+		return nil
+	})
+
+	batch := bus.Subscribe("order.batch_uploaded", "accrual.enqueue", func(ctx context.Context, e events.Event, logger *zap.SugaredLogger) error {
+		for _, uploaded := range e.(*orderDomain.BatchUploaded).Orders {
+			enqueueUploaded(accrualService, uploaded, logger)
+		}
+		return nil
+	})
+
+	return errors.Join(uploaded, batch)
+}
+
+func enqueueUploaded(accrualService *Service, event *orderDomain.Uploaded, logger *zap.SugaredLogger) {
+	queued := accrualService.Enqueue(&order.OrderDTO{
+		OrderID:    event.OrderID,
+		Status:     string(orderDomain.New),
+		Number:     event.OrderNumber,
+		UploadedAt: event.UploadedAt.UTC().Format(time.RFC3339),
+		CustomerID: event.CustomerID,
+	})
+	if !queued {
+		logger.Warnw("accrual: queue is full, order left for periodic polling", "order", event.OrderNumber)
+	}
+}
@@ -0,0 +1,56 @@
+package accrual
+
+import (
+	"context"
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+)
+
+// PreviewService оценивает начисление до загрузки заказа тем же калькулятором, что и реальные начисления
+type PreviewService struct {
+	calculator *accrual.Calculator
+}
+
+func NewPreviewService(calculator *accrual.Calculator) *PreviewService {
+	return &PreviewService{
+		calculator: calculator,
+	}
+}
+
+func (s *PreviewService) Preview(ctx context.Context, customerID string, req PreviewRequestDTO) (*PreviewDTO, error) {
+	if len(req.Items) == 0 {
+		return nil, ErrEmptyPreview
+	}
+
+	items := make([]accrual.Item, len(req.Items))
+	for i, item := range req.Items {
+		if item.Name == "" || item.Price < 0 {
+			return nil, ErrInvalidPreviewItem
+		}
+		items[i] = accrual.Item{Name: item.Name, Price: item.Price}
+	}
+
+	calc, err := s.calculator.Estimate(ctx, customerID, items, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &PreviewDTO{
+		Accrual: calc.Total,
+		Tier:    calc.Tier,
+		Items:   make([]*PreviewItemResultDTO, len(calc.Items)),
+	}
+	for i, line := range calc.Items {
+		preview.Items[i] = &PreviewItemResultDTO{
+			Name:      line.Name,
+			Price:     line.Price,
+			Rule:      line.Rule,
+			Base:      line.Base,
+			Promotion: line.Promotion,
+			Accrual:   line.Total,
+		}
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,68 @@
+package accrual
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/application/order"
+	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+	"go.uber.org/zap"
+)
+
+type stubStatsRepository struct {
+	accrual.Repository
+}
+
+func (r *stubStatsRepository) CustomerStats(_ context.Context, _ string) (*accrual.Stats, error) {
+	return &accrual.Stats{Count: 10, Average: 150}, nil
+}
+
+func TestPreview_MatchesRealAccrual(t *testing.T) {
+	now := time.Now()
+	calculator := accrual.NewCalculator(
+		[]accrual.RewardRule{
+			{Match: "Bork", Reward: 10, RewardType: accrual.RewardPercent},
+			{Match: "Чайник", Reward: 50, RewardType: accrual.RewardPoints},
+		},
+		[]accrual.Promotion{
+			{Name: "double-bork", Match: "bork", Multiplier: 2, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+		},
+		[]accrual.Tier{{Name: "silver", MinAccrued: 1000, Multiplier: 1.5}},
+		&stubStatsRepository{},
+	)
+
+	items := []order.LineItemDTO{
+		{Name: "Утюг Bork", Price: 1000},
+		{Name: "Чайник", Price: 3000},
+		{Name: "Хлеб", Price: 50},
+	}
+
+	previewItems := make([]PreviewItemDTO, len(items))
+	for i, item := range items {
+		previewItems[i] = PreviewItemDTO{Name: item.Name, Price: item.Price}
+	}
+
+	preview, err := NewPreviewService(calculator).Preview(context.Background(), "c1", PreviewRequestDTO{Items: previewItems})
+	require.NoError(t, err)
+
+	// система начислений считает по тем же правилам, что и оценка
+	var base float64
+	for _, item := range preview.Items {
+		base += item.Base
+	}
+
+	s := NewService("", nil, false, nil, nil, nil, calculator, nil, nil, nil, zap.NewNop().Sugar())
+	acc := &accrual.Accrual{OrderNumber: "79927398713", State: accrual.Processed, Amount: base}
+	err = s.adjust(context.Background(), &order.OrderDTO{
+		Number:     "79927398713",
+		CustomerID: "c1",
+		Metadata:   &order.OrderMetadataDTO{Items: items},
+	}, acc)
+	require.NoError(t, err)
+
+	assert.Equal(t, 375.0, preview.Accrual)
+	assert.Equal(t, preview.Accrual, acc.Amount)
+}
@@ -0,0 +1,127 @@
+package accrual
+
+import (
+	"context"
+
+	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/transaction"
+)
+
+type ReviewService struct {
+	reviews    accrual.ReviewRepository
+	repository accrual.Repository
+	eventBus   events.Bus
+	transactor transaction.Transactor
+}
+
+func NewReviewService(reviews accrual.ReviewRepository, repository accrual.Repository, eventBus events.Bus, transactor transaction.Transactor) *ReviewService {
+	return &ReviewService{
+		reviews:    reviews,
+		repository: repository,
+		eventBus:   eventBus,
+		transactor: transactor,
+	}
+}
+
+func (s *ReviewService) List(ctx context.Context, statuses []string, limit int, offset int) ([]*ReviewDTO, error) {
+	st := make([]accrual.ReviewStatus, len(statuses))
+	for i, status := range statuses {
+		st[i] = accrual.ReviewStatus(status)
+	}
+
+	reviews, err := s.reviews.List(ctx, st, uint64(limit), uint64(offset))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ReviewDTO, len(reviews))
+	for i, r := range reviews {
+		result[i] = toReviewDTO(r)
+	}
+
+	return result, nil
+}
+
+// Approve выпускает отложенное начисление в том виде, в котором его прислала система начислений
+func (s *ReviewService) Approve(ctx context.Context, id string, decision ReviewDecisionDTO) (*ReviewDTO, error) {
+	review, err := s.reviews.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = review.Approve(decision.Reviewer, decision.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.release(ctx, review, review.ProviderState, review.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return toReviewDTO(review), nil
+}
+
+// Reject отбрасывает начисление, заказ становится недействительным
+func (s *ReviewService) Reject(ctx context.Context, id string, decision ReviewDecisionDTO) (*ReviewDTO, error) {
+	review, err := s.reviews.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = review.Reject(decision.Reviewer, decision.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.release(ctx, review, accrual.Invalid, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return toReviewDTO(review), nil
+}
+
+// release сохраняет решение и выпускает начисление в одной транзакции.
+// Решение записывается первым, поэтому из двух одновременных решений начисление выпустит только одно.
+func (s *ReviewService) release(ctx context.Context, review *accrual.Review, state accrual.State, amount float64) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.reviews.Decide(ctx, review)
+		if err != nil {
+			return err
+		}
+
+		err = s.repository.Save(ctx, &accrual.Accrual{
+			OrderNumber: review.OrderNumber,
+			State:       state,
+			Amount:      amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.eventBus.Publish(ctx, &accrual.CreatedEvent{
+			OrderNumber: review.OrderNumber,
+			Amount:      amount,
+			Status:      string(state),
+			CustomerID:  review.CustomerID,
+		})
+	})
+}
+
+func toReviewDTO(r *accrual.Review) *ReviewDTO {
+	return &ReviewDTO{
+		ID:            r.ID,
+		OrderNumber:   r.OrderNumber,
+		CustomerID:    r.CustomerID,
+		ProviderState: string(r.ProviderState),
+		Amount:        r.Amount,
+		Reasons:       r.Reasons,
+		Status:        string(r.Status),
+		CreatedAt:     r.CreatedAt,
+		DecidedAt:     r.DecidedAt,
+		DecidedBy:     r.DecidedBy,
+		Comment:       r.Comment,
+	}
+}
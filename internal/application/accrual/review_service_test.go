@@ -0,0 +1,173 @@
+package accrual
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/application/order"
+	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+	"go.uber.org/zap"
+)
+
+// memoryReviewRepository повторяет условное обновление Postgres: решение записывается, только пока проверка в PENDING
+type memoryReviewRepository struct {
+	accrual.ReviewRepository
+	mu     sync.Mutex
+	review accrual.Review
+}
+
+func (r *memoryReviewRepository) Get(_ context.Context, id string) (*accrual.Review, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.review.ID != id {
+		return nil, accrual.ErrReviewNotFound
+	}
+
+	copied := r.review
+	return &copied, nil
+}
+
+func (r *memoryReviewRepository) Decide(_ context.Context, review *accrual.Review) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.review.Status != accrual.ReviewPending {
+		return accrual.ErrReviewAlreadyDecided
+	}
+
+	r.review = *review
+	return nil
+}
+
+type memoryAccrualRepository struct {
+	accrual.Repository
+	saved atomic.Int32
+}
+
+func (r *memoryAccrualRepository) Save(_ context.Context, _ *accrual.Accrual) error {
+	r.saved.Add(1)
+	return nil
+}
+
+type publishCounter struct {
+	events.Bus
+	published atomic.Int32
+}
+
+func (b *publishCounter) Publish(_ context.Context, _ events.Event) error {
+	b.published.Add(1)
+	return nil
+}
+
+type inlineTransactor struct{}
+
+func (inlineTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func TestReviewService_ConcurrentDecisions(t *testing.T) {
+	reviews := &memoryReviewRepository{review: accrual.Review{
+		ID:            "r1",
+		OrderNumber:   "79927398713",
+		CustomerID:    "c1",
+		ProviderState: accrual.Processed,
+		Amount:        5000,
+		Status:        accrual.ReviewPending,
+	}}
+	accruals := &memoryAccrualRepository{}
+	bus := &publishCounter{}
+	s := NewReviewService(reviews, accruals, bus, inlineTransactor{})
+
+	// все решения читают проверку до того, как какое-либо из них записано
+	var (
+		wg       sync.WaitGroup
+		start    = make(chan struct{})
+		accepted atomic.Int32
+		conflict atomic.Int32
+	)
+	decide := []func(ctx context.Context, id string, decision ReviewDecisionDTO) (*ReviewDTO, error){
+		s.Approve, s.Approve, s.Reject, s.Approve, s.Reject,
+	}
+	for _, fn := range decide {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			_, err := fn(context.Background(), "r1", ReviewDecisionDTO{Reviewer: "admin"})
+			switch {
+			case err == nil:
+				accepted.Add(1)
+			case errors.Is(err, ErrReviewAlreadyDecided):
+				conflict.Add(1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	require.Equal(t, int32(1), accepted.Load())
+	assert.Equal(t, int32(len(decide)-1), conflict.Load())
+	assert.Equal(t, int32(1), accruals.saved.Load())
+	assert.Equal(t, int32(1), bus.published.Load())
+}
+
+type txKey struct{}
+
+// markingTransactor помечает контекст транзакции, чтобы проверить, какие записи в неё попали
+type markingTransactor struct{}
+
+func (markingTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(context.WithValue(ctx, txKey{}, true))
+}
+
+// parkingRepository запоминает, какие записи сделаны внутри транзакции
+type parkingRepository struct {
+	accrual.Repository
+	inTx *[]bool
+}
+
+func (r *parkingRepository) Get(_ context.Context, _ string) (*accrual.Accrual, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *parkingRepository) Save(ctx context.Context, _ *accrual.Accrual) error {
+	*r.inTx = append(*r.inTx, ctx.Value(txKey{}) != nil)
+	return nil
+}
+
+type parkingReviews struct {
+	accrual.ReviewRepository
+	inTx *[]bool
+}
+
+func (r *parkingReviews) Save(ctx context.Context, _ *accrual.Review) error {
+	*r.inTx = append(*r.inTx, ctx.Value(txKey{}) != nil)
+	return nil
+}
+
+func TestService_ParkWithinTransaction(t *testing.T) {
+	var inTx []bool
+	detector := accrual.NewAnomalyDetector(accrual.NewMaxAmountRule(100))
+	s := NewService("", nil, false, &parkingRepository{inTx: &inTx}, &parkingReviews{inTx: &inTx}, detector, nil, nil, nil, markingTransactor{}, zap.NewNop().Sugar())
+
+	parked, err := s.park(context.Background(), &order.OrderDTO{CustomerID: "c1"}, &accrual.Accrual{
+		OrderNumber: "79927398713",
+		State:       accrual.Processed,
+		Amount:      500,
+	})
+	require.NoError(t, err)
+	assert.True(t, parked)
+	// отложенное начисление и проверка пишутся в одной транзакции
+	assert.Equal(t, []bool{true, true}, inTx)
+}
@@ -1,15 +1,22 @@
 package accrual
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/sviatilnik/gophermart/internal/application/order"
 	"github.com/sviatilnik/gophermart/internal/domain/accrual"
 	"github.com/sviatilnik/gophermart/internal/domain/events"
+	orderDomain "github.com/sviatilnik/gophermart/internal/domain/order"
+	"github.com/sviatilnik/gophermart/internal/domain/transaction"
 	"go.uber.org/zap"
 )
 
@@ -19,30 +26,130 @@ type checkOrderResponse struct {
 	Amount      float64 `json:"accrual"`
 }
 
+// queueSize - сколько загруженных заказов может ждать немедленной проверки
+const queueSize = 1000
+
+type registerOrderRequest struct {
+	OrderNumber string               `json:"order"`
+	Goods       []registerOrderGoods `json:"goods"`
+}
+
+type registerOrderGoods struct {
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+}
+
 type Service struct {
-	url          string
-	repository   accrual.Repository
-	orderService *order.Service
-	eventBus     events.Bus
-	logger       *zap.SugaredLogger
+	url            string
+	client         *http.Client
+	registerOrders bool
+	repository     accrual.Repository
+	reviews        accrual.ReviewRepository
+	detector       *accrual.AnomalyDetector
+	calculator     *accrual.Calculator
+	orderService   *order.Service
+	eventBus       events.Bus
+	transactor     transaction.Transactor
+	logger         *zap.SugaredLogger
+	queue          chan *order.OrderDTO
 }
 
-func NewService(url string, repository accrual.Repository, orderService *order.Service, eventBus events.Bus, logger *zap.SugaredLogger) *Service {
+func NewService(
+	url string,
+	client *http.Client,
+	registerOrders bool,
+	repository accrual.Repository,
+	reviews accrual.ReviewRepository,
+	detector *accrual.AnomalyDetector,
+	calculator *accrual.Calculator,
+	orderService *order.Service,
+	eventBus events.Bus,
+	transactor transaction.Transactor,
+	logger *zap.SugaredLogger,
+) *Service {
 	return &Service{
-		url:          url,
-		repository:   repository,
-		orderService: orderService,
-		eventBus:     eventBus,
-		logger:       logger,
+		url:            url,
+		client:         client,
+		registerOrders: registerOrders,
+		repository:     repository,
+		reviews:        reviews,
+		detector:       detector,
+		calculator:     calculator,
+		orderService:   orderService,
+		eventBus:       eventBus,
+		transactor:     transactor,
+		logger:         logger,
+		queue:          make(chan *order.OrderDTO, queueSize),
+	}
+}
+
+// Enqueue ставит заказ в очередь на немедленную проверку.
+// Если очередь переполнена, заказ будет проверен при следующем периодическом опросе.
+func (s *Service) Enqueue(o *order.OrderDTO) bool {
+	select {
+	case s.queue <- o:
+		return true
+	default:
+		return false
+	}
+}
+
+// RegisterOrder передаёт позиции чека системе начислений, если она поддерживает регистрацию заказов.
+// Уже зарегистрированный заказ (409) ошибкой не считается.
+func (s *Service) RegisterOrder(ctx context.Context, number string, items []orderDomain.LineItem) error {
+	if !s.registerOrders || len(items) == 0 {
+		return nil
+	}
+
+	req := registerOrderRequest{
+		OrderNumber: number,
+		Goods:       make([]registerOrderGoods, len(items)),
 	}
+	for i, item := range items {
+		req.Goods[i] = registerOrderGoods{Description: item.Name, Price: item.Price}
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url+"/api/orders", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		return fmt.Errorf("accrual: order registration failed: %s", resp.Status)
+	}
+
+	return nil
 }
 
 func (s *Service) GetAccruals(ctx context.Context) {
 	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			s.logger.Info("accrual: service shutting down")
+			return
+		case o := <-s.queue:
+			orders := s.filterUnprocessed(ctx, append([]*order.OrderDTO{o}, s.drainQueue()...))
+			if len(orders) == 0 {
+				continue
+			}
+
+			s.logger.Infow("accrual: checking uploaded orders", "count", len(orders))
+			s.checkOrders(ctx, orders)
 		case <-ticker.C:
 			s.logger.Info("accrual: starting ...")
 
@@ -57,28 +164,160 @@ func (s *Service) GetAccruals(ctx context.Context) {
 				continue
 			}
 
-			rl := NewRateLimiter()
-			var wg sync.WaitGroup
-
-			for _, o := range orders {
-				wg.Add(1)
-				go s.Worker(ctx, o, rl, s.url+"/api/orders/"+o.Number, &wg)
-			}
-
-			wg.Wait()
+			s.checkOrders(ctx, orders)
 			s.logger.Info("accrual: check done")
 			time.Sleep(1 * time.Second)
 		}
 	}
 }
 
-func (s *Service) Worker(ctx context.Context, o *order.OrderDTO, rl *RateLimiter, url string, wg *sync.WaitGroup) {
-	defer wg.Done()
+func (s *Service) checkOrders(ctx context.Context, orders []*order.OrderDTO) {
+	orders, err := s.skipUnderReview(ctx, orders)
+	if err != nil {
+		s.logger.Error("accrual: failed to fetch order accruals", zap.Error(err))
+		return
+	}
+
+	rl := NewRateLimiter()
+	var wg sync.WaitGroup
+
+	for _, o := range orders {
+		wg.Add(1)
+		go s.Worker(ctx, o, rl, s.url+"/api/orders/"+o.Number, &wg)
+	}
+
+	wg.Wait()
+}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+func (s *Service) drainQueue() []*order.OrderDTO {
+	orders := make([]*order.OrderDTO, 0)
+	for {
+		select {
+		case o := <-s.queue:
+			orders = append(orders, o)
+		default:
+			return orders
+		}
+	}
+}
+
+// skipUnderReview отбрасывает заказы, начисление по которым ждёт ручной проверки
+func (s *Service) skipUnderReview(ctx context.Context, orders []*order.OrderDTO) ([]*order.OrderDTO, error) {
+	numbers := make([]string, len(orders))
+	for i, o := range orders {
+		numbers[i] = o.Number
+	}
+
+	accruals, err := s.repository.GetForOrders(ctx, numbers)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*order.OrderDTO, 0, len(orders))
+	for _, o := range orders {
+		if a, has := accruals[o.Number]; has && a.State == accrual.PendingReview {
+			continue
+		}
+		result = append(result, o)
 	}
 
+	return result, nil
+}
+
+// adjust применяет к начислению системы начислений акции и уровень клиента по позициям чека заказа
+func (s *Service) adjust(ctx context.Context, o *order.OrderDTO, acc *accrual.Accrual) error {
+	if acc.State != accrual.Processed || acc.Amount == 0 {
+		return nil
+	}
+
+	var items []accrual.Item
+	if o.Metadata != nil {
+		for _, item := range o.Metadata.Items {
+			items = append(items, accrual.Item{Name: item.Name, Price: item.Price})
+		}
+	}
+
+	calc, err := s.calculator.Adjust(ctx, o.CustomerID, acc.Amount, items, time.Now())
+	if err != nil {
+		return err
+	}
+
+	acc.Amount = calc.Total
+
+	return nil
+}
+
+// park проверяет ответ системы начислений и, если он подозрительный,
+// откладывает начисление в очередь ручной проверки вместо зачисления баллов
+func (s *Service) park(ctx context.Context, o *order.OrderDTO, acc *accrual.Accrual) (bool, error) {
+	candidate := &accrual.Candidate{
+		OrderNumber: acc.OrderNumber,
+		CustomerID:  o.CustomerID,
+		State:       acc.State,
+		Amount:      acc.Amount,
+	}
+
+	previous, err := s.repository.Get(ctx, acc.OrderNumber)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	if previous != nil {
+		candidate.PreviousState = previous.State
+	}
+
+	reasons, err := s.detector.Detect(ctx, candidate)
+	if err != nil {
+		return false, err
+	}
+
+	if len(reasons) == 0 {
+		return false, nil
+	}
+
+	// без записи проверки отложенное начисление некому было бы освободить, поэтому пишем их вместе
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.repository.Save(ctx, &accrual.Accrual{
+			OrderNumber: acc.OrderNumber,
+			State:       accrual.PendingReview,
+			Amount:      acc.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.reviews.Save(ctx, accrual.NewReview(candidate, reasons))
+	})
+	if err != nil {
+		return false, err
+	}
+
+	s.logger.Warnw("accrual: parked for manual review", "order", acc.OrderNumber, "reasons", reasons)
+
+	return true, nil
+}
+
+// filterUnprocessed отбрасывает заказы, которые уже успел обработать периодический опрос
+func (s *Service) filterUnprocessed(ctx context.Context, queued []*order.OrderDTO) []*order.OrderDTO {
+	result := make([]*order.OrderDTO, 0, len(queued))
+	for _, q := range queued {
+		o, err := s.orderService.GetOrder(ctx, q.Number)
+		if err != nil {
+			s.logger.Error("accrual: failed to fetch queued order", zap.String("order", q.Number), zap.Error(err))
+			continue
+		}
+
+		// отменённые после постановки в очередь заказы в систему начислений не отправляем
+		if slices.Contains(orderDomain.AwaitingAccrual(), orderDomain.State(o.Status)) {
+			result = append(result, o)
+		}
+	}
+
+	return result
+}
+
+func (s *Service) Worker(ctx context.Context, o *order.OrderDTO, rl *RateLimiter, url string, wg *sync.WaitGroup) {
+	defer wg.Done()
+
 	retryCount := 5
 	for {
 		select {
@@ -96,7 +335,7 @@ func (s *Service) Worker(ctx context.Context, o *order.OrderDTO, rl *RateLimiter
 				continue
 			}
 
-			resp, err := client.Do(req)
+			resp, err := s.client.Do(req)
 			if err != nil {
 				time.Sleep(1 * time.Second)
 				continue
@@ -121,18 +360,39 @@ func (s *Service) Worker(ctx context.Context, o *order.OrderDTO, rl *RateLimiter
 					Amount:      jsonResponse.Amount,
 				}
 
-				err = s.repository.Save(ctx, acc)
+				err = s.adjust(ctx, o, acc)
 				if err != nil {
-					s.logger.Error("accrual: failed to save order accrual", zap.Error(err))
+					s.logger.Error("accrual: failed to apply promotions", zap.Error(err))
 					continue
 				}
 
-				s.eventBus.Publish(&accrual.CreatedEvent{
-					OrderNumber: acc.OrderNumber,
-					Amount:      acc.Amount,
-					Status:      string(acc.State),
-					CustomerID:  o.CustomerID,
+				parked, err := s.park(ctx, o, acc)
+				if err != nil {
+					s.logger.Error("accrual: failed to check order accrual", zap.Error(err))
+					continue
+				}
+
+				if parked {
+					return
+				}
+
+				err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					err := s.repository.Save(ctx, acc)
+					if err != nil {
+						return err
+					}
+
+					return s.eventBus.Publish(ctx, &accrual.CreatedEvent{
+						OrderNumber: acc.OrderNumber,
+						Amount:      acc.Amount,
+						Status:      string(acc.State),
+						CustomerID:  o.CustomerID,
+					})
 				})
+				if err != nil {
+					s.logger.Error("accrual: failed to save order accrual", zap.Error(err))
+					continue
+				}
 
 				return
 			}
@@ -0,0 +1,123 @@
+package accrual
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/application/order"
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+	orderDomain "github.com/sviatilnik/gophermart/internal/domain/order"
+	"go.uber.org/zap"
+)
+
+// stubOrderRepository отдаёт заказы из памяти, остальные методы не нужны очереди
+type stubOrderRepository struct {
+	orderDomain.Repository
+	orders map[orderDomain.Number]*orderDomain.Order
+}
+
+func (r *stubOrderRepository) Get(_ context.Context, number orderDomain.Number) (*orderDomain.Order, error) {
+	o, ok := r.orders[number]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return o, nil
+}
+
+// stubBus запоминает подписчиков, чтобы вызвать их напрямую
+type stubBus struct {
+	events.Bus
+	handlers map[string]events.Handler
+}
+
+func (b *stubBus) Subscribe(event string, _ string, handler events.Handler) error {
+	b.handlers[event] = handler
+	return nil
+}
+
+func newQueueService(orders ...*orderDomain.Order) *Service {
+	repo := &stubOrderRepository{orders: make(map[orderDomain.Number]*orderDomain.Order)}
+	for _, o := range orders {
+		repo.orders[o.Number] = o
+	}
+
+	orderService := order.NewOrderService(repo, nil, nil, nil, nil, orderDomain.NewNumberSchemes(), 0)
+
+	return NewService("", nil, false, nil, nil, nil, nil, orderService, nil, nil, zap.NewNop().Sugar())
+}
+
+func TestService_EnqueueWhenFull(t *testing.T) {
+	s := newQueueService()
+
+	for i := range queueSize {
+		require.True(t, s.Enqueue(&order.OrderDTO{Number: "79927398713"}), "order %d", i)
+	}
+
+	assert.False(t, s.Enqueue(&order.OrderDTO{Number: "12345678903"}))
+	assert.Len(t, s.drainQueue(), queueSize)
+	assert.True(t, s.Enqueue(&order.OrderDTO{Number: "12345678903"}))
+}
+
+func TestService_DrainAndFilterQueue(t *testing.T) {
+	s := newQueueService(
+		&orderDomain.Order{Number: "79927398713", CustomerID: "c1", State: orderDomain.New},
+		&orderDomain.Order{Number: "12345678903", CustomerID: "c1", State: orderDomain.Processed},
+		&orderDomain.Order{Number: "4111111111111111", CustomerID: "c2", State: orderDomain.Processing},
+		&orderDomain.Order{Number: "5555555555554444", CustomerID: "c2", State: orderDomain.Cancelled},
+	)
+
+	for _, number := range []string{"79927398713", "12345678903", "4111111111111111", "5555555555554444", "4012888888881881"} {
+		require.True(t, s.Enqueue(&order.OrderDTO{Number: number}))
+	}
+
+	queued := s.drainQueue()
+	require.Len(t, queued, 5)
+	assert.Empty(t, s.drainQueue())
+
+	orders := s.filterUnprocessed(context.Background(), queued)
+
+	numbers := make([]string, len(orders))
+	for i, o := range orders {
+		numbers[i] = o.Number
+	}
+	assert.Equal(t, []string{"79927398713", "4111111111111111"}, numbers)
+}
+
+func TestRegisterEventHandlers_EnqueueUploaded(t *testing.T) {
+	s := newQueueService()
+	bus := &stubBus{handlers: make(map[string]events.Handler)}
+	require.NoError(t, RegisterEventHandlers(bus, s))
+
+	uploadedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	logger := zap.NewNop().Sugar()
+
+	err := bus.handlers["order.uploaded"](context.Background(), &orderDomain.Uploaded{
+		OrderID: "o1", OrderNumber: "79927398713", CustomerID: "c1", UploadedAt: uploadedAt,
+	}, logger)
+	require.NoError(t, err)
+
+	err = bus.handlers["order.batch_uploaded"](context.Background(), &orderDomain.BatchUploaded{
+		Orders: []*orderDomain.Uploaded{
+			{OrderID: "o2", OrderNumber: "12345678903", CustomerID: "c1", UploadedAt: uploadedAt},
+			{OrderID: "o3", OrderNumber: "4111111111111111", CustomerID: "c1", UploadedAt: uploadedAt},
+		},
+	}, logger)
+	require.NoError(t, err)
+
+	queued := s.drainQueue()
+	require.Len(t, queued, 3)
+	assert.Equal(t, &order.OrderDTO{
+		OrderID:    "o1",
+		Status:     string(orderDomain.New),
+		Number:     "79927398713",
+		UploadedAt: "2025-01-02T03:04:05Z",
+		CustomerID: "c1",
+	}, queued[0])
+	assert.Equal(t, "12345678903", queued[1].Number)
+	assert.Equal(t, "4111111111111111", queued[2].Number)
+}
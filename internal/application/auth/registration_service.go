@@ -3,7 +3,9 @@ package auth
 import (
 	"context"
 	"github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/transaction"
 	"github.com/sviatilnik/gophermart/internal/domain/user"
+	"time"
 )
 
 type RegistrationService struct {
@@ -11,14 +13,16 @@ type RegistrationService struct {
 	loginChecker    user.LoginChecker
 	passwordChecker user.PasswordChecker
 	eventBus        events.Bus
+	transactor      transaction.Transactor
 }
 
-func NewRegistrationService(repo user.Repository, bus events.Bus) *RegistrationService {
+func NewRegistrationService(repo user.Repository, bus events.Bus, transactor transaction.Transactor) *RegistrationService {
 	return &RegistrationService{
 		repo:            repo,
 		loginChecker:    user.NewLoginCheckerService(),
 		passwordChecker: user.NewSimplePasswordChecker(),
 		eventBus:        bus,
+		transactor:      transactor,
 	}
 }
 
@@ -38,12 +42,15 @@ func (s *RegistrationService) Register(ctx context.Context, req RegisterRequest)
 		return nil, err
 	}
 
-	err = s.repo.Save(ctx, newUser)
-	if err != nil {
-		return nil, err
-	}
+	// пользователь и событие для создания кошелька сохраняются вместе
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.repo.Save(ctx, newUser)
+		if err != nil {
+			return err
+		}
 
-	err = s.eventBus.Publish(&user.Registered{UserID: newUser.ID, Email: req.Login})
+		return s.eventBus.Publish(ctx, &user.Registered{UserID: newUser.ID, Email: req.Login, OccurredAt: time.Now()})
+	})
 	if err != nil {
 		return nil, err
 	}
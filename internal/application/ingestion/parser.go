@@ -0,0 +1,174 @@
+package ingestion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/application/order"
+	"github.com/sviatilnik/gophermart/internal/domain/ingestion"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported file format")
+	ErrMissingColumn     = errors.New("required column is missing")
+)
+
+// Record - строка файла партнёра
+type Record struct {
+	Line  int
+	Login string
+	Order order.CreateOrderDTO
+}
+
+type jsonLine struct {
+	Login    string                  `json:"login"`
+	Number   string                  `json:"number"`
+	Source   string                  `json:"source"`
+	Metadata *order.OrderMetadataDTO `json:"metadata"`
+}
+
+// IsSupported - файлы других форматов в каталоге не трогаем
+func IsSupported(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".jsonl":
+		return true
+	}
+
+	return false
+}
+
+// Parse разбирает CSV или JSON Lines. Ошибки отдельных строк попадают в file, а не прерывают разбор.
+func Parse(name string, data []byte, file *ingestion.File) ([]*Record, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return parseCSV(data, file)
+	case ".jsonl":
+		return parseJSONLines(data, file)
+	}
+
+	return nil, ErrUnsupportedFormat
+}
+
+// parseCSV ожидает заголовок; обязательны колонки login и number,
+// необязательны source, merchant_id, purchase_amount, currency и purchased_at (RFC3339)
+func parseCSV(data []byte, file *ingestion.File) ([]*Record, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"login", "number"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, required)
+		}
+	}
+
+	records := make([]*Record, 0)
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			file.AddError(line, "", err)
+			continue
+		}
+
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		record := &Record{
+			Line:  line,
+			Login: value("login"),
+			Order: order.CreateOrderDTO{Number: value("number"), Source: value("source")},
+		}
+
+		metadata, err := csvMetadata(value)
+		if err != nil {
+			file.AddError(line, record.Order.Number, err)
+			continue
+		}
+		record.Order.Metadata = metadata
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func csvMetadata(value func(column string) string) (*order.OrderMetadataDTO, error) {
+	metadata := &order.OrderMetadataDTO{
+		MerchantID: value("merchant_id"),
+		Currency:   value("currency"),
+	}
+
+	if amount := value("purchase_amount"); amount != "" {
+		a, err := strconv.ParseFloat(amount, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid purchase_amount: %w", err)
+		}
+		metadata.PurchaseAmount = a
+	}
+
+	if purchasedAt := value("purchased_at"); purchasedAt != "" {
+		t, err := time.Parse(time.RFC3339, purchasedAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid purchased_at: %w", err)
+		}
+		metadata.PurchasedAt = &t
+	}
+
+	if metadata.MerchantID == "" && metadata.Currency == "" && metadata.PurchaseAmount == 0 && metadata.PurchasedAt == nil {
+		return nil, nil
+	}
+
+	return metadata, nil
+}
+
+func parseJSONLines(data []byte, file *ingestion.File) ([]*Record, error) {
+	records := make([]*Record, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var l jsonLine
+		err := json.Unmarshal([]byte(text), &l)
+		if err != nil {
+			file.AddError(line, "", err)
+			continue
+		}
+
+		records = append(records, &Record{
+			Line:  line,
+			Login: l.Login,
+			Order: order.CreateOrderDTO{Number: l.Number, Source: l.Source, Metadata: l.Metadata},
+		})
+	}
+
+	return records, scanner.Err()
+}
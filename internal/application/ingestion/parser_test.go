@@ -0,0 +1,75 @@
+package ingestion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/domain/ingestion"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		data        string
+		wantNumbers []string
+		wantErrors  []int
+		wantErr     error
+	}{
+		{
+			name: "csv with metadata",
+			file: "partner.csv",
+			data: "login,number,merchant_id,purchase_amount\n" +
+				"alice,79927398713,shop-1,1200.50\n" +
+				"bob,12345678903,,abc\n" +
+				"carol,4561261212345467,,\n",
+			wantNumbers: []string{"79927398713", "4561261212345467"},
+			wantErrors:  []int{3},
+		},
+		{
+			name:    "csv without number column",
+			file:    "partner.CSV",
+			data:    "login,order\nalice,79927398713\n",
+			wantErr: ErrMissingColumn,
+		},
+		{
+			name: "json lines",
+			file: "nightly.jsonl",
+			data: `{"login":"alice","number":"79927398713","metadata":{"merchant_id":"shop-1"}}` + "\n\n" +
+				`{"login":"bob",` + "\n" +
+				`{"login":"carol","number":"12345678903"}` + "\n",
+			wantNumbers: []string{"79927398713", "12345678903"},
+			wantErrors:  []int{3},
+		},
+		{
+			name:    "unknown format",
+			file:    "orders.xml",
+			wantErr: ErrUnsupportedFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := ingestion.NewFile(tt.file, "checksum")
+
+			records, err := Parse(tt.file, []byte(tt.data), file)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			numbers := make([]string, len(records))
+			for i, r := range records {
+				numbers[i] = r.Order.Number
+			}
+			assert.Equal(t, tt.wantNumbers, numbers)
+
+			lines := make([]int, len(file.Errors))
+			for i, e := range file.Errors {
+				lines[i] = e.Line
+			}
+			assert.Equal(t, tt.wantErrors, lines)
+		})
+	}
+}
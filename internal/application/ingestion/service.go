@@ -0,0 +1,199 @@
+package ingestion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/application/order"
+	"github.com/sviatilnik/gophermart/internal/domain/ingestion"
+	"github.com/sviatilnik/gophermart/internal/domain/user"
+	"go.uber.org/zap"
+)
+
+const (
+	doneDir   = "done"
+	failedDir = "failed"
+	// settleTime - файл, который менялся недавно, возможно ещё дописывается партнёром
+	settleTime = 10 * time.Second
+)
+
+// Service забирает файлы партнёров из каталога и создаёт по ним заказы
+type Service struct {
+	dir          string
+	repo         ingestion.Repository
+	userRepo     user.Repository
+	orderService *order.Service
+	logger       *zap.SugaredLogger
+}
+
+func NewService(dir string, repo ingestion.Repository, userRepo user.Repository, orderService *order.Service, logger *zap.SugaredLogger) *Service {
+	return &Service{
+		dir:          dir,
+		repo:         repo,
+		userRepo:     userRepo,
+		orderService: orderService,
+		logger:       logger,
+	}
+}
+
+// Start периодически проверяет каталог, пока не отменён контекст
+func (s *Service) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("ingestion: service shutting down")
+			return
+		case <-ticker.C:
+			err := s.Scan(ctx)
+			if err != nil {
+				s.logger.Error("ingestion: scan failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Scan обрабатывает все готовые файлы в каталоге
+func (s *Service) Scan(ctx context.Context) error {
+	for _, dir := range []string{doneDir, failedDir} {
+		err := os.MkdirAll(filepath.Join(s.dir, dir), 0o755)
+		if err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !IsSupported(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < settleTime {
+			continue
+		}
+
+		file, err := s.ingest(ctx, entry.Name())
+		if err != nil {
+			s.logger.Error("ingestion: file processing failed", zap.String("file", entry.Name()), zap.Error(err))
+			continue
+		}
+
+		s.logger.Infow("ingestion: file processed",
+			"file", file.Name,
+			"status", file.Status,
+			"accepted", file.Accepted,
+			"skipped", file.Skipped,
+			"errors", len(file.Errors))
+	}
+
+	return nil
+}
+
+func (s *Service) ingest(ctx context.Context, name string) (*ingestion.File, error) {
+	path := filepath.Join(s.dir, name)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	file := ingestion.NewFile(name, hex.EncodeToString(sum[:]))
+
+	exists, err := s.repo.Exists(ctx, file.Checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	// повторно выложенный файл не загружаем, но убираем из каталога, чтобы не проверять его снова
+	if exists {
+		file.Fail(errors.New("file with the same checksum was already ingested"))
+		return file, s.archive(path, file)
+	}
+
+	records, err := Parse(name, data, file)
+	if err != nil {
+		file.Fail(err)
+	} else {
+		s.createOrders(ctx, records, file)
+		file.Finish()
+	}
+
+	err = s.repo.Save(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return file, s.archive(path, file)
+}
+
+func (s *Service) createOrders(ctx context.Context, records []*Record, file *ingestion.File) {
+	file.Lines = len(records) + len(file.Errors)
+
+	for _, record := range records {
+		usr, err := s.userRepo.FindByLogin(ctx, user.NewLogin(record.Login))
+		if err != nil {
+			file.AddError(record.Line, record.Order.Number, err)
+			continue
+		}
+
+		req := record.Order
+		req.CustomerID = usr.ID
+		if req.Source == "" && req.Metadata != nil {
+			req.Source = req.Metadata.MerchantID
+		}
+
+		_, err = s.orderService.Create(ctx, req)
+		// заказ уже загружен этим же пользователем, например из прерванной обработки файла
+		if errors.Is(err, order.ErrAlreadyExists) {
+			file.Skipped++
+			continue
+		}
+		if err != nil {
+			file.AddError(record.Line, record.Order.Number, err)
+			continue
+		}
+
+		file.Accepted++
+	}
+}
+
+// archive переносит файл в done или failed и кладёт рядом отчёт по строкам
+func (s *Service) archive(path string, file *ingestion.File) error {
+	dir := doneDir
+	if file.Status == ingestion.Failed {
+		dir = failedDir
+	}
+
+	target := filepath.Join(s.dir, dir, file.ProcessedAt.UTC().Format("20060102T150405")+"-"+file.Name)
+
+	report, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(target+".report.json", report, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path, target)
+}
@@ -0,0 +1,20 @@
+package leader
+
+import "time"
+
+// StatusDTO - DTO состояния выбора лидера
+type StatusDTO struct {
+	InstanceID string    `json:"instance_id"`
+	IsLeader   bool      `json:"is_leader"`
+	Leader     *LeaseDTO `json:"leader,omitempty"`
+	Jobs       []string  `json:"jobs"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// LeaseDTO - DTO текущей аренды лидерства
+type LeaseDTO struct {
+	InstanceID string    `json:"instance_id"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	RenewedAt  time.Time `json:"renewed_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,206 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/domain/leader"
+	"go.uber.org/zap"
+)
+
+// Job - фоновая задача, которая должна выполняться только на лидере.
+// Контекст задачи отменяется при потере лидерства.
+type Job func(ctx context.Context)
+
+var ErrInvalidTiming = errors.New("lease ttl must be greater than renew interval, and both positive")
+
+type namedJob struct {
+	name string
+	run  Job
+}
+
+type Elector struct {
+	repo       leader.Repository
+	leaseName  string
+	instanceID string
+	ttl        time.Duration
+	heartbeat  time.Duration
+	logger     *zap.SugaredLogger
+
+	mu       sync.RWMutex
+	jobs     []namedJob
+	isLeader bool
+	// renewedAt - начало последнего успешного продления; аренда действует минимум ttl от него
+	renewedAt time.Time
+	cancel    context.CancelFunc
+	running   sync.WaitGroup
+}
+
+func NewElector(repo leader.Repository, leaseName string, instanceID string, ttl time.Duration, renewInterval time.Duration, logger *zap.SugaredLogger) (*Elector, error) {
+	if renewInterval <= 0 || ttl <= renewInterval {
+		return nil, ErrInvalidTiming
+	}
+
+	return &Elector{
+		repo:       repo,
+		leaseName:  leaseName,
+		instanceID: instanceID,
+		ttl:        ttl,
+		heartbeat:  renewInterval,
+		logger:     logger,
+		jobs:       make([]namedJob, 0),
+	}, nil
+}
+
+// Register добавляет задачу, запускаемую при получении лидерства
+func (e *Elector) Register(name string, job Job) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.jobs = append(e.jobs, namedJob{name: name, run: job})
+}
+
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.isLeader
+}
+
+// Run участвует в выборах до отмены контекста, продлевая аренду каждые renewInterval
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.heartbeat)
+	defer ticker.Stop()
+
+	e.tick(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.stepDown()
+
+			// контекст уже отменён, освобождаем аренду с отдельным контекстом, чтобы другая реплика подхватила её сразу
+			releaseCtx, cancel := context.WithTimeout(context.Background(), e.heartbeat)
+			err := e.repo.Release(releaseCtx, e.leaseName, e.instanceID)
+			cancel()
+			if err != nil {
+				e.logger.Error("leader: failed to release lease", zap.Error(err))
+			}
+
+			return
+		case <-ticker.C:
+			e.tick(ctx)
+		}
+	}
+}
+
+func (e *Elector) Status(ctx context.Context) (*StatusDTO, error) {
+	e.mu.RLock()
+	status := &StatusDTO{
+		InstanceID: e.instanceID,
+		IsLeader:   e.isLeader,
+		Jobs:       make([]string, len(e.jobs)),
+		CheckedAt:  time.Now(),
+	}
+	for i, job := range e.jobs {
+		status.Jobs[i] = job.name
+	}
+	e.mu.RUnlock()
+
+	lease, err := e.repo.Get(ctx, e.leaseName)
+	if errors.Is(err, leader.ErrLeaseNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !lease.IsExpired(status.CheckedAt) {
+		status.Leader = &LeaseDTO{
+			InstanceID: lease.HolderID,
+			AcquiredAt: lease.AcquiredAt,
+			RenewedAt:  lease.RenewedAt,
+			ExpiresAt:  lease.ExpiresAt,
+		}
+	}
+
+	return status, nil
+}
+
+func (e *Elector) tick(ctx context.Context) {
+	startedAt := time.Now()
+	acquireCtx, cancel := context.WithTimeout(ctx, e.heartbeat)
+	acquired, err := e.repo.TryAcquire(acquireCtx, e.leaseName, e.instanceID, e.ttl)
+	cancel()
+
+	if err != nil {
+		e.logger.Error("leader: failed to acquire lease", zap.Error(err))
+
+		// разовая ошибка базы не повод останавливать задачи, пока аренда ещё действует.
+		// Уходим, если аренда истечёт раньше следующей попытки, иначе задачи будут работать на двух репликах
+		if e.leaseExpiresBefore(time.Now().Add(e.heartbeat)) {
+			e.stepDown()
+		}
+		return
+	}
+
+	if acquired {
+		e.mu.Lock()
+		e.renewedAt = startedAt
+		e.mu.Unlock()
+
+		e.becomeLeader(ctx)
+	} else {
+		e.stepDown()
+	}
+}
+
+func (e *Elector) leaseExpiresBefore(t time.Time) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return !e.renewedAt.Add(e.ttl).After(t)
+}
+
+func (e *Elector) becomeLeader(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.isLeader {
+		return
+	}
+
+	e.logger.Infow("leader: leadership acquired", "instance", e.instanceID)
+
+	jobsCtx, cancel := context.WithCancel(ctx)
+	e.isLeader = true
+	e.cancel = cancel
+
+	for _, job := range e.jobs {
+		e.running.Add(1)
+		go func() {
+			defer e.running.Done()
+			job.run(jobsCtx)
+		}()
+	}
+}
+
+func (e *Elector) stepDown() {
+	e.mu.Lock()
+	if !e.isLeader {
+		e.mu.Unlock()
+		return
+	}
+
+	e.logger.Infow("leader: leadership lost", "instance", e.instanceID)
+
+	e.isLeader = false
+	e.cancel()
+	e.cancel = nil
+	e.mu.Unlock()
+
+	// дожидаемся остановки задач, прежде чем лидерство сможет получить кто-то ещё
+	e.running.Wait()
+}
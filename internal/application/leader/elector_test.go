@@ -0,0 +1,163 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/domain/leader"
+	"go.uber.org/zap"
+)
+
+type memoryRepository struct {
+	mu     sync.Mutex
+	leases map[string]*leader.Lease
+	// failing - TryAcquire возвращает ошибку, как при недоступной базе
+	failing atomic.Bool
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{leases: make(map[string]*leader.Lease)}
+}
+
+func (m *memoryRepository) TryAcquire(_ context.Context, name string, holderID string, ttl time.Duration) (bool, error) {
+	if m.failing.Load() {
+		return false, errors.New("connection refused")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	lease, ok := m.leases[name]
+	if ok && lease.HolderID != holderID && !lease.IsExpired(now) {
+		return false, nil
+	}
+
+	if !ok || lease.HolderID != holderID {
+		lease = &leader.Lease{Name: name, HolderID: holderID, AcquiredAt: now}
+		m.leases[name] = lease
+	}
+	lease.RenewedAt = now
+	lease.ExpiresAt = now.Add(ttl)
+
+	return true, nil
+}
+
+func (m *memoryRepository) Release(_ context.Context, name string, holderID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if lease, ok := m.leases[name]; ok && lease.HolderID == holderID {
+		delete(m.leases, name)
+	}
+
+	return nil
+}
+
+func (m *memoryRepository) Get(_ context.Context, name string) (*leader.Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lease, ok := m.leases[name]
+	if !ok {
+		return nil, leader.ErrLeaseNotFound
+	}
+
+	copied := *lease
+	return &copied, nil
+}
+
+func TestElector_Failover(t *testing.T) {
+	repo := newMemoryRepository()
+	logger := zap.NewNop().Sugar()
+
+	var running atomic.Int32
+	job := func(ctx context.Context) {
+		running.Add(1)
+		<-ctx.Done()
+		running.Add(-1)
+	}
+
+	first, err := NewElector(repo, "jobs", "first", 30*time.Millisecond, 10*time.Millisecond, logger)
+	require.NoError(t, err)
+	first.Register("job", job)
+	second, err := NewElector(repo, "jobs", "second", 30*time.Millisecond, 10*time.Millisecond, logger)
+	require.NoError(t, err)
+	second.Register("job", job)
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	firstDone := make(chan struct{})
+	go func() {
+		first.Run(firstCtx)
+		close(firstDone)
+	}()
+
+	require.Eventually(t, first.IsLeader, time.Second, 5*time.Millisecond)
+
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	defer stopSecond()
+	go second.Run(secondCtx)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, second.IsLeader())
+	assert.Equal(t, int32(1), running.Load())
+
+	status, err := second.Status(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, status.Leader)
+	assert.Equal(t, "first", status.Leader.InstanceID)
+
+	stopFirst()
+	<-firstDone
+
+	require.Eventually(t, second.IsLeader, time.Second, 5*time.Millisecond)
+	assert.False(t, first.IsLeader())
+	assert.Eventually(t, func() bool { return running.Load() == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestElector_KeepsLeadershipUntilLeaseExpires(t *testing.T) {
+	repo := newMemoryRepository()
+
+	elector, err := NewElector(repo, "jobs", "first", 200*time.Millisecond, 20*time.Millisecond, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go elector.Run(ctx)
+
+	require.Eventually(t, elector.IsLeader, time.Second, 5*time.Millisecond)
+
+	// несколько неудачных продлений подряд, но аренда ещё не истекла
+	repo.failing.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, elector.IsLeader())
+
+	require.Eventually(t, func() bool { return !elector.IsLeader() }, time.Second, 5*time.Millisecond)
+
+	repo.failing.Store(false)
+	require.Eventually(t, elector.IsLeader, time.Second, 5*time.Millisecond)
+}
+
+func TestNewElector_InvalidTiming(t *testing.T) {
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		renew time.Duration
+	}{
+		{name: "zero renew interval", ttl: time.Second, renew: 0},
+		{name: "negative ttl", ttl: -time.Second, renew: time.Second},
+		{name: "renew interval not shorter than ttl", ttl: time.Second, renew: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewElector(newMemoryRepository(), "jobs", "first", tt.ttl, tt.renew, zap.NewNop().Sugar())
+			assert.ErrorIs(t, err, ErrInvalidTiming)
+		})
+	}
+}
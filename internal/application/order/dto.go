@@ -1,9 +1,37 @@
 package order
 
+import "time"
+
 // CreateOrderDTO - DTO для входящих данных
 type CreateOrderDTO struct {
 	Number     string `json:"number"`
 	CustomerID string `json:"customer_id"`
+	// Source - источник заказа (мерчант), по нему выбирается схема проверки номера.
+	// Определяется на сервере, из тела запроса не читается
+	Source   string            `json:"-"`
+	Metadata *OrderMetadataDTO `json:"metadata,omitempty"`
+}
+
+// LineItemDTO - DTO позиции чека
+type LineItemDTO struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+// OrderMetadataDTO - DTO сведений о покупке
+type OrderMetadataDTO struct {
+	MerchantID     string        `json:"merchant_id,omitempty"`
+	PurchaseAmount float64       `json:"purchase_amount,omitempty"`
+	Currency       string        `json:"currency,omitempty"`
+	PurchasedAt    *time.Time    `json:"purchased_at,omitempty"`
+	Items          []LineItemDTO `json:"items,omitempty"`
+}
+
+// CreateOrdersBatchDTO - DTO для пакетной загрузки заказов
+type CreateOrdersBatchDTO struct {
+	Numbers    []string
+	CustomerID string
+	Source     string
 }
 
 // OrderDTO - DTO для ответа
@@ -14,4 +42,71 @@ type OrderDTO struct {
 	Number     string  `json:"number"`
 	UploadedAt string  `json:"uploaded_at"`
 	CustomerID string  `json:"-"`
+
+	Metadata *OrderMetadataDTO `json:"metadata,omitempty"`
+}
+
+// CancelOrderDTO - DTO отмены заказа администратором
+type CancelOrderDTO struct {
+	Reason string `json:"reason"`
+}
+
+// TransitionDTO - DTO записи истории статусов заказа
+type TransitionDTO struct {
+	From       string `json:"from,omitempty"`
+	To         string `json:"to"`
+	Cause      string `json:"cause"`
+	OccurredAt string `json:"occurred_at"`
+}
+
+// OrderDetailsDTO - DTO заказа с историей смены статусов
+type OrderDetailsDTO struct {
+	*OrderDTO
+	Timeline []*TransitionDTO `json:"timeline"`
+}
+
+// HistoryEventDTO - DTO события из истории заказа
+type HistoryEventDTO struct {
+	Version    int       `json:"version"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// ListOrdersDTO - DTO запроса страницы заказов пользователя
+type ListOrdersDTO struct {
+	CustomerID string
+	Statuses   []string
+	From       *time.Time
+	To         *time.Time
+	Cursor     string
+	Limit      int
+	Sort       string
+}
+
+// OrdersPageDTO - DTO страницы заказов
+type OrdersPageDTO struct {
+	Orders     []*OrderDTO
+	NextCursor string
+}
+
+const (
+	BatchItemAccepted        = "accepted"
+	BatchItemAlreadyUploaded = "already_uploaded"
+	BatchItemOtherCustomer   = "owned_by_other_customer"
+	BatchItemInvalid         = "invalid"
+)
+
+// BatchItemResultDTO - DTO результата загрузки одного номера из пакета
+type BatchItemResultDTO struct {
+	Number string `json:"number"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// BatchResultDTO - DTO ответа на пакетную загрузку
+type BatchResultDTO struct {
+	Accepted int                   `json:"accepted"`
+	Rejected int                   `json:"rejected"`
+	Items    []*BatchItemResultDTO `json:"items"`
 }
@@ -1,8 +1,21 @@
 package order
 
-import orderDomain "github.com/sviatilnik/gophermart/internal/domain/order"
+import (
+	"errors"
+
+	orderDomain "github.com/sviatilnik/gophermart/internal/domain/order"
+	"github.com/sviatilnik/gophermart/internal/domain/pagination"
+)
 
 var (
 	ErrAlreadyExists                 = orderDomain.ErrAlreadyExists
 	ErrAlreadyCreatedByOtherCustomer = orderDomain.ErrAlreadyCreatedByOtherCustomer
+	ErrOrderNotFound                 = orderDomain.ErrOrderNotFound
+	ErrUnknownState                  = orderDomain.ErrUnknownState
+	ErrInvalidMetadata               = orderDomain.ErrInvalidMetadata
+	ErrInvalidCursor                 = pagination.ErrInvalidCursor
+	ErrInvalidSort                   = pagination.ErrInvalidDirection
+	ErrNotCancellable                = errors.New("order can be cancelled only while it is NEW")
+	ErrEmptyBatch                    = errors.New("order batch is empty")
+	ErrBatchTooLarge                 = errors.New("order batch is too large")
 )
@@ -2,33 +2,22 @@ package order
 
 import (
 	"context"
+	"errors"
 	"github.com/sviatilnik/gophermart/internal/domain/accrual"
 	"github.com/sviatilnik/gophermart/internal/domain/events"
 	"github.com/sviatilnik/gophermart/internal/domain/order"
 	"go.uber.org/zap"
-	"time"
 )
 
-func RegisterEventHandlers(bus events.Bus, orderService *Service) {
-	bus.Subscribe("accrual.created", func(e events.Event, logger *zap.SugaredLogger) error {
-
+func RegisterEventHandlers(bus events.Bus, orderService *Service) error {
+	return bus.Subscribe("accrual.created", "order.apply_accrual", func(ctx context.Context, e events.Event, logger *zap.SugaredLogger) error {
 		event := e.(*accrual.CreatedEvent)
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		num, err := order.NewOrderNumber(event.OrderNumber)
-		if err != nil {
-			return err
+		err := orderService.applyAccrual(ctx, event)
+		if errors.Is(err, errOrderCancelled) {
+			logger.Infow("accrual for cancelled order ignored", "order", event.OrderNumber, "status", event.Status)
+			return nil
 		}
-
-		o, err := orderService.orderRepo.Get(ctx, num)
-		if err != nil {
-			return err
-		}
-
-		o.State = order.State(event.Status)
-		err = orderService.orderRepo.Save(ctx, o)
 		if err != nil {
 			return err
 		}
@@ -38,3 +27,13 @@ func RegisterEventHandlers(bus events.Bus, orderService *Service) {
 		return nil
 	})
 }
+
+// stateFromAccrual переводит статус системы начислений в статус заказа
+func stateFromAccrual(status string) order.State {
+	switch accrual.State(status) {
+	case accrual.Registered:
+		return order.Processing
+	default:
+		return order.State(status)
+	}
+}
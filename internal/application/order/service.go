@@ -5,31 +5,51 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+	"github.com/sviatilnik/gophermart/internal/domain/events"
 	"github.com/sviatilnik/gophermart/internal/domain/order"
+	"github.com/sviatilnik/gophermart/internal/domain/pagination"
+	"github.com/sviatilnik/gophermart/internal/domain/transaction"
 	"github.com/sviatilnik/gophermart/internal/domain/user"
+	"strings"
 	"time"
 )
 
 type Service struct {
-	orderRepo   order.Repository
-	userRepo    user.Repository
-	accrualRepo accrual.Repository
+	orderRepo    order.Repository
+	userRepo     user.Repository
+	accrualRepo  accrual.Repository
+	eventBus     events.Bus
+	transactor   transaction.Transactor
+	schemes      *order.NumberSchemes
+	maxBatchSize int
 }
 
-func NewOrderService(orderRepo order.Repository, userRepo user.Repository, accrualRepo accrual.Repository) *Service {
+func NewOrderService(orderRepo order.Repository, userRepo user.Repository, accrualRepo accrual.Repository, bus events.Bus, transactor transaction.Transactor, schemes *order.NumberSchemes, maxBatchSize int) *Service {
 	return &Service{
-		orderRepo:   orderRepo,
-		userRepo:    userRepo,
-		accrualRepo: accrualRepo,
+		orderRepo:    orderRepo,
+		userRepo:     userRepo,
+		accrualRepo:  accrualRepo,
+		eventBus:     bus,
+		transactor:   transactor,
+		schemes:      schemes,
+		maxBatchSize: maxBatchSize,
 	}
 }
 
 func (s *Service) Create(ctx context.Context, req CreateOrderDTO) (*OrderDTO, error) {
-	orderNumber, err := order.NewOrderNumber(req.Number)
+	orderNumber, err := order.ParseNumber(req.Number, s.schemes.For(req.Source))
 	if err != nil {
 		return nil, err
 	}
 
+	var metadata *order.Metadata
+	if req.Metadata != nil {
+		metadata = toMetadata(req.Metadata)
+		if err = metadata.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	usr, err := s.userRepo.FindByID(ctx, req.CustomerID)
 	if err != nil {
 		return nil, err
@@ -48,28 +68,421 @@ func (s *Service) Create(ctx context.Context, req CreateOrderDTO) (*OrderDTO, er
 		return nil, ErrAlreadyExists
 	}
 
-	newOrder := order.NewOrder(orderNumber, usr.ID)
+	newOrder := order.NewOrder(orderNumber, usr.ID, metadata)
+
+	uploaded := &order.Uploaded{
+		OrderID:     newOrder.ID,
+		OrderNumber: string(newOrder.Number),
+		CustomerID:  newOrder.CustomerID,
+		UploadedAt:  newOrder.CreatedAt,
+	}
+	if metadata != nil {
+		uploaded.Items = metadata.Items
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.orderRepo.Save(ctx, newOrder)
+		if err != nil {
+			return err
+		}
 
-	err = s.orderRepo.Save(ctx, newOrder)
+		return s.eventBus.Publish(ctx, uploaded)
+	})
+	if errors.Is(err, order.ErrAlreadyExists) {
+		// заказ успели загрузить параллельным запросом - проверяем, кем
+		return nil, s.existingOrderError(ctx, orderNumber, req.CustomerID)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &OrderDTO{
-		OrderID:    newOrder.ID,
-		Status:     string(newOrder.State),
-		Number:     string(newOrder.Number),
-		UploadedAt: newOrder.CreatedAt.UTC().Format(time.RFC3339),
-		CustomerID: newOrder.CustomerID,
-	}, nil
+	return toOrderDTO(newOrder), nil
+}
+
+// CreateBatch загружает несколько заказов одной транзакцией.
+// Ошибка в отдельном номере не прерывает загрузку остальных - она попадает в результат по этому номеру.
+func (s *Service) CreateBatch(ctx context.Context, req CreateOrdersBatchDTO) (*BatchResultDTO, error) {
+	if len(req.Numbers) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if s.maxBatchSize > 0 && len(req.Numbers) > s.maxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	usr, err := s.userRepo.FindByID(ctx, req.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	validator := s.schemes.For(req.Source)
+	items := make([]*BatchItemResultDTO, len(req.Numbers))
+	newOrders := make([]*order.Order, 0, len(req.Numbers))
+	seen := make(map[order.Number]bool, len(req.Numbers))
+
+	for i, number := range req.Numbers {
+		items[i] = &BatchItemResultDTO{Number: number}
+
+		orderNumber, err := order.ParseNumber(number, validator)
+		if err != nil {
+			items[i].Status = BatchItemInvalid
+			items[i].Reason = err.Error()
+			continue
+		}
+
+		if seen[orderNumber] {
+			items[i].Status = BatchItemAlreadyUploaded
+			continue
+		}
+		seen[orderNumber] = true
+
+		newOrders = append(newOrders, order.NewOrder(orderNumber, usr.ID, nil))
+	}
+
+	var existing map[order.Number]string
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		existing, err = s.orderRepo.SaveBatch(ctx, newOrders)
+		if err != nil {
+			return err
+		}
+
+		uploaded := make([]*order.Uploaded, 0, len(newOrders))
+		for _, newOrder := range newOrders {
+			if _, exists := existing[newOrder.Number]; exists {
+				continue
+			}
+
+			uploaded = append(uploaded, &order.Uploaded{
+				OrderID:     newOrder.ID,
+				OrderNumber: string(newOrder.Number),
+				CustomerID:  newOrder.CustomerID,
+				UploadedAt:  newOrder.CreatedAt,
+			})
+		}
+
+		if len(uploaded) == 0 {
+			return nil
+		}
+
+		return s.eventBus.Publish(ctx, &order.BatchUploaded{Orders: uploaded})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BatchResultDTO{Items: items}
+
+	for _, item := range items {
+		if item.Status == "" {
+			owner, exists := existing[order.Number(item.Number)]
+			switch {
+			case !exists:
+				item.Status = BatchItemAccepted
+			case owner == req.CustomerID:
+				item.Status = BatchItemAlreadyUploaded
+			default:
+				item.Status = BatchItemOtherCustomer
+			}
+		}
+
+		if item.Status == BatchItemAccepted {
+			result.Accepted++
+		} else {
+			result.Rejected++
+		}
+	}
+
+	return result, nil
+}
+
+func (s *Service) GetOrder(ctx context.Context, number string) (*OrderDTO, error) {
+	orderNumber, err := s.schemes.ParseAny(number)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := s.orderRepo.Get(ctx, orderNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return toOrderDTO(o), nil
+}
+
+func (s *Service) existingOrderError(ctx context.Context, number order.Number, customerID string) error {
+	existsOrder, err := s.orderRepo.Get(ctx, number)
+	if err != nil {
+		return err
+	}
+
+	if existsOrder.CustomerID != customerID {
+		return ErrAlreadyCreatedByOtherCustomer
+	}
+
+	return ErrAlreadyExists
+}
+
+// updateAttempts - сколько раз повторять обновление заказа при конфликте версий
+const updateAttempts = 3
+
+// update загружает заказ, применяет к нему change и сохраняет вместе с событием, которое вернул change.
+// При конфликте версий заказ перечитывается и change применяется заново.
+func (s *Service) update(ctx context.Context, number order.Number, change func(o *order.Order) (events.Event, error)) error {
+	for range updateAttempts {
+		o, err := s.orderRepo.Get(ctx, number)
+		if err != nil {
+			return err
+		}
+
+		event, err := change(o)
+		if err != nil {
+			return err
+		}
+
+		err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			err := s.orderRepo.Save(ctx, o)
+			if err != nil || event == nil {
+				return err
+			}
+
+			return s.eventBus.Publish(ctx, event)
+		})
+		if !errors.Is(err, order.ErrVersionConflict) {
+			return err
+		}
+	}
+
+	return order.ErrVersionConflict
+}
+
+// Cancel отменяет заказ пользователя, пока он ещё не ушёл в обработку
+func (s *Service) Cancel(ctx context.Context, customerID string, number string) (*OrderDTO, error) {
+	dto, err := s.cancel(ctx, number, func(o *order.Order) error {
+		// чужой заказ не отменяем и не раскрываем, что он существует
+		if o.CustomerID != customerID {
+			return ErrOrderNotFound
+		}
+
+		return o.TransitionTo(order.Cancelled, order.CustomerCause(customerID))
+	})
+	if errors.Is(err, order.ErrInvalidTransition) {
+		return nil, ErrNotCancellable
+	}
+
+	return dto, err
+}
+
+// ForceCancel отменяет заказ в любом статусе. Если за заказ уже начислены баллы,
+// кошелёк сторнирует начисление по событию order.cancelled.
+func (s *Service) ForceCancel(ctx context.Context, number string, req CancelOrderDTO) (*OrderDTO, error) {
+	return s.cancel(ctx, number, func(o *order.Order) error {
+		o.ForceCancel(order.AdminCause(req.Reason))
+		return nil
+	})
+}
+
+func (s *Service) cancel(ctx context.Context, number string, change func(o *order.Order) error) (*OrderDTO, error) {
+	orderNumber, err := s.schemes.ParseAny(number)
+	if err != nil {
+		return nil, err
+	}
+
+	var cancelled *order.Order
+	err = s.update(ctx, orderNumber, func(o *order.Order) (events.Event, error) {
+		previous := o.State
+
+		err := change(o)
+		if err != nil {
+			return nil, err
+		}
+
+		cancelled = o
+		if previous == order.Cancelled {
+			return nil, nil
+		}
+
+		return &order.CancelledEvent{
+			OrderID:       o.ID,
+			OrderNumber:   string(o.Number),
+			CustomerID:    o.CustomerID,
+			PreviousState: previous,
+			Cause:         o.Transitions()[len(o.Transitions())-1].Cause,
+		}, nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toOrderDTO(cancelled), nil
+}
+
+// errOrderCancelled - ответ системы начислений пришёл по уже отменённому заказу
+var errOrderCancelled = errors.New("order is cancelled")
+
+// applyAccrual переводит заказ в статус из ответа системы начислений.
+// order.processed публикуется только при первом переходе в PROCESSED.
+func (s *Service) applyAccrual(ctx context.Context, event *accrual.CreatedEvent) error {
+	num, err := s.schemes.ParseAny(event.OrderNumber)
+	if err != nil {
+		return err
+	}
+
+	return s.update(ctx, num, func(o *order.Order) (events.Event, error) {
+		if o.State == order.Cancelled {
+			return nil, errOrderCancelled
+		}
+
+		previous := o.State
+		err := o.TransitionTo(stateFromAccrual(event.Status), order.CauseAccrualSystem)
+		if err != nil {
+			return nil, err
+		}
+
+		if previous == order.Processed || o.State != order.Processed {
+			return nil, nil
+		}
+
+		err = o.Credit(event.Amount)
+		if err != nil {
+			return nil, err
+		}
+
+		return &order.ProcessedEvent{
+			OrderID:     o.ID,
+			OrderNumber: string(o.Number),
+			CustomerID:  o.CustomerID,
+			Accrual:     event.Amount,
+		}, nil
+	})
+}
+
+// RecordCredit записывает в историю обработанного заказа начисление, зачисленное в обход order.processed,
+// например при исправлении расхождения сверкой. Событие не публикуется: кошелёк пополняет вызывающий.
+func (s *Service) RecordCredit(ctx context.Context, number string, amount float64) error {
+	num, err := s.schemes.ParseAny(number)
+	if err != nil {
+		return err
+	}
+
+	err = s.update(ctx, num, func(o *order.Order) (events.Event, error) {
+		return nil, o.Credit(amount)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrOrderNotFound
+	}
+
+	return err
+}
+
+// GetHistory возвращает полную историю заказа для аудита
+func (s *Service) GetHistory(ctx context.Context, number string) ([]*HistoryEventDTO, error) {
+	orderNumber, err := s.schemes.ParseAny(number)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.orderRepo.GetHistory(ctx, orderNumber)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*HistoryEventDTO, len(history))
+	for i, e := range history {
+		result[i] = &HistoryEventDTO{
+			Version:    i + 1,
+			Type:       e.GetType(),
+			OccurredAt: e.GetOccurredAt(),
+			Data:       e,
+		}
+	}
+
+	return result, nil
 }
 
-func (s *Service) GetOrders(ctx context.Context, customerID string, limit int, offset int) ([]*OrderDTO, error) {
-	customerOrders, err := s.orderRepo.GetForCustomer(ctx, customerID, uint64(limit), uint64(offset))
+// GetOrderDetails возвращает заказ пользователя вместе с историей статусов
+func (s *Service) GetOrderDetails(ctx context.Context, customerID string, number string) (*OrderDetailsDTO, error) {
+	orderNumber, err := s.schemes.ParseAny(number)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := s.orderRepo.Get(ctx, orderNumber)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	// чужой заказ не показываем, даже сам факт его существования
+	if o.CustomerID != customerID {
+		return nil, ErrOrderNotFound
+	}
+
+	transitions, err := s.orderRepo.GetTransitions(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &OrderDetailsDTO{
+		OrderDTO: toOrderDTO(o),
+		Timeline: make([]*TransitionDTO, len(transitions)),
+	}
+
+	for i, t := range transitions {
+		details.Timeline[i] = &TransitionDTO{
+			From:       string(t.From),
+			To:         string(t.To),
+			Cause:      t.Cause,
+			OccurredAt: t.OccurredAt.UTC().Format(time.RFC3339),
+		}
+	}
+
+	acc, err := s.accrualRepo.GetForOrders(ctx, []string{string(o.Number)})
+	if err != nil {
+		return nil, err
+	}
+
+	if a, has := acc[string(o.Number)]; has {
+		details.Accrual = a.Amount
+	}
+
+	return details, nil
+}
+
+func (s *Service) GetOrders(ctx context.Context, req ListOrdersDTO) (*OrdersPageDTO, error) {
+	page, err := pagination.NewPage(req.Cursor, uint64(req.Limit), req.Sort, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]order.State, len(req.Statuses))
+	for i, status := range req.Statuses {
+		states[i] = order.State(status)
+		if !states[i].IsKnown() {
+			return nil, order.ErrUnknownState
+		}
+	}
+
+	customerOrders, err := s.orderRepo.List(ctx, order.ListQuery{
+		CustomerID: req.CustomerID,
+		States:     states,
+		Page:       page,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	customerOrders, nextCursor := pagination.Trim(customerOrders, page.Limit, func(o *order.Order) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: o.CreatedAt, ID: o.ID}
+	})
+
 	oNumbers := make([]string, len(customerOrders))
 	for i, o := range customerOrders {
 		oNumbers[i] = string(o.Number)
@@ -83,14 +496,7 @@ func (s *Service) GetOrders(ctx context.Context, customerID string, limit int, o
 	result := make([]*OrderDTO, 0)
 	for _, ordr := range customerOrders {
 		n := string(ordr.Number)
-
-		o := &OrderDTO{
-			OrderID:    ordr.ID,
-			Status:     string(ordr.State),
-			Number:     n,
-			UploadedAt: ordr.CreatedAt.UTC().Format(time.RFC3339),
-			CustomerID: ordr.CustomerID,
-		}
+		o := toOrderDTO(ordr)
 
 		a, has := acc[n]
 		if has {
@@ -99,25 +505,61 @@ func (s *Service) GetOrders(ctx context.Context, customerID string, limit int, o
 		result = append(result, o)
 	}
 
-	return result, nil
+	return &OrdersPageDTO{
+		Orders:     result,
+		NextCursor: nextCursor,
+	}, nil
 }
 
 func (s *Service) GetUnprocessedOrders(ctx context.Context, limit int, offset int) ([]*OrderDTO, error) {
-	customerOrders, err := s.orderRepo.GetByStates(ctx, []order.State{order.New, order.Processing}, uint64(limit), uint64(offset))
+	customerOrders, err := s.orderRepo.GetByStates(ctx, order.AwaitingAccrual(), uint64(limit), uint64(offset))
 	if err != nil {
 		return nil, err
 	}
 
 	result := make([]*OrderDTO, 0)
 	for _, o := range customerOrders {
-		result = append(result, &OrderDTO{
-			OrderID:    o.ID,
-			Status:     string(o.State),
-			Number:     string(o.Number),
-			UploadedAt: o.CreatedAt.UTC().Format(time.RFC3339),
-			CustomerID: o.CustomerID,
-		})
+		result = append(result, toOrderDTO(o))
 	}
 
 	return result, nil
 }
+
+func toOrderDTO(o *order.Order) *OrderDTO {
+	dto := &OrderDTO{
+		OrderID:    o.ID,
+		Status:     string(o.State),
+		Number:     string(o.Number),
+		UploadedAt: o.CreatedAt.UTC().Format(time.RFC3339),
+		CustomerID: o.CustomerID,
+	}
+
+	if o.Metadata != nil {
+		dto.Metadata = &OrderMetadataDTO{
+			MerchantID:     o.Metadata.MerchantID,
+			PurchaseAmount: o.Metadata.PurchaseAmount,
+			Currency:       o.Metadata.Currency,
+			PurchasedAt:    o.Metadata.PurchasedAt,
+		}
+		for _, item := range o.Metadata.Items {
+			dto.Metadata.Items = append(dto.Metadata.Items, LineItemDTO{Name: item.Name, Price: item.Price})
+		}
+	}
+
+	return dto
+}
+
+func toMetadata(dto *OrderMetadataDTO) *order.Metadata {
+	metadata := &order.Metadata{
+		MerchantID:     dto.MerchantID,
+		PurchaseAmount: dto.PurchaseAmount,
+		Currency:       strings.ToUpper(dto.Currency),
+		PurchasedAt:    dto.PurchasedAt,
+	}
+
+	for _, item := range dto.Items {
+		metadata.Items = append(metadata.Items, order.LineItem{Name: item.Name, Price: item.Price})
+	}
+
+	return metadata
+}
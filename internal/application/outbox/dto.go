@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeadLetterFilterDTO - DTO фильтра dead letter событий
+type DeadLetterFilterDTO struct {
+	IDs       []int64    `json:"ids,omitempty"`
+	EventType string     `json:"event_type,omitempty"`
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// FailureDTO - DTO неудачной попытки доставки
+type FailureDTO struct {
+	Attempt    int       `json:"attempt"`
+	Error      string    `json:"error"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// DeadLetterDTO - DTO недоставленного события для ответа
+type DeadLetterDTO struct {
+	ID             int64           `json:"id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	AggregateType  string          `json:"aggregate_type"`
+	AggregateID    string          `json:"aggregate_id"`
+	Payload        json.RawMessage `json:"payload"`
+	Headers        map[string]any  `json:"headers"`
+	Retries        int             `json:"retries"`
+	LastError      string          `json:"last_error"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	DeadLetteredAt time.Time       `json:"dead_lettered_at"`
+	Failures       []*FailureDTO   `json:"failures,omitempty"`
+}
+
+// BulkResultDTO - DTO результата массовой операции
+type BulkResultDTO struct {
+	Affected int64 `json:"affected"`
+}
@@ -0,0 +1,8 @@
+package outbox
+
+import outboxDomain "github.com/sviatilnik/gophermart/internal/domain/outbox"
+
+var (
+	ErrDeadLetterNotFound = outboxDomain.ErrDeadLetterNotFound
+	ErrEmptyFilter        = outboxDomain.ErrEmptyFilter
+)
@@ -0,0 +1,116 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/sviatilnik/gophermart/internal/domain/outbox"
+)
+
+// DeadLetterService - разбор событий, которые не удалось доставить подписчикам
+type DeadLetterService struct {
+	repo outbox.DeadLetterRepository
+}
+
+func NewDeadLetterService(repo outbox.DeadLetterRepository) *DeadLetterService {
+	return &DeadLetterService{
+		repo: repo,
+	}
+}
+
+func (s *DeadLetterService) List(ctx context.Context, filter DeadLetterFilterDTO, limit int, offset int) ([]*DeadLetterDTO, error) {
+	deadLetters, err := s.repo.List(ctx, toFilter(filter), uint64(limit), uint64(offset))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*DeadLetterDTO, len(deadLetters))
+	for i, d := range deadLetters {
+		result[i] = toDeadLetterDTO(d)
+	}
+
+	return result, nil
+}
+
+// Get возвращает событие вместе с полной историей ошибок
+func (s *DeadLetterService) Get(ctx context.Context, id int64) (*DeadLetterDTO, error) {
+	d, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDeadLetterDTO(d), nil
+}
+
+func (s *DeadLetterService) UpdateHeaders(ctx context.Context, id int64, headers map[string]any) (*DeadLetterDTO, error) {
+	if headers == nil {
+		headers = map[string]any{}
+	}
+
+	err := s.repo.UpdateHeaders(ctx, id, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, id)
+}
+
+// Replay возвращает события в очередь доставки. Без фильтра операция не выполняется,
+// чтобы случайно не переотправить все события разом.
+func (s *DeadLetterService) Replay(ctx context.Context, filter DeadLetterFilterDTO) (*BulkResultDTO, error) {
+	return s.bulk(ctx, filter, s.repo.Replay)
+}
+
+func (s *DeadLetterService) Discard(ctx context.Context, filter DeadLetterFilterDTO) (*BulkResultDTO, error) {
+	return s.bulk(ctx, filter, s.repo.Discard)
+}
+
+func (s *DeadLetterService) bulk(ctx context.Context, filter DeadLetterFilterDTO, apply func(ctx context.Context, filter outbox.Filter) (int64, error)) (*BulkResultDTO, error) {
+	f := toFilter(filter)
+	if f.IsEmpty() {
+		return nil, ErrEmptyFilter
+	}
+
+	affected, err := apply(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	// операция над одним событием должна сообщать, что его нет
+	if affected == 0 && len(f.IDs) == 1 {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	return &BulkResultDTO{Affected: affected}, nil
+}
+
+func toFilter(dto DeadLetterFilterDTO) outbox.Filter {
+	return outbox.Filter{
+		IDs:           dto.IDs,
+		EventType:     dto.EventType,
+		From:          dto.From,
+		To:            dto.To,
+		ErrorContains: dto.Error,
+	}
+}
+
+func toDeadLetterDTO(d *outbox.DeadLetter) *DeadLetterDTO {
+	dto := &DeadLetterDTO{
+		ID:             d.ID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		AggregateType:  d.AggregateType,
+		AggregateID:    d.AggregateID,
+		Payload:        d.Payload,
+		Headers:        d.Headers,
+		Retries:        d.Retries,
+		LastError:      d.LastError,
+		OccurredAt:     d.OccurredAt,
+		DeadLetteredAt: d.DeadLetteredAt,
+	}
+
+	for _, f := range d.Failures {
+		dto.Failures = append(dto.Failures, &FailureDTO{Attempt: f.Attempt, Error: f.Error, OccurredAt: f.OccurredAt})
+	}
+
+	return dto
+}
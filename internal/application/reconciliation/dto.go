@@ -0,0 +1,27 @@
+package reconciliation
+
+import "time"
+
+// DiscrepancyDTO - DTO расхождения для ответа
+type DiscrepancyDTO struct {
+	ID             string     `json:"id"`
+	Kind           string     `json:"kind"`
+	Status         string     `json:"status"`
+	OrderNumber    string     `json:"order"`
+	CustomerID     string     `json:"customer_id"`
+	OrderState     string     `json:"order_state"`
+	ExpectedAmount float64    `json:"expected_amount"`
+	ActualAmount   float64    `json:"actual_amount"`
+	Deposits       int        `json:"deposits"`
+	DetectedAt     time.Time  `json:"detected_at"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	RepairedAt     *time.Time `json:"repaired_at,omitempty"`
+}
+
+// ReportDTO - итог одного прогона сверки
+type ReportDTO struct {
+	StartedAt     time.Time         `json:"started_at"`
+	FinishedAt    time.Time         `json:"finished_at"`
+	Discrepancies []*DiscrepancyDTO `json:"discrepancies"`
+	Repaired      int               `json:"repaired"`
+}
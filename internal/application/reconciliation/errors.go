@@ -0,0 +1,9 @@
+package reconciliation
+
+import reconciliationDomain "github.com/sviatilnik/gophermart/internal/domain/reconciliation"
+
+var (
+	ErrDiscrepancyNotFound = reconciliationDomain.ErrDiscrepancyNotFound
+	ErrNotRepairable       = reconciliationDomain.ErrNotRepairable
+	ErrNotOpen             = reconciliationDomain.ErrNotOpen
+)
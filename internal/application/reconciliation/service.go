@@ -0,0 +1,230 @@
+package reconciliation
+
+import (
+	"context"
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/application/order"
+	"github.com/sviatilnik/gophermart/internal/application/wallet"
+	"github.com/sviatilnik/gophermart/internal/domain/reconciliation"
+	"github.com/sviatilnik/gophermart/internal/domain/transaction"
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	repo          reconciliation.Repository
+	orderService  *order.Service
+	walletService *wallet.Service
+	transactor    transaction.Transactor
+	stuckAfter    time.Duration
+	autoRepair    bool
+	logger        *zap.SugaredLogger
+}
+
+func NewService(repo reconciliation.Repository, orderService *order.Service, walletService *wallet.Service, transactor transaction.Transactor, stuckAfter time.Duration, autoRepair bool, logger *zap.SugaredLogger) *Service {
+	return &Service{
+		repo:          repo,
+		orderService:  orderService,
+		walletService: walletService,
+		transactor:    transactor,
+		stuckAfter:    stuckAfter,
+		autoRepair:    autoRepair,
+		logger:        logger,
+	}
+}
+
+// Start периодически запускает сверку, пока не отменён контекст
+func (s *Service) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("reconciliation: service shutting down")
+			return
+		case <-ticker.C:
+			report, err := s.Run(ctx)
+			if err != nil {
+				s.logger.Error("reconciliation: run failed", zap.Error(err))
+				continue
+			}
+
+			s.logger.Infow("reconciliation: run done",
+				"discrepancies", len(report.Discrepancies),
+				"repaired", report.Repaired)
+		}
+	}
+}
+
+func (s *Service) Run(ctx context.Context) (*ReportDTO, error) {
+	report := &ReportDTO{
+		StartedAt:     time.Now(),
+		Discrepancies: make([]*DiscrepancyDTO, 0),
+	}
+
+	found, err := s.detect(ctx, report.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range found {
+		err = s.repo.Save(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.autoRepair && d.IsSafeToRepair() {
+			err = s.repair(ctx, d)
+			if err != nil {
+				s.logger.Error("reconciliation: auto repair failed", zap.String("order", d.OrderNumber), zap.Error(err))
+			} else {
+				report.Repaired++
+			}
+		}
+
+		report.Discrepancies = append(report.Discrepancies, toDTO(d))
+	}
+
+	// всё, что не подтвердилось в этом прогоне, уже исправлено каким-то другим путём
+	err = s.repo.ResolveNotSeenSince(ctx, report.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now()
+
+	return report, nil
+}
+
+func (s *Service) List(ctx context.Context, statuses []string, limit int, offset int) ([]*DiscrepancyDTO, error) {
+	st := make([]reconciliation.Status, len(statuses))
+	for i, status := range statuses {
+		st[i] = reconciliation.Status(status)
+	}
+
+	discrepancies, err := s.repo.List(ctx, st, uint64(limit), uint64(offset))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*DiscrepancyDTO, len(discrepancies))
+	for i, d := range discrepancies {
+		result[i] = toDTO(d)
+	}
+
+	return result, nil
+}
+
+func (s *Service) Repair(ctx context.Context, id string) (*DiscrepancyDTO, error) {
+	d, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repair(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDTO(d), nil
+}
+
+func (s *Service) detect(ctx context.Context, now time.Time) ([]*reconciliation.Discrepancy, error) {
+	result := make([]*reconciliation.Discrepancy, 0)
+
+	processed, err := s.repo.ProcessedOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, facts := range processed {
+		result = append(result, reconciliation.CheckProcessed(facts)...)
+	}
+
+	stuck, err := s.repo.StuckOrders(ctx, now.Add(-s.stuckAfter))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, facts := range stuck {
+		result = append(result, reconciliation.CheckStuck(facts, now, s.stuckAfter)...)
+	}
+
+	return result, nil
+}
+
+// repair закрывает расхождение, записывает начисление в историю заказа и зачисляет баллы в одной транзакции.
+// Параллельный прогон ждёт блокировку строки расхождения и уже не застаёт его открытым,
+// а перепроверка сведений не даёт зачислить по расхождению, которое исправили другим путём.
+func (s *Service) repair(ctx context.Context, d *reconciliation.Discrepancy) error {
+	if !d.IsSafeToRepair() {
+		return ErrNotRepairable
+	}
+
+	repaired := *d
+	err := repaired.MarkRepaired()
+	if err != nil {
+		return err
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.repo.UpdateStatus(ctx, &repaired)
+		if err != nil {
+			return err
+		}
+
+		facts, err := s.repo.OrderFacts(ctx, d.OrderNumber)
+		if err != nil {
+			return err
+		}
+		if !stillMissingDeposit(facts, d) {
+			return ErrNotRepairable
+		}
+
+		err = s.orderService.RecordCredit(ctx, facts.OrderNumber, facts.AccrualAmount)
+		if err != nil {
+			return err
+		}
+
+		return s.walletService.Deposit(ctx, facts.CustomerID, facts.OrderNumber, facts.AccrualAmount)
+	})
+	if err != nil {
+		return err
+	}
+
+	*d = repaired
+
+	return nil
+}
+
+func stillMissingDeposit(facts *reconciliation.OrderFacts, d *reconciliation.Discrepancy) bool {
+	if facts == nil || facts.CustomerID != d.CustomerID {
+		return false
+	}
+
+	for _, found := range reconciliation.CheckProcessed(facts) {
+		if found.Kind == reconciliation.MissingDeposit {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toDTO(d *reconciliation.Discrepancy) *DiscrepancyDTO {
+	return &DiscrepancyDTO{
+		ID:             d.ID,
+		Kind:           string(d.Kind),
+		Status:         string(d.Status),
+		OrderNumber:    d.OrderNumber,
+		CustomerID:     d.CustomerID,
+		OrderState:     d.OrderState,
+		ExpectedAmount: d.ExpectedAmount,
+		ActualAmount:   d.ActualAmount,
+		Deposits:       d.Deposits,
+		DetectedAt:     d.DetectedAt,
+		LastSeenAt:     d.LastSeenAt,
+		RepairedAt:     d.RepairedAt,
+	}
+}
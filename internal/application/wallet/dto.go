@@ -18,3 +18,19 @@ type Withdraw struct {
 	Amount      float64   `json:"sum"`
 	ProcessedAt time.Time `json:"processed_at"`
 }
+
+// ListWithdrawsDTO - DTO запроса страницы списаний пользователя
+type ListWithdrawsDTO struct {
+	CustomerID string
+	From       *time.Time
+	To         *time.Time
+	Cursor     string
+	Limit      int
+	Sort       string
+}
+
+// WithdrawsPageDTO - DTO страницы списаний
+type WithdrawsPageDTO struct {
+	Withdraws  []*Withdraw
+	NextCursor string
+}
@@ -1,8 +1,16 @@
 package wallet
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/sviatilnik/gophermart/internal/domain/order"
+	"github.com/sviatilnik/gophermart/internal/domain/pagination"
+)
 
 var (
 	ErrNotEnoughFunds      = errors.New("not enough funds")
-	ErrOrderNumberNotValid = errors.New("order number not valid")
+	ErrWalletExists        = errors.New("wallet already exists")
+	ErrOrderNumberNotValid = order.ErrOrderNumberNotValid
+	ErrInvalidCursor       = pagination.ErrInvalidCursor
+	ErrInvalidSort         = pagination.ErrInvalidDirection
 )
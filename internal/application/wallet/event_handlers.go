@@ -2,22 +2,23 @@ package wallet
 
 import (
 	"context"
-	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+	"errors"
+
 	"github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/order"
 	"github.com/sviatilnik/gophermart/internal/domain/user"
 	"go.uber.org/zap"
-	"time"
 )
 
-func RegisterEventHandlers(bus events.Bus, walletService *Service) {
-	bus.Subscribe("user.registered", func(e events.Event, logger *zap.SugaredLogger) error {
-
+func RegisterEventHandlers(bus events.Bus, walletService *Service) error {
+	created := bus.Subscribe("user.registered", "wallet.create", func(ctx context.Context, e events.Event, logger *zap.SugaredLogger) error {
 		event := e.(*user.Registered)
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
+		// повторная доставка события застаёт кошелёк уже созданным
 		_, err := walletService.Create(ctx, event.UserID)
+		if errors.Is(err, ErrWalletExists) {
+			return nil
+		}
 		if err != nil {
 			logger.Error("wallet creation failed", zap.Error(err))
 			return err
@@ -26,18 +27,31 @@ func RegisterEventHandlers(bus events.Bus, walletService *Service) {
 		return nil
 	})
 
-	bus.Subscribe("accrual.created", func(e events.Event, logger *zap.SugaredLogger) error {
-		event := e.(*accrual.CreatedEvent)
+	// зачисляем только после перехода заказа в PROCESSED, чтобы не начислить за отменённый заказ
+	deposited := bus.Subscribe("order.processed", "wallet.deposit", func(ctx context.Context, e events.Event, logger *zap.SugaredLogger) error {
+		event := e.(*order.ProcessedEvent)
+
+		return walletService.Deposit(ctx, event.CustomerID, event.OrderNumber, event.Accrual)
+	})
+
+	reversed := bus.Subscribe("order.cancelled", "wallet.reverse_deposit", func(ctx context.Context, e events.Event, logger *zap.SugaredLogger) error {
+		event := e.(*order.CancelledEvent)
 
-		if event.Status == string(accrual.Processed) {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
+		if event.PreviousState != order.Processed {
+			return nil
+		}
 
-			err := walletService.Deposit(ctx, event.CustomerID, event.Amount)
-			if err != nil {
-				return err
-			}
+		amount, err := walletService.ReverseDeposit(ctx, event.CustomerID, event.OrderNumber, event.Cause)
+		if err != nil {
+			return err
 		}
+
+		if amount > 0 {
+			logger.Infow("wallet: accrual reversed for cancelled order", "order", event.OrderNumber, "amount", amount)
+		}
+
 		return nil
 	})
+
+	return errors.Join(created, deposited, reversed)
 }
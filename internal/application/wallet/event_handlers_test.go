@@ -0,0 +1,53 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/user"
+	"github.com/sviatilnik/gophermart/internal/domain/wallet"
+	"go.uber.org/zap"
+)
+
+// existingWallets - кошельки, которые уже есть в хранилище
+type existingWallets struct {
+	wallet.Repository
+	customers map[string]bool
+}
+
+func (r *existingWallets) Exists(_ context.Context, customerID string) (bool, error) {
+	return r.customers[customerID], nil
+}
+
+func (r *existingWallets) Store(_ context.Context, w *wallet.Wallet) error {
+	r.customers[w.CustomerID] = true
+	return nil
+}
+
+type stubBus struct {
+	events.Bus
+	handlers map[string]events.Handler
+}
+
+func (b *stubBus) Subscribe(event string, _ string, handler events.Handler) error {
+	b.handlers[event] = handler
+	return nil
+}
+
+func TestRegisterEventHandlers_CreateIsIdempotent(t *testing.T) {
+	repo := &existingWallets{customers: make(map[string]bool)}
+	bus := &stubBus{handlers: make(map[string]events.Handler)}
+	require.NoError(t, RegisterEventHandlers(bus, NewWalletService(repo, bus, nil, nil)))
+
+	registered := &user.Registered{UserID: "u1"}
+	logger := zap.NewNop().Sugar()
+
+	require.NoError(t, bus.handlers["user.registered"](context.Background(), registered, logger))
+	assert.True(t, repo.customers["u1"])
+
+	// повторная доставка не должна уводить событие в мёртвые письма
+	assert.NoError(t, bus.handlers["user.registered"](context.Background(), registered, logger))
+}
@@ -4,21 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/sviatilnik/gophermart/internal/domain/events"
 	"github.com/sviatilnik/gophermart/internal/domain/order"
+	"github.com/sviatilnik/gophermart/internal/domain/pagination"
+	"github.com/sviatilnik/gophermart/internal/domain/transaction"
 	"github.com/sviatilnik/gophermart/internal/domain/wallet"
 )
 
 type Service struct {
-	repo     wallet.Repository
-	eventBus events.Bus
+	repo       wallet.Repository
+	eventBus   events.Bus
+	transactor transaction.Transactor
+	schemes    *order.NumberSchemes
 }
 
-func NewWalletService(repo wallet.Repository, bus events.Bus) *Service {
+func NewWalletService(repo wallet.Repository, bus events.Bus, transactor transaction.Transactor, schemes *order.NumberSchemes) *Service {
 	return &Service{
-		repo:     repo,
-		eventBus: bus,
+		repo:       repo,
+		eventBus:   bus,
+		transactor: transactor,
+		schemes:    schemes,
 	}
 }
 
@@ -42,7 +49,7 @@ func (s *Service) Create(ctx context.Context, customerID string) (*Wallet, error
 	}
 
 	if exists {
-		return nil, fmt.Errorf("wallet %s already exists", customerID)
+		return nil, fmt.Errorf("%w: %s", ErrWalletExists, customerID)
 	}
 
 	w := wallet.NewWallet(customerID)
@@ -53,6 +60,10 @@ func (s *Service) Create(ctx context.Context, customerID string) (*Wallet, error
 	}
 
 	err = s.repo.Store(ctx, w)
+	// кошелёк успели создать параллельно
+	if errors.Is(err, wallet.ErrVersionConflict) {
+		return nil, fmt.Errorf("%w: %s", ErrWalletExists, customerID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -64,26 +75,41 @@ func (s *Service) Create(ctx context.Context, customerID string) (*Wallet, error
 	}, nil
 }
 
-func (s *Service) Deposit(ctx context.Context, customerID string, amount float64) error {
-	wallt, err := s.repo.Load(ctx, customerID)
-	if err != nil {
-		return err
-	}
+// Deposit зачисляет баллы за заказ. Повторное зачисление по тому же заказу ничего не меняет.
+func (s *Service) Deposit(ctx context.Context, customerID string, orderNumber string, amount float64) error {
+	// до 3 попыток в случае конфликта версий
+	for range 3 {
+		wallt, err := s.repo.Load(ctx, customerID)
+		if err != nil {
+			return err
+		}
 
-	err = wallt.HandleCommand(wallet.NewDepositCommand(customerID, amount))
-	if err != nil {
-		return err
-	}
+		err = wallt.HandleCommand(wallet.NewDepositCommand(customerID, orderNumber, amount))
+		if err != nil {
+			return err
+		}
 
-	err = s.repo.Store(ctx, wallt)
-	if err != nil {
-		return err
+		err = s.repo.Store(ctx, wallt)
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, wallet.ErrVersionConflict) {
+			return err
+		}
 	}
 
-	return nil
+	return wallet.ErrVersionConflict
 }
 
 func (s *Service) Withdraw(ctx context.Context, customerID string, orderNumber string, amount float64) error {
+	// номер списания вводит сам пользователь, поэтому он проверяется схемой по умолчанию, как и загруженные им заказы.
+	// Ошибка проверки номера содержит нарушенное правило, отдаём её как есть
+	number, err := order.ParseNumber(orderNumber, s.schemes.For(order.DefaultSource))
+	if err != nil {
+		return err
+	}
+
 	// до 3 попыток в случае конфликта версий
 	for range 3 {
 		wallt, err := s.repo.Load(ctx, customerID)
@@ -91,15 +117,7 @@ func (s *Service) Withdraw(ctx context.Context, customerID string, orderNumber s
 			return err
 		}
 
-		cmd, err := wallet.NewWithdrawCommand(customerID, orderNumber, amount)
-		if err != nil {
-			if errors.Is(err, order.ErrOrderNumberNotValid) {
-				return ErrOrderNumberNotValid
-			}
-			return err
-		}
-
-		err = wallt.HandleCommand(cmd)
+		err = wallt.HandleCommand(wallet.NewWithdrawCommand(customerID, number, amount))
 		if err != nil {
 			if errors.Is(err, wallet.ErrInsufficientFunds) {
 				return ErrNotEnoughFunds
@@ -108,7 +126,20 @@ func (s *Service) Withdraw(ctx context.Context, customerID string, orderNumber s
 			return err
 		}
 
-		err = s.repo.Store(ctx, wallt)
+		// списание и уведомление о нём сохраняются вместе
+		err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			err := s.repo.Store(ctx, wallt)
+			if err != nil {
+				return err
+			}
+
+			return s.eventBus.Publish(ctx, &wallet.PointsWithdrawn{
+				CustomerID:  customerID,
+				OrderNumber: orderNumber,
+				Amount:      amount,
+				WithdrawnAt: time.Now(),
+			})
+		})
 		if err == nil {
 			return nil
 		}
@@ -122,12 +153,56 @@ func (s *Service) Withdraw(ctx context.Context, customerID string, orderNumber s
 	return wallet.ErrVersionConflict
 }
 
-func (s *Service) GetWithdraws(ctx context.Context, customerID string) ([]*Withdraw, error) {
-	withdraws, err := s.repo.Withdraws(ctx, customerID)
+// ReverseDeposit сторнирует начисление по заказу и возвращает списанную сумму.
+// Если по заказу ничего не зачислено, возвращает 0 без ошибки.
+func (s *Service) ReverseDeposit(ctx context.Context, customerID string, orderNumber string, reason string) (float64, error) {
+	for range 3 {
+		wallt, err := s.repo.Load(ctx, customerID)
+		if err != nil {
+			return 0, err
+		}
+
+		err = wallt.HandleCommand(wallet.NewReverseCommand(customerID, orderNumber, reason))
+		if errors.Is(err, wallet.ErrNothingToReverse) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		reversed := wallt.Events()[0].(*wallet.Reversed)
+
+		err = s.repo.Store(ctx, wallt)
+		if err == nil {
+			return reversed.Amount, nil
+		}
+
+		if !errors.Is(err, wallet.ErrVersionConflict) {
+			return 0, err
+		}
+	}
+
+	return 0, wallet.ErrVersionConflict
+}
+
+func (s *Service) GetWithdraws(ctx context.Context, req ListWithdrawsDTO) (*WithdrawsPageDTO, error) {
+	page, err := pagination.NewPage(req.Cursor, uint64(req.Limit), req.Sort, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	withdraws, err := s.repo.Withdraws(ctx, wallet.WithdrawsQuery{
+		CustomerID: req.CustomerID,
+		Page:       page,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	withdraws, nextCursor := pagination.Trim(withdraws, page.Limit, func(w *wallet.Withdraw) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: w.CreatedAt, ID: w.ID}
+	})
+
 	res := make([]*Withdraw, len(withdraws))
 	for i, w := range withdraws {
 		res[i] = &Withdraw{
@@ -137,5 +212,8 @@ func (s *Service) GetWithdraws(ctx context.Context, customerID string) ([]*Withd
 		}
 	}
 
-	return res, nil
+	return &WithdrawsPageDTO{
+		Withdraws:  res,
+		NextCursor: nextCursor,
+	}, nil
 }
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/domain/webhook"
+	"go.uber.org/zap"
+)
+
+type DelivererConfig struct {
+	BatchSize    int
+	PollInterval time.Duration
+	// Lease - на сколько отправка скрывается от других проходов, пока идёт запрос
+	Lease       time.Duration
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// DisableAfter - после стольких ошибок подряд подписка отключается
+	DisableAfter int
+}
+
+// Deliverer отправляет события из очереди на адреса партнёров
+type Deliverer struct {
+	repo   webhook.Repository
+	client *http.Client
+	config DelivererConfig
+	logger *zap.SugaredLogger
+}
+
+func NewDeliverer(repo webhook.Repository, client *http.Client, config DelivererConfig, logger *zap.SugaredLogger) *Deliverer {
+	return &Deliverer{
+		repo:   repo,
+		client: client,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info("webhook: deliverer shutting down")
+			return
+		case <-ticker.C:
+			// выбираем очередь до конца, не дожидаясь следующего тика
+			for {
+				n, err := d.DeliverBatch(ctx)
+				if err != nil {
+					d.logger.Errorw("webhook: delivery failed", "error", err)
+					break
+				}
+				if n < d.config.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// DeliverBatch отправляет одну пачку и возвращает, сколько отправок было взято
+func (d *Deliverer) DeliverBatch(ctx context.Context) (int, error) {
+	deliveries, err := d.repo.Claim(ctx, d.config.BatchSize, d.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		err = d.deliver(ctx, delivery)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (d *Deliverer) deliver(ctx context.Context, delivery *webhook.Delivery) error {
+	attempt := d.send(ctx, delivery)
+	if attempt.Succeeded() {
+		return d.repo.RecordSuccess(ctx, delivery, attempt)
+	}
+
+	if attempt.Error == "" {
+		attempt.Error = "unexpected response status " + strconv.Itoa(attempt.ResponseStatus)
+	}
+
+	var retryAt *time.Time
+	if attempt.Number < d.config.MaxAttempts {
+		at := attempt.OccurredAt.Add(d.backoff(attempt.Number))
+		retryAt = &at
+	}
+
+	disabled, err := d.repo.RecordFailure(ctx, delivery, attempt, retryAt, d.config.DisableAfter)
+	if err != nil {
+		return err
+	}
+
+	if disabled {
+		d.logger.Warnw("webhook: subscription disabled after repeated failures", "subscription", delivery.SubscriptionID, "url", delivery.URL)
+	}
+	if retryAt == nil {
+		d.logger.Errorw("webhook: delivery attempts exhausted", "delivery", delivery.ID, "event", delivery.EventID, "error", attempt.Error)
+	}
+
+	return nil
+}
+
+// send выполняет один подписанный запрос; ошибка сети или ответ попадают в запись журнала
+func (d *Deliverer) send(ctx context.Context, delivery *webhook.Delivery) *webhook.Attempt {
+	now := time.Now()
+	attempt := &webhook.Attempt{
+		DeliveryID:     delivery.ID,
+		Number:         delivery.Attempts + 1,
+		RequestSnippet: webhook.Snippet(delivery.Body),
+		OccurredAt:     now,
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhook.DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(webhook.EventHeader, delivery.EventType)
+	request.Header.Set(webhook.TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	request.Header.Set(webhook.SignatureHeader, webhook.Sign(delivery.Secret, now, delivery.Body))
+
+	resp, err := d.client.Do(request)
+	attempt.Duration = time.Since(now)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhook.SnippetLength))
+	attempt.ResponseStatus = resp.StatusCode
+	attempt.ResponseSnippet = webhook.Snippet(body)
+
+	return attempt
+}
+
+// backoff удваивает паузу с каждой попыткой, не превышая MaxBackoff
+func (d *Deliverer) backoff(attempt int) time.Duration {
+	delay := d.config.BaseBackoff << min(attempt-1, 30)
+	if delay <= 0 || delay > d.config.MaxBackoff {
+		return d.config.MaxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/domain/webhook"
+	"go.uber.org/zap"
+)
+
+const testSecret = "partner-secret-0123456789"
+
+type memoryRepository struct {
+	webhook.Repository
+
+	pending  []*webhook.Delivery
+	attempts []*webhook.Attempt
+	retryAt  map[int64]*time.Time
+	failures int
+	disabled bool
+}
+
+func (r *memoryRepository) Claim(_ context.Context, limit int, _ time.Duration) ([]*webhook.Delivery, error) {
+	if r.disabled {
+		return nil, nil
+	}
+
+	n := min(limit, len(r.pending))
+	claimed := r.pending[:n]
+	r.pending = r.pending[n:]
+	return claimed, nil
+}
+
+func (r *memoryRepository) RecordSuccess(_ context.Context, d *webhook.Delivery, attempt *webhook.Attempt) error {
+	r.attempts = append(r.attempts, attempt)
+	d.Status = webhook.DeliveryDelivered
+	r.failures = 0
+	return nil
+}
+
+func (r *memoryRepository) RecordFailure(_ context.Context, d *webhook.Delivery, attempt *webhook.Attempt, retryAt *time.Time, disableAfter int) (bool, error) {
+	r.attempts = append(r.attempts, attempt)
+	r.retryAt[d.ID] = retryAt
+	d.Attempts = attempt.Number
+	if retryAt != nil {
+		r.pending = append(r.pending, d)
+	}
+
+	r.failures++
+	if !r.disabled && r.failures >= disableAfter {
+		r.disabled = true
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func newDelivery(id int64, url string) *webhook.Delivery {
+	return &webhook.Delivery{
+		ID:             id,
+		SubscriptionID: "subscription",
+		EventID:        "event-" + strconv.FormatInt(id, 10),
+		EventType:      "order.processed",
+		Body:           []byte(`{"type":"order.processed","data":{"order_number":"12345678903"}}`),
+		Status:         webhook.DeliveryPending,
+		URL:            url,
+		Secret:         testSecret,
+	}
+}
+
+func newTestDeliverer(repo webhook.Repository) *Deliverer {
+	return NewDeliverer(repo, http.DefaultClient, DelivererConfig{
+		BatchSize:    10,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   time.Minute,
+		DisableAfter: 3,
+	}, zap.NewNop().Sugar())
+}
+
+func TestDeliverer_DeliverBatch(t *testing.T) {
+	t.Run("signed request is delivered", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			unix, err := strconv.ParseInt(r.Header.Get(webhook.TimestampHeader), 10, 64)
+			require.NoError(t, err)
+
+			assert.True(t, webhook.Verify(testSecret, time.Unix(unix, 0), body, r.Header.Get(webhook.SignatureHeader)))
+			assert.Equal(t, "order.processed", r.Header.Get(webhook.EventHeader))
+			assert.Equal(t, "1", r.Header.Get(webhook.DeliveryHeader))
+
+			w.Write([]byte(`{"ok":true}`))
+		}))
+		defer server.Close()
+
+		repo := &memoryRepository{pending: []*webhook.Delivery{newDelivery(1, server.URL)}, retryAt: map[int64]*time.Time{}}
+		n, err := newTestDeliverer(repo).DeliverBatch(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		require.Len(t, repo.attempts, 1)
+		assert.Equal(t, http.StatusOK, repo.attempts[0].ResponseStatus)
+		assert.Equal(t, `{"ok":true}`, repo.attempts[0].ResponseSnippet)
+		assert.Contains(t, repo.attempts[0].RequestSnippet, "12345678903")
+	})
+
+	t.Run("failed delivery is retried with backoff and gives up", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		repo := &memoryRepository{pending: []*webhook.Delivery{newDelivery(1, server.URL)}, retryAt: map[int64]*time.Time{}}
+		deliverer := newTestDeliverer(repo)
+		deliverer.config.DisableAfter = 10
+
+		_, err := deliverer.DeliverBatch(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, repo.retryAt[1])
+		assert.Equal(t, time.Second, repo.retryAt[1].Sub(repo.attempts[0].OccurredAt))
+		assert.Equal(t, "unexpected response status 503", repo.attempts[0].Error)
+
+		_, err = deliverer.DeliverBatch(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2*time.Second, repo.retryAt[1].Sub(repo.attempts[1].OccurredAt))
+
+		_, err = deliverer.DeliverBatch(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, repo.retryAt[1])
+		assert.Empty(t, repo.pending)
+	})
+
+	t.Run("endpoint that keeps failing is disabled", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		repo := &memoryRepository{retryAt: map[int64]*time.Time{}}
+		for i := range 5 {
+			repo.pending = append(repo.pending, newDelivery(int64(i+1), server.URL))
+		}
+
+		deliverer := newTestDeliverer(repo)
+		deliverer.config.BatchSize = 1
+		for range 5 {
+			_, err := deliverer.DeliverBatch(context.Background())
+			require.NoError(t, err)
+		}
+
+		assert.True(t, repo.disabled)
+		assert.Len(t, repo.attempts, 3)
+	})
+}
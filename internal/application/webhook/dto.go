@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CreateSubscriptionDTO - DTO для регистрации webhook партнёра
+type CreateSubscriptionDTO struct {
+	Partner    string   `json:"partner"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+}
+
+// SubscriptionDTO - DTO подписки для ответа, секрет не возвращается
+type SubscriptionDTO struct {
+	ID                  string     `json:"id"`
+	Partner             string     `json:"partner"`
+	URL                 string     `json:"url"`
+	EventTypes          []string   `json:"event_types"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// AttemptDTO - DTO записи журнала отправки
+type AttemptDTO struct {
+	Attempt         int       `json:"attempt"`
+	Request         string    `json:"request"`
+	ResponseStatus  int       `json:"response_status,omitempty"`
+	Response        string    `json:"response,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	OccurredAt      time.Time `json:"occurred_at"`
+}
+
+// DeliveryDTO - DTO отправки события подписке вместе с журналом попыток
+type DeliveryDTO struct {
+	ID            int64           `json:"id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Body          json.RawMessage `json:"body"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	Log           []*AttemptDTO   `json:"log,omitempty"`
+}
+
+// payload - тело запроса к партнёру
+type payload struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
@@ -0,0 +1,12 @@
+package webhook
+
+import webhookDomain "github.com/sviatilnik/gophermart/internal/domain/webhook"
+
+var (
+	ErrSubscriptionNotFound = webhookDomain.ErrSubscriptionNotFound
+	ErrEmptyPartner         = webhookDomain.ErrEmptyPartner
+	ErrInvalidURL           = webhookDomain.ErrInvalidURL
+	ErrNoEventTypes         = webhookDomain.ErrNoEventTypes
+	ErrUnsupportedEvent     = webhookDomain.ErrUnsupportedEvent
+	ErrWeakSecret           = webhookDomain.ErrWeakSecret
+)
@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/webhook"
+	"go.uber.org/zap"
+)
+
+func RegisterEventHandlers(bus events.Bus, webhookService *Service) error {
+	var errs []error
+	for _, eventType := range webhook.Events {
+		errs = append(errs, bus.Subscribe(eventType, "webhook.enqueue", func(ctx context.Context, e events.Event, logger *zap.SugaredLogger) error {
+			return webhookService.Enqueue(ctx, e)
+		}))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/webhook"
+)
+
+// Encoder кодирует событие в версионированный формат, тот же, что пишется в outbox
+type Encoder interface {
+	Encode(event events.Event) (int, []byte, error)
+}
+
+type Service struct {
+	repo    webhook.Repository
+	encoder Encoder
+}
+
+func NewService(repo webhook.Repository, encoder Encoder) *Service {
+	return &Service{
+		repo:    repo,
+		encoder: encoder,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, req CreateSubscriptionDTO) (*SubscriptionDTO, error) {
+	subscription, err := webhook.NewSubscription(req.Partner, req.URL, req.EventTypes, req.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.Create(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	return toSubscriptionDTO(subscription), nil
+}
+
+func (s *Service) List(ctx context.Context) ([]*SubscriptionDTO, error) {
+	subscriptions, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*SubscriptionDTO, len(subscriptions))
+	for i, subscription := range subscriptions {
+		result[i] = toSubscriptionDTO(subscription)
+	}
+
+	return result, nil
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*SubscriptionDTO, error) {
+	subscription, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toSubscriptionDTO(subscription), nil
+}
+
+func (s *Service) Delete(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// Enable включает подписку, отключённую из-за ошибок. Накопившиеся отправки уйдут при следующем проходе.
+func (s *Service) Enable(ctx context.Context, id string) (*SubscriptionDTO, error) {
+	err := s.repo.Enable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, id)
+}
+
+// Deliveries - последние отправки подписки с журналом попыток
+func (s *Service) Deliveries(ctx context.Context, subscriptionID string, limit int) ([]*DeliveryDTO, error) {
+	_, err := s.repo.Get(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.Deliveries(ctx, subscriptionID, uint64(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*DeliveryDTO, len(deliveries))
+	for i, d := range deliveries {
+		result[i] = toDeliveryDTO(d)
+	}
+
+	return result, nil
+}
+
+// Enqueue ставит событие в очередь отправки всем подпискам на него
+func (s *Service) Enqueue(ctx context.Context, event events.Event) error {
+	subscriptions, err := s.repo.Matching(ctx, event.GetName())
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+
+	version, data, err := s.encoder.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	// при доставке из outbox у события уже есть идентификатор; повторная доставка даст тот же id
+	md := events.NewMetadata(ctx)
+	if delivery, ok := events.DeliveryFrom(ctx); ok {
+		md = delivery.Metadata
+	}
+
+	body, err := json.Marshal(payload{
+		ID:            md.EventID,
+		Type:          event.GetName(),
+		Version:       version,
+		OccurredAt:    md.OccurredAt,
+		CorrelationID: md.CorrelationID,
+		Data:          data,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]*webhook.Delivery, len(subscriptions))
+	for i, subscription := range subscriptions {
+		deliveries[i] = &webhook.Delivery{
+			SubscriptionID: subscription.ID,
+			EventID:        md.EventID,
+			EventType:      event.GetName(),
+			Body:           body,
+			Status:         webhook.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}
+	}
+
+	return s.repo.Enqueue(ctx, deliveries)
+}
+
+func toSubscriptionDTO(s *webhook.Subscription) *SubscriptionDTO {
+	return &SubscriptionDTO{
+		ID:                  s.ID,
+		Partner:             s.Partner,
+		URL:                 s.URL,
+		EventTypes:          s.EventTypes,
+		Active:              s.Active,
+		ConsecutiveFailures: s.ConsecutiveFailures,
+		DisabledAt:          s.DisabledAt,
+		DisabledReason:      s.DisabledReason,
+		CreatedAt:           s.CreatedAt,
+	}
+}
+
+func toDeliveryDTO(d *webhook.Delivery) *DeliveryDTO {
+	dto := &DeliveryDTO{
+		ID:            d.ID,
+		EventID:       d.EventID,
+		EventType:     d.EventType,
+		Body:          d.Body,
+		Status:        string(d.Status),
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		LastError:     d.LastError,
+		CreatedAt:     d.CreatedAt,
+		DeliveredAt:   d.DeliveredAt,
+	}
+
+	for _, a := range d.Log {
+		dto.Log = append(dto.Log, &AttemptDTO{
+			Attempt:         a.Number,
+			Request:         a.RequestSnippet,
+			ResponseStatus:  a.ResponseStatus,
+			Response:        a.ResponseSnippet,
+			Error:           a.Error,
+			DurationSeconds: a.Duration.Seconds(),
+			OccurredAt:      a.OccurredAt,
+		})
+	}
+
+	return dto
+}
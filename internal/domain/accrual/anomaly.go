@@ -0,0 +1,170 @@
+package accrual
+
+import (
+	"context"
+	"fmt"
+)
+
+// Candidate - ответ системы начислений, который ещё не применён
+type Candidate struct {
+	OrderNumber   string
+	CustomerID    string
+	PreviousState State
+	State         State
+	Amount        float64
+}
+
+// Stats - распределение обработанных начислений
+type Stats struct {
+	Count   int
+	Average float64
+	Max     float64
+}
+
+type AnomalyRule interface {
+	Name() string
+	// Check возвращает причину, по которой начисление подозрительно, или пустую строку
+	Check(ctx context.Context, candidate *Candidate) (string, error)
+}
+
+type AnomalyDetector struct {
+	rules []AnomalyRule
+}
+
+func NewAnomalyDetector(rules ...AnomalyRule) *AnomalyDetector {
+	return &AnomalyDetector{
+		rules: rules,
+	}
+}
+
+// Detect прогоняет начисление через все правила и возвращает сработавшие причины
+func (d *AnomalyDetector) Detect(ctx context.Context, candidate *Candidate) ([]string, error) {
+	reasons := make([]string, 0)
+	for _, rule := range d.rules {
+		reason, err := rule.Check(ctx, candidate)
+		if err != nil {
+			return nil, err
+		}
+
+		if reason != "" {
+			reasons = append(reasons, rule.Name()+": "+reason)
+		}
+	}
+
+	return reasons, nil
+}
+
+// transitions - какие статусы может вернуть система начислений после уже полученного
+var transitions = map[State][]State{
+	"":         {Registered, Processing, Invalid, Processed},
+	New:        {Registered, Processing, Invalid, Processed},
+	Registered: {Registered, Processing, Invalid, Processed},
+	Processing: {Processing, Invalid, Processed},
+}
+
+type TransitionRule struct{}
+
+func NewTransitionRule() *TransitionRule {
+	return &TransitionRule{}
+}
+
+func (r *TransitionRule) Name() string {
+	return "transition"
+}
+
+func (r *TransitionRule) Check(_ context.Context, c *Candidate) (string, error) {
+	if c.Amount < 0 {
+		return fmt.Sprintf("negative amount %.2f", c.Amount), nil
+	}
+
+	if c.Amount > 0 && c.State != Processed {
+		return fmt.Sprintf("amount %.2f in state %s", c.Amount, c.State), nil
+	}
+
+	for _, allowed := range transitions[c.PreviousState] {
+		if allowed == c.State {
+			return "", nil
+		}
+	}
+
+	return fmt.Sprintf("unexpected transition %s -> %s", c.PreviousState, c.State), nil
+}
+
+// MaxAmountRule ограничивает размер одного начисления
+type MaxAmountRule struct {
+	max float64
+}
+
+func NewMaxAmountRule(max float64) *MaxAmountRule {
+	return &MaxAmountRule{
+		max: max,
+	}
+}
+
+func (r *MaxAmountRule) Name() string {
+	return "max_amount"
+}
+
+func (r *MaxAmountRule) Check(_ context.Context, c *Candidate) (string, error) {
+	if r.max > 0 && c.Amount > r.max {
+		return fmt.Sprintf("amount %.2f exceeds limit %.2f", c.Amount, r.max), nil
+	}
+
+	return "", nil
+}
+
+// HistoryRule сравнивает начисление со средним по истории пользователя или всей системы начислений
+type HistoryRule struct {
+	name       string
+	stats      func(ctx context.Context, c *Candidate) (*Stats, error)
+	factor     float64
+	minSamples int
+}
+
+func NewCustomerHistoryRule(repo Repository, factor float64, minSamples int) *HistoryRule {
+	return &HistoryRule{
+		name: "customer_history",
+		stats: func(ctx context.Context, c *Candidate) (*Stats, error) {
+			return repo.CustomerStats(ctx, c.CustomerID)
+		},
+		factor:     factor,
+		minSamples: minSamples,
+	}
+}
+
+func NewProviderHistoryRule(repo Repository, factor float64, minSamples int) *HistoryRule {
+	return &HistoryRule{
+		name: "provider_history",
+		stats: func(ctx context.Context, _ *Candidate) (*Stats, error) {
+			return repo.ProviderStats(ctx)
+		},
+		factor:     factor,
+		minSamples: minSamples,
+	}
+}
+
+func (r *HistoryRule) Name() string {
+	return r.name
+}
+
+func (r *HistoryRule) Check(ctx context.Context, c *Candidate) (string, error) {
+	if r.factor <= 0 || c.Amount <= 0 {
+		return "", nil
+	}
+
+	stats, err := r.stats(ctx, c)
+	if err != nil {
+		return "", err
+	}
+
+	// на короткой истории среднее ничего не говорит
+	if stats.Count < r.minSamples || stats.Average <= 0 {
+		return "", nil
+	}
+
+	if c.Amount > stats.Average*r.factor {
+		return fmt.Sprintf("amount %.2f is more than %.1fx average %.2f", c.Amount, r.factor, stats.Average), nil
+	}
+
+	return "", nil
+}
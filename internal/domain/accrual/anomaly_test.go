@@ -0,0 +1,107 @@
+package accrual
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type statsRepository struct {
+	Repository
+	customer *Stats
+	provider *Stats
+}
+
+func (r *statsRepository) CustomerStats(_ context.Context, _ string) (*Stats, error) {
+	return r.customer, nil
+}
+
+func (r *statsRepository) ProviderStats(_ context.Context) (*Stats, error) {
+	return r.provider, nil
+}
+
+func TestAnomalyDetector_Detect(t *testing.T) {
+	repo := &statsRepository{
+		customer: &Stats{Count: 20, Average: 100, Max: 300},
+		provider: &Stats{Count: 1000, Average: 200, Max: 5000},
+	}
+
+	detector := NewAnomalyDetector(
+		NewTransitionRule(),
+		NewMaxAmountRule(10000),
+		NewCustomerHistoryRule(repo, 10, 5),
+		NewProviderHistoryRule(repo, 20, 5),
+	)
+
+	tests := []struct {
+		name      string
+		candidate *Candidate
+		wantRules []string
+	}{
+		{
+			name:      "regular accrual",
+			candidate: &Candidate{PreviousState: Processing, State: Processed, Amount: 500},
+			wantRules: nil,
+		},
+		{
+			name:      "first response without accrual",
+			candidate: &Candidate{State: Registered},
+			wantRules: nil,
+		},
+		{
+			name:      "downgrade of processed order",
+			candidate: &Candidate{PreviousState: Processed, State: Processing},
+			wantRules: []string{"transition"},
+		},
+		{
+			name:      "unknown status",
+			candidate: &Candidate{PreviousState: Processing, State: "REFUNDED"},
+			wantRules: []string{"transition"},
+		},
+		{
+			name:      "amount for unprocessed order",
+			candidate: &Candidate{PreviousState: Registered, State: Processing, Amount: 10},
+			wantRules: []string{"transition"},
+		},
+		{
+			name:      "far above customer history",
+			candidate: &Candidate{PreviousState: Processing, State: Processed, Amount: 1500},
+			wantRules: []string{"customer_history"},
+		},
+		{
+			name:      "far above everything",
+			candidate: &Candidate{PreviousState: Processing, State: Processed, Amount: 50000},
+			wantRules: []string{"max_amount", "customer_history", "provider_history"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons, err := detector.Detect(context.Background(), tt.candidate)
+			require.NoError(t, err)
+			require.Len(t, reasons, len(tt.wantRules))
+
+			for i, rule := range tt.wantRules {
+				assert.Contains(t, reasons[i], rule+":")
+			}
+		})
+	}
+}
+
+func TestHistoryRule_ShortHistory(t *testing.T) {
+	repo := &statsRepository{customer: &Stats{Count: 2, Average: 1}}
+
+	reason, err := NewCustomerHistoryRule(repo, 2, 5).Check(context.Background(), &Candidate{State: Processed, Amount: 1000})
+	require.NoError(t, err)
+	assert.Empty(t, reason)
+}
+
+func TestReview_Decide(t *testing.T) {
+	review := NewReview(&Candidate{OrderNumber: "12345678903", State: Processed, Amount: 50000}, []string{"max_amount"})
+
+	require.NoError(t, review.Approve("admin", "checked with vendor"))
+	assert.Equal(t, ReviewApproved, review.Status)
+	assert.NotNil(t, review.DecidedAt)
+	assert.ErrorIs(t, review.Reject("admin", ""), ErrReviewAlreadyDecided)
+}
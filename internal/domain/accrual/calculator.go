@@ -0,0 +1,221 @@
+package accrual
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	RewardPercent = "%"
+	RewardPoints  = "pt"
+
+	accrualSystemRule = "accrual-system"
+)
+
+// RewardRule - правило вознаграждения за товар, формат совпадает с правилами системы начислений
+type RewardRule struct {
+	Match      string  `json:"match"`
+	Reward     float64 `json:"reward"`
+	RewardType string  `json:"reward_type"`
+}
+
+func (r RewardRule) matches(name string) bool {
+	return strings.Contains(strings.ToLower(name), strings.ToLower(r.Match))
+}
+
+func (r RewardRule) points(price float64) float64 {
+	if r.RewardType == RewardPoints {
+		return r.Reward
+	}
+
+	return price * r.Reward / 100
+}
+
+// Promotion - акция, умножающая начисление за подходящие товары в течение срока действия.
+// Пустой Match означает любой товар.
+type Promotion struct {
+	Name       string    `json:"name"`
+	Match      string    `json:"match"`
+	Multiplier float64   `json:"multiplier"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+}
+
+func (p Promotion) appliesTo(name string, at time.Time) bool {
+	if at.Before(p.StartsAt) || (!p.EndsAt.IsZero() && !at.Before(p.EndsAt)) {
+		return false
+	}
+
+	return p.Match == "" || strings.Contains(strings.ToLower(name), strings.ToLower(p.Match))
+}
+
+// Tier - уровень клиента по сумме уже полученных начислений
+type Tier struct {
+	Name       string  `json:"name"`
+	MinAccrued float64 `json:"min_accrued"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// Item - позиция, за которую считается начисление
+type Item struct {
+	Name  string
+	Price float64
+}
+
+// ItemCalculation - расчёт начисления по одной позиции
+type ItemCalculation struct {
+	Item
+	Rule      string
+	Base      float64
+	Promotion string
+	Total     float64
+}
+
+// Calculation - итог расчёта начисления
+type Calculation struct {
+	Items []*ItemCalculation
+	Tier  string
+	Total float64
+}
+
+// Calculator считает начисление: базовые баллы по правилам, затем акции и множитель уровня клиента.
+// Один и тот же расчёт используется для предварительной оценки и для реальных начислений.
+type Calculator struct {
+	rules      []RewardRule
+	promotions []Promotion
+	tiers      []Tier
+	repository Repository
+}
+
+func NewCalculator(rules []RewardRule, promotions []Promotion, tiers []Tier, repository Repository) *Calculator {
+	return &Calculator{
+		rules:      rules,
+		promotions: promotions,
+		tiers:      tiers,
+		repository: repository,
+	}
+}
+
+// Estimate оценивает начисление за позиции чека по настроенным правилам вознаграждения
+func (c *Calculator) Estimate(ctx context.Context, customerID string, items []Item, at time.Time) (*Calculation, error) {
+	return c.apply(ctx, customerID, c.lines(items), at)
+}
+
+// Adjust применяет акции и уровень клиента к начислению, которое посчитала система начислений.
+// Начисление раскладывается по позициям чека пропорционально базовым баллам из правил, как в Estimate,
+// поэтому акции на отдельные товары применяются так же, как в предварительной оценке.
+// Без позиций начисление считается одной строкой, и к нему подходят только акции на любой товар.
+func (c *Calculator) Adjust(ctx context.Context, customerID string, base float64, items []Item, at time.Time) (*Calculation, error) {
+	lines := c.lines(items)
+
+	weights := make([]float64, len(lines))
+	var sum float64
+	for i, line := range lines {
+		weights[i] = line.Base
+		sum += line.Base
+	}
+	// ни одно правило не подошло - раскладываем по ценам
+	if sum == 0 {
+		for i, line := range lines {
+			weights[i] = line.Price
+			sum += line.Price
+		}
+	}
+
+	if sum == 0 {
+		return c.apply(ctx, customerID, []*ItemCalculation{{Rule: accrualSystemRule, Base: base}}, at)
+	}
+
+	for i, line := range lines {
+		line.Rule = accrualSystemRule
+		line.Base = base * weights[i] / sum
+	}
+
+	return c.apply(ctx, customerID, lines, at)
+}
+
+// lines считает базовые баллы позиций по первому подходящему правилу
+func (c *Calculator) lines(items []Item) []*ItemCalculation {
+	lines := make([]*ItemCalculation, len(items))
+	for i, item := range items {
+		lines[i] = &ItemCalculation{Item: item}
+		for _, rule := range c.rules {
+			if rule.matches(item.Name) {
+				lines[i].Rule = rule.Match
+				lines[i].Base = rule.points(item.Price)
+				break
+			}
+		}
+	}
+
+	return lines
+}
+
+func (c *Calculator) apply(ctx context.Context, customerID string, lines []*ItemCalculation, at time.Time) (*Calculation, error) {
+	tier, err := c.tier(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	calc := &Calculation{Items: lines}
+	multiplier := 1.0
+	if tier != nil {
+		calc.Tier = tier.Name
+		multiplier = tier.Multiplier
+	}
+
+	for _, line := range lines {
+		promotion := c.bestPromotion(line.Name, at)
+
+		total := line.Base * multiplier
+		if promotion != nil {
+			line.Promotion = promotion.Name
+			total *= promotion.Multiplier
+		}
+
+		line.Total = round(total)
+		calc.Total += line.Total
+	}
+	calc.Total = round(calc.Total)
+
+	return calc, nil
+}
+
+// bestPromotion - акции не суммируются, берётся самая выгодная
+func (c *Calculator) bestPromotion(name string, at time.Time) *Promotion {
+	var best *Promotion
+	for i, p := range c.promotions {
+		if p.appliesTo(name, at) && (best == nil || p.Multiplier > best.Multiplier) {
+			best = &c.promotions[i]
+		}
+	}
+
+	return best
+}
+
+func (c *Calculator) tier(ctx context.Context, customerID string) (*Tier, error) {
+	if len(c.tiers) == 0 {
+		return nil, nil
+	}
+
+	stats, err := c.repository.CustomerStats(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	accrued := stats.Average * float64(stats.Count)
+
+	var current *Tier
+	for i, t := range c.tiers {
+		if accrued >= t.MinAccrued && (current == nil || t.MinAccrued > current.MinAccrued) {
+			current = &c.tiers[i]
+		}
+	}
+
+	return current, nil
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,70 @@
+package accrual
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculator(t *testing.T) {
+	now := time.Now()
+	repo := &statsRepository{customer: &Stats{Count: 10, Average: 150}}
+
+	calculator := NewCalculator(
+		[]RewardRule{
+			{Match: "Bork", Reward: 10, RewardType: RewardPercent},
+			{Match: "Чайник", Reward: 50, RewardType: RewardPoints},
+		},
+		[]Promotion{
+			{Name: "double-bork", Match: "bork", Multiplier: 2, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+			{Name: "expired", Multiplier: 5, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)},
+		},
+		[]Tier{
+			{Name: "silver", MinAccrued: 1000, Multiplier: 1.5},
+			{Name: "gold", MinAccrued: 5000, Multiplier: 2},
+		},
+		repo,
+	)
+
+	calc, err := calculator.Estimate(context.Background(), "customer", []Item{
+		{Name: "Утюг Bork", Price: 1000},
+		{Name: "Чайник", Price: 3000},
+		{Name: "Хлеб", Price: 50},
+	}, now)
+	require.NoError(t, err)
+
+	assert.Equal(t, "silver", calc.Tier)
+	require.Len(t, calc.Items, 3)
+	assert.Equal(t, 300.0, calc.Items[0].Total)
+	assert.Equal(t, "double-bork", calc.Items[0].Promotion)
+	assert.Equal(t, 75.0, calc.Items[1].Total)
+	assert.Equal(t, 0.0, calc.Items[2].Total)
+	assert.Equal(t, 375.0, calc.Total)
+
+	adjusted, err := calculator.Adjust(context.Background(), "customer", 100, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, 150.0, adjusted.Total)
+
+	// система начислений посчитала столько же, сколько правила: итог совпадает с оценкой
+	adjusted, err = calculator.Adjust(context.Background(), "customer", 150, []Item{
+		{Name: "Утюг Bork", Price: 1000},
+		{Name: "Чайник", Price: 3000},
+		{Name: "Хлеб", Price: 50},
+	}, now)
+	require.NoError(t, err)
+	require.Len(t, adjusted.Items, 3)
+	assert.Equal(t, "double-bork", adjusted.Items[0].Promotion)
+	assert.Equal(t, calc.Total, adjusted.Total)
+
+	// без подходящих правил начисление раскладывается по ценам
+	adjusted, err = calculator.Adjust(context.Background(), "customer", 40, []Item{
+		{Name: "Пылесос", Price: 300},
+		{Name: "Хлеб", Price: 100},
+	}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 45.0, adjusted.Items[0].Total)
+	assert.Equal(t, 15.0, adjusted.Items[1].Total)
+}
@@ -3,10 +3,12 @@ package accrual
 type State string
 
 const (
-	New        State = "NEW"
-	Processing State = "PROCESSING"
-	Invalid    State = "INVALID"
-	Processed  State = "PROCESSED"
+	New           State = "NEW"
+	Registered    State = "REGISTERED"
+	Processing    State = "PROCESSING"
+	Invalid       State = "INVALID"
+	Processed     State = "PROCESSED"
+	PendingReview State = "PENDING_REVIEW"
 )
 
 type Accrual struct {
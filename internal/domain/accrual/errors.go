@@ -0,0 +1,8 @@
+package accrual
+
+import "errors"
+
+var (
+	ErrReviewNotFound       = errors.New("accrual review not found")
+	ErrReviewAlreadyDecided = errors.New("accrual review already decided")
+)
@@ -10,3 +10,16 @@ type CreatedEvent struct {
 func (c *CreatedEvent) GetName() string {
 	return "accrual.created"
 }
+
+func (c *CreatedEvent) AggregateType() string {
+	return "accrual"
+}
+
+func (c *CreatedEvent) AggregateID() string {
+	return c.OrderNumber
+}
+
+// PartitionKey - начисления одного покупателя зачисляются в его кошелёк по очереди
+func (c *CreatedEvent) PartitionKey() string {
+	return c.CustomerID
+}
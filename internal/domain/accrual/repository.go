@@ -6,4 +6,6 @@ type Repository interface {
 	Save(ctx context.Context, accrual *Accrual) error
 	Get(ctx context.Context, orderNumber string) (*Accrual, error)
 	GetForOrders(ctx context.Context, orderNumbers []string) (map[string]*Accrual, error)
+	CustomerStats(ctx context.Context, customerID string) (*Stats, error)
+	ProviderStats(ctx context.Context) (*Stats, error)
 }
@@ -0,0 +1,65 @@
+package accrual
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "PENDING"
+	ReviewApproved ReviewStatus = "APPROVED"
+	ReviewRejected ReviewStatus = "REJECTED"
+)
+
+// Review - подозрительное начисление, отложенное до ручной проверки
+type Review struct {
+	ID            string
+	OrderNumber   string
+	CustomerID    string
+	ProviderState State
+	Amount        float64
+	Reasons       []string
+	Status        ReviewStatus
+	CreatedAt     time.Time
+	DecidedAt     *time.Time
+	DecidedBy     string
+	Comment       string
+}
+
+func NewReview(candidate *Candidate, reasons []string) *Review {
+	return &Review{
+		ID:            uuid.NewString(),
+		OrderNumber:   candidate.OrderNumber,
+		CustomerID:    candidate.CustomerID,
+		ProviderState: candidate.State,
+		Amount:        candidate.Amount,
+		Reasons:       reasons,
+		Status:        ReviewPending,
+		CreatedAt:     time.Now(),
+	}
+}
+
+func (r *Review) Approve(by string, comment string) error {
+	return r.decide(ReviewApproved, by, comment)
+}
+
+func (r *Review) Reject(by string, comment string) error {
+	return r.decide(ReviewRejected, by, comment)
+}
+
+func (r *Review) decide(status ReviewStatus, by string, comment string) error {
+	if r.Status != ReviewPending {
+		return ErrReviewAlreadyDecided
+	}
+
+	now := time.Now()
+	r.Status = status
+	r.DecidedAt = &now
+	r.DecidedBy = by
+	r.Comment = comment
+
+	return nil
+}
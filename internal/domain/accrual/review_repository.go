@@ -0,0 +1,11 @@
+package accrual
+
+import "context"
+
+type ReviewRepository interface {
+	Save(ctx context.Context, review *Review) error
+	// Decide сохраняет решение, только если проверка ещё ждёт его; иначе ErrReviewAlreadyDecided
+	Decide(ctx context.Context, review *Review) error
+	Get(ctx context.Context, id string) (*Review, error)
+	List(ctx context.Context, statuses []ReviewStatus, limit uint64, offset uint64) ([]*Review, error)
+}
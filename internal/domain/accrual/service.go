@@ -1,5 +0,0 @@
-package accrual
-
-type Service interface {
-	CheckOrder(orderNumber string) (*Accrual, error)
-}
@@ -1,10 +1,18 @@
 package events
 
-import "go.uber.org/zap"
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
 
 type Bus interface {
-	Publish(event Event) error
-	Subscribe(event string, handler Handler) error
+	Publish(ctx context.Context, event Event) error
+	// Subscribe подписывает handler на событие. Имя подписчика должно быть уникальным среди подписчиков события
+	// и не меняться между версиями: по нему шина помнит, кто уже обработал событие
+	Subscribe(event string, name string, handler Handler) error
+	// Use добавляет middleware, которыми шина оборачивает каждого подписчика
+	Use(middlewares ...Middleware)
 }
 
-type Handler func(event Event, logger *zap.SugaredLogger) error
+type Handler func(ctx context.Context, event Event, logger *zap.SugaredLogger) error
@@ -3,3 +3,17 @@ package events
 type Event interface {
 	GetName() string
 }
+
+// AggregateEvent - событие, относящееся к конкретному агрегату.
+// Outbox сохраняет его тип и идентификатор вместе с событием.
+type AggregateEvent interface {
+	Event
+	AggregateType() string
+	AggregateID() string
+}
+
+// PartitionedEvent - событие с ключом партиции. События с одинаковым ключом доставляются строго по очереди.
+type PartitionedEvent interface {
+	Event
+	PartitionKey() string
+}
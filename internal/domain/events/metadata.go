@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Metadata - конверт события: кто и в ответ на что его опубликовал
+type Metadata struct {
+	EventID string `json:"event_id"`
+	// CorrelationID общий для всей цепочки событий, начатой одним запросом
+	CorrelationID string `json:"correlation_id"`
+	// CausationID - запрос или событие, непосредственно вызвавшее это событие
+	CausationID string    `json:"causation_id,omitempty"`
+	Actor       string    `json:"actor,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// Trace - контекст действия, которое публикует события
+type Trace struct {
+	CorrelationID string `json:"correlation_id,omitempty"`
+	CausationID   string `json:"causation_id,omitempty"`
+	Actor         string `json:"actor,omitempty"`
+}
+
+// SystemActor - автор событий, опубликованных фоновыми задачами
+const SystemActor = "system"
+
+type traceKey struct{}
+
+func WithTrace(ctx context.Context, trace Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+func TraceFrom(ctx context.Context) Trace {
+	trace, _ := ctx.Value(traceKey{}).(Trace)
+	return trace
+}
+
+// WithActor дополняет трассировку контекста автором действия
+func WithActor(ctx context.Context, actor string) context.Context {
+	trace := TraceFrom(ctx)
+	trace.Actor = actor
+
+	return WithTrace(ctx, trace)
+}
+
+// NewMetadata создаёт конверт для нового события. Без трассировки в контексте событие начинает новую цепочку.
+func NewMetadata(ctx context.Context) Metadata {
+	trace := TraceFrom(ctx)
+	md := Metadata{
+		EventID:       uuid.NewString(),
+		CorrelationID: trace.CorrelationID,
+		CausationID:   trace.CausationID,
+		Actor:         trace.Actor,
+		OccurredAt:    time.Now(),
+	}
+	if md.CorrelationID == "" {
+		md.CorrelationID = md.EventID
+	}
+	if md.Actor == "" {
+		md.Actor = SystemActor
+	}
+
+	return md
+}
+
+// Caused - трассировка для действий подписчика: события, которые он опубликует, вызваны этим событием
+func (m Metadata) Caused() Trace {
+	return Trace{
+		CorrelationID: m.CorrelationID,
+		CausationID:   m.EventID,
+		Actor:         m.Actor,
+	}
+}
@@ -0,0 +1,27 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMetadata(t *testing.T) {
+	root := NewMetadata(context.Background())
+	assert.NotEmpty(t, root.EventID)
+	assert.Equal(t, root.EventID, root.CorrelationID)
+	assert.Empty(t, root.CausationID)
+	assert.Equal(t, SystemActor, root.Actor)
+
+	ctx := WithActor(WithTrace(context.Background(), Trace{CorrelationID: "request-1", CausationID: "request-1"}), "user:42")
+	first := NewMetadata(ctx)
+	assert.Equal(t, "request-1", first.CorrelationID)
+	assert.Equal(t, "request-1", first.CausationID)
+	assert.Equal(t, "user:42", first.Actor)
+
+	second := NewMetadata(WithTrace(context.Background(), first.Caused()))
+	assert.Equal(t, "request-1", second.CorrelationID)
+	assert.Equal(t, first.EventID, second.CausationID)
+	assert.Equal(t, "user:42", second.Actor)
+}
@@ -0,0 +1,34 @@
+package events
+
+import "context"
+
+// Middleware оборачивает подписчика так же, как middleware в chi оборачивает http.Handler
+type Middleware func(next Handler) Handler
+
+// Chain оборачивает handler в middlewares; первый middleware оказывается внешним
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// Delivery - сведения о текущей доставке события подписчику
+type Delivery struct {
+	Metadata  Metadata
+	EventName string
+	Handler   string
+	Attempt   int
+}
+
+type deliveryKey struct{}
+
+func WithDelivery(ctx context.Context, delivery Delivery) context.Context {
+	return context.WithValue(ctx, deliveryKey{}, delivery)
+}
+
+func DeliveryFrom(ctx context.Context) (Delivery, bool) {
+	delivery, ok := ctx.Value(deliveryKey{}).(Delivery)
+	return delivery, ok
+}
@@ -0,0 +1,56 @@
+package ingestion
+
+import "time"
+
+type Status string
+
+const (
+	Done   Status = "DONE"
+	Failed Status = "FAILED"
+)
+
+// LineError - строка файла, по которой заказ не создан
+type LineError struct {
+	Line   int    `json:"line"`
+	Number string `json:"number,omitempty"`
+	Error  string `json:"error"`
+}
+
+// File - результат загрузки файла партнёра. Контрольная сумма не даёт загрузить один файл дважды.
+type File struct {
+	Checksum    string      `json:"checksum"`
+	Name        string      `json:"file"`
+	Status      Status      `json:"status"`
+	Lines       int         `json:"lines"`
+	Accepted    int         `json:"accepted"`
+	Skipped     int         `json:"skipped"`
+	Errors      []LineError `json:"errors"`
+	ProcessedAt time.Time   `json:"processed_at"`
+}
+
+func NewFile(name string, checksum string) *File {
+	return &File{
+		Name:     name,
+		Checksum: checksum,
+		Status:   Done,
+		Errors:   make([]LineError, 0),
+	}
+}
+
+func (f *File) AddError(line int, number string, err error) {
+	f.Errors = append(f.Errors, LineError{Line: line, Number: number, Error: err.Error()})
+}
+
+// Fail помечает файл неразобранным целиком
+func (f *File) Fail(err error) {
+	f.AddError(0, "", err)
+	f.Finish()
+}
+
+// Finish фиксирует итог: файл с ошибками хотя бы в одной строке считается неуспешным
+func (f *File) Finish() {
+	if len(f.Errors) > 0 {
+		f.Status = Failed
+	}
+	f.ProcessedAt = time.Now()
+}
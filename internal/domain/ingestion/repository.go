@@ -0,0 +1,8 @@
+package ingestion
+
+import "context"
+
+type Repository interface {
+	Exists(ctx context.Context, checksum string) (bool, error)
+	Save(ctx context.Context, file *File) error
+}
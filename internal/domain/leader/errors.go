@@ -0,0 +1,7 @@
+package leader
+
+import "errors"
+
+var (
+	ErrLeaseNotFound = errors.New("lease not found")
+)
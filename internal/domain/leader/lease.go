@@ -0,0 +1,15 @@
+package leader
+
+import "time"
+
+type Lease struct {
+	Name       string
+	HolderID   string
+	AcquiredAt time.Time
+	RenewedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+func (l *Lease) IsExpired(now time.Time) bool {
+	return !l.ExpiresAt.After(now)
+}
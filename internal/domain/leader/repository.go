@@ -0,0 +1,14 @@
+package leader
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// TryAcquire захватывает аренду, если она свободна или истекла, либо продлевает свою.
+	// Возвращает false, если аренда удерживается другим экземпляром.
+	TryAcquire(ctx context.Context, name string, holderID string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name string, holderID string) error
+	Get(ctx context.Context, name string) (*Lease, error)
+}
@@ -0,0 +1,26 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrUnknownState      = errors.New("unknown order state")
+	ErrInvalidTransition = errors.New("invalid order state transition")
+)
+
+// TransitionError - попытка перевести заказ в статус, недопустимый из текущего
+type TransitionError struct {
+	From State
+	To   State
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", ErrInvalidTransition, e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
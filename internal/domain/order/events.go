@@ -0,0 +1,105 @@
+package order
+
+import "time"
+
+// AggregateType - тип агрегата заказа в outbox
+const AggregateType = "order"
+
+type Uploaded struct {
+	OrderID     string
+	OrderNumber string
+	CustomerID  string
+	UploadedAt  time.Time
+	Items       []LineItem
+}
+
+func (e *Uploaded) GetName() string {
+	return "order.uploaded"
+}
+
+func (e *Uploaded) AggregateType() string {
+	return AggregateType
+}
+
+func (e *Uploaded) AggregateID() string {
+	return e.OrderID
+}
+
+func (e *Uploaded) PartitionKey() string {
+	return e.CustomerID
+}
+
+// BatchUploaded публикуется один раз на пакетную загрузку, чтобы заказы проверялись вместе
+type BatchUploaded struct {
+	Orders []*Uploaded
+}
+
+func (e *BatchUploaded) GetName() string {
+	return "order.batch_uploaded"
+}
+
+// AggregateType - пакет загружает один покупатель, поэтому событие относится к нему
+func (e *BatchUploaded) AggregateType() string {
+	return "customer"
+}
+
+func (e *BatchUploaded) AggregateID() string {
+	if len(e.Orders) == 0 {
+		return ""
+	}
+
+	return e.Orders[0].CustomerID
+}
+
+func (e *BatchUploaded) PartitionKey() string {
+	return e.AggregateID()
+}
+
+// ProcessedEvent публикуется, когда заказ впервые переходит в PROCESSED и начисление можно зачислять
+type ProcessedEvent struct {
+	OrderID     string
+	OrderNumber string
+	CustomerID  string
+	Accrual     float64
+}
+
+func (e *ProcessedEvent) GetName() string {
+	return "order.processed"
+}
+
+func (e *ProcessedEvent) AggregateType() string {
+	return AggregateType
+}
+
+func (e *ProcessedEvent) AggregateID() string {
+	return e.OrderID
+}
+
+func (e *ProcessedEvent) PartitionKey() string {
+	return e.CustomerID
+}
+
+// CancelledEvent публикуется при отмене заказа пользователем или администратором
+type CancelledEvent struct {
+	OrderID       string
+	OrderNumber   string
+	CustomerID    string
+	PreviousState State
+	Cause         string
+}
+
+func (e *CancelledEvent) GetName() string {
+	return "order.cancelled"
+}
+
+func (e *CancelledEvent) AggregateType() string {
+	return AggregateType
+}
+
+func (e *CancelledEvent) AggregateID() string {
+	return e.OrderID
+}
+
+func (e *CancelledEvent) PartitionKey() string {
+	return e.CustomerID
+}
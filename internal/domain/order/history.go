@@ -0,0 +1,66 @@
+package order
+
+import "time"
+
+// Event - событие истории заказа, из которых восстанавливается его состояние.
+// Это не события шины (Uploaded, ProcessedEvent и т.д.): история хранится вместе с заказом и наружу не публикуется.
+type Event interface {
+	GetType() string
+	GetOccurredAt() time.Time
+}
+
+// OrderUploaded - первое событие истории, заказ загружен покупателем
+type OrderUploaded struct {
+	OrderID    string
+	Number     Number
+	CustomerID string
+	Metadata   *Metadata
+	OccurredAt time.Time
+}
+
+func (e *OrderUploaded) GetType() string { return "uploaded" }
+
+func (e *OrderUploaded) GetOccurredAt() time.Time { return e.OccurredAt }
+
+// OrderStatusChanged - переход по таблице статусов, кроме отмены
+type OrderStatusChanged struct {
+	From       State
+	To         State
+	Cause      string
+	OccurredAt time.Time
+}
+
+func (e *OrderStatusChanged) GetType() string { return "status_changed" }
+
+func (e *OrderStatusChanged) GetOccurredAt() time.Time { return e.OccurredAt }
+
+// OrderCancelled - отмена покупателем или администратором
+type OrderCancelled struct {
+	From       State
+	Cause      string
+	OccurredAt time.Time
+}
+
+func (e *OrderCancelled) GetType() string { return "cancelled" }
+
+func (e *OrderCancelled) GetOccurredAt() time.Time { return e.OccurredAt }
+
+// OrderCredited - по заказу начислены баллы; сам кошелёк пополняется по событию order.processed
+type OrderCredited struct {
+	Amount     float64
+	OccurredAt time.Time
+}
+
+func (e *OrderCredited) GetType() string { return "credited" }
+
+func (e *OrderCredited) GetOccurredAt() time.Time { return e.OccurredAt }
+
+// FromHistory восстанавливает заказ по его истории
+func FromHistory(history []Event) *Order {
+	o := &Order{}
+	for _, e := range history {
+		o.apply(e)
+	}
+
+	return o
+}
@@ -0,0 +1,48 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var ErrInvalidMetadata = errors.New("invalid order metadata")
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// LineItem - позиция чека
+type LineItem struct {
+	Name  string
+	Price float64
+}
+
+// Metadata - необязательные сведения о покупке, к которой относится заказ
+type Metadata struct {
+	MerchantID     string
+	PurchaseAmount float64
+	Currency       string
+	PurchasedAt    *time.Time
+	Items          []LineItem
+}
+
+func (m *Metadata) Validate() error {
+	if m.PurchaseAmount < 0 {
+		return fmt.Errorf("%w: purchase amount must not be negative", ErrInvalidMetadata)
+	}
+
+	if m.Currency != "" && !currencyPattern.MatchString(m.Currency) {
+		return fmt.Errorf("%w: currency must be an ISO 4217 code", ErrInvalidMetadata)
+	}
+
+	for i, item := range m.Items {
+		if item.Name == "" {
+			return fmt.Errorf("%w: item %d has no name", ErrInvalidMetadata, i)
+		}
+		if item.Price < 0 {
+			return fmt.Errorf("%w: item %d has negative price", ErrInvalidMetadata, i)
+		}
+	}
+
+	return nil
+}
@@ -2,7 +2,7 @@ package order
 
 import (
 	"errors"
-	"unicode"
+	"fmt"
 )
 
 var (
@@ -11,32 +11,30 @@ var (
 
 type Number string
 
+// NewOrderNumber проверяет номер схемой по умолчанию - цифры и алгоритм Луна
 func NewOrderNumber(number string) (Number, error) {
-	if !isValid(number) {
-		return "", ErrOrderNumberNotValid
+	return ParseNumber(number, DefaultValidator())
+}
+
+func ParseNumber(number string, validator NumberValidator) (Number, error) {
+	err := validator.Validate(number)
+	if err != nil {
+		return "", err
 	}
 
 	return Number(number), nil
 }
 
-func isValid(number string) bool {
-	sum := 0
-	parity := len(number) % 2
-
-	for i, char := range number {
-		if !unicode.IsDigit(char) {
-			return false
-		}
-
-		digit := int(char - '0')
-		if i%2 == parity {
-			digit *= 2
-			if digit > 9 {
-				digit -= 9
-			}
-		}
-		sum += digit
-	}
+// ValidationError - номер не прошёл конкретное правило проверки
+type ValidationError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrOrderNumberNotValid, e.Rule, e.Reason)
+}
 
-	return sum%10 == 0
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrOrderNumberNotValid
 }
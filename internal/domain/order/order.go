@@ -1,24 +1,157 @@
 package order
 
 import (
-	"github.com/google/uuid"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+const (
+	CauseAccrualSystem = "accrual-system"
+	CauseAdmin         = "admin"
+)
+
+// Order - агрегат заказа. Состояние заказа - результат применения его истории событий,
+// таблица orders хранит только проекцию последнего состояния для запросов.
 type Order struct {
 	ID         string
 	Number     Number
 	CustomerID string
 	CreatedAt  time.Time
 	State      State
+	// Metadata - сведения о покупке, nil если пользователь загрузил только номер
+	Metadata *Metadata
+	// Version - число событий в истории заказа, включая ещё не сохранённые
+	Version int
+	// Credited - сколько баллов начислено по заказу
+	Credited float64
+
+	changes     []Event
+	transitions []*Transition
 }
 
-func NewOrder(number Number, customerID string) *Order {
-	return &Order{
-		ID:         uuid.NewString(),
+func NewOrder(number Number, customerID string, metadata *Metadata) *Order {
+	o := &Order{}
+	o.record(&OrderUploaded{
+		OrderID:    uuid.NewString(),
 		Number:     number,
 		CustomerID: customerID,
-		CreatedAt:  time.Now(),
-		State:      New,
+		Metadata:   metadata,
+		OccurredAt: time.Now(),
+	})
+
+	return o
+}
+
+// TransitionTo переводит заказ в новый статус и запоминает переход для истории.
+// Повторная установка текущего статуса ничего не меняет.
+func (o *Order) TransitionTo(to State, cause string) error {
+	if !to.IsKnown() {
+		return ErrUnknownState
+	}
+
+	if o.State == to {
+		return nil
+	}
+
+	if !o.State.CanTransitionTo(to) {
+		return &TransitionError{From: o.State, To: to}
+	}
+
+	if to == Cancelled {
+		o.record(&OrderCancelled{From: o.State, Cause: cause, OccurredAt: time.Now()})
+		return nil
+	}
+
+	o.record(&OrderStatusChanged{From: o.State, To: to, Cause: cause, OccurredAt: time.Now()})
+
+	return nil
+}
+
+// ForceCancel отменяет заказ в любом статусе в обход таблицы переходов.
+// Используется только администратором.
+func (o *Order) ForceCancel(cause string) {
+	if o.State == Cancelled {
+		return
+	}
+
+	o.record(&OrderCancelled{From: o.State, Cause: cause, OccurredAt: time.Now()})
+}
+
+// Credit записывает в историю начисление баллов за обработанный заказ
+func (o *Order) Credit(amount float64) error {
+	if o.State != Processed {
+		return &TransitionError{From: o.State, To: Processed}
 	}
+
+	if amount <= 0 || o.Credited > 0 {
+		return nil
+	}
+
+	o.record(&OrderCredited{Amount: amount, OccurredAt: time.Now()})
+
+	return nil
+}
+
+func (o *Order) record(e Event) {
+	o.changes = append(o.changes, e)
+	o.apply(e)
+
+	// переходы статусов дублируются в order_state_transitions для ленты заказа
+	switch e := e.(type) {
+	case *OrderUploaded:
+		o.transitions = append(o.transitions, newTransition(o.ID, "", New, CustomerCause(o.CustomerID), e.OccurredAt))
+	case *OrderStatusChanged:
+		o.transitions = append(o.transitions, newTransition(o.ID, e.From, e.To, e.Cause, e.OccurredAt))
+	case *OrderCancelled:
+		o.transitions = append(o.transitions, newTransition(o.ID, e.From, Cancelled, e.Cause, e.OccurredAt))
+	}
+}
+
+func (o *Order) apply(e Event) {
+	switch e := e.(type) {
+	case *OrderUploaded:
+		o.ID = e.OrderID
+		o.Number = e.Number
+		o.CustomerID = e.CustomerID
+		o.Metadata = e.Metadata
+		o.CreatedAt = e.OccurredAt
+		o.State = New
+	case *OrderStatusChanged:
+		o.State = e.To
+	case *OrderCancelled:
+		o.State = Cancelled
+	case *OrderCredited:
+		o.Credited += e.Amount
+	}
+
+	o.Version++
+}
+
+// Changes возвращает события, ещё не сохранённые в репозитории
+func (o *Order) Changes() []Event {
+	return o.changes
+}
+
+// Transitions возвращает переходы, ещё не сохранённые в репозитории
+func (o *Order) Transitions() []*Transition {
+	return o.transitions
+}
+
+// ClearChanges вызывается репозиторием после сохранения
+func (o *Order) ClearChanges() {
+	o.changes = nil
+	o.transitions = nil
+}
+
+func CustomerCause(customerID string) string {
+	return "customer:" + customerID
+}
+
+func AdminCause(reason string) string {
+	if reason == "" {
+		return CauseAdmin
+	}
+
+	return CauseAdmin + ":" + reason
 }
@@ -0,0 +1,141 @@
+package order
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrder_TransitionTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    []State
+		want    State
+		wantErr error
+	}{
+		{
+			name: "regular processing",
+			path: []State{Processing, Processed},
+			want: Processed,
+		},
+		{
+			name: "processed right away",
+			path: []State{Processed},
+			want: Processed,
+		},
+		{
+			name: "repeated status",
+			path: []State{Processing, Processing},
+			want: Processing,
+		},
+		{
+			name:    "downgrade of processed order",
+			path:    []State{Processed, Processing},
+			want:    Processed,
+			wantErr: ErrInvalidTransition,
+		},
+		{
+			name:    "invalid order can not be processed",
+			path:    []State{Invalid, Processed},
+			want:    Invalid,
+			wantErr: ErrInvalidTransition,
+		},
+		{
+			name: "cancelled while new",
+			path: []State{Cancelled},
+			want: Cancelled,
+		},
+		{
+			name:    "processing order can not be cancelled",
+			path:    []State{Processing, Cancelled},
+			want:    Processing,
+			wantErr: ErrInvalidTransition,
+		},
+		{
+			name:    "cancelled order can not be processed",
+			path:    []State{Cancelled, Processed},
+			want:    Cancelled,
+			wantErr: ErrInvalidTransition,
+		},
+		{
+			name:    "unknown status",
+			path:    []State{"REGISTERED"},
+			want:    New,
+			wantErr: ErrUnknownState,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewOrder("79927398713", "customer", nil)
+
+			var err error
+			for _, state := range tt.path {
+				err = o.TransitionTo(state, CauseAccrualSystem)
+			}
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, o.State)
+		})
+	}
+}
+
+func TestOrder_TransitionsHistory(t *testing.T) {
+	o := NewOrder("79927398713", "customer", nil)
+	require.NoError(t, o.TransitionTo(Processing, CauseAccrualSystem))
+	require.NoError(t, o.TransitionTo(Processed, CauseAccrualSystem))
+
+	history := o.Transitions()
+	require.Len(t, history, 3)
+
+	assert.Equal(t, State(""), history[0].From)
+	assert.Equal(t, New, history[0].To)
+	assert.Equal(t, "customer:customer", history[0].Cause)
+	assert.Equal(t, Processing, history[2].From)
+	assert.Equal(t, Processed, history[2].To)
+	assert.Equal(t, CauseAccrualSystem, history[2].Cause)
+
+	var transitionErr *TransitionError
+	assert.ErrorAs(t, o.TransitionTo(New, CauseAccrualSystem), &transitionErr)
+	assert.Equal(t, Processed, transitionErr.From)
+}
+
+func TestOrder_ForceCancel(t *testing.T) {
+	o := NewOrder("79927398713", "customer", nil)
+	require.NoError(t, o.TransitionTo(Processed, CauseAccrualSystem))
+	o.ClearChanges()
+
+	o.ForceCancel(AdminCause("wrong receipt"))
+	o.ForceCancel(AdminCause("repeated"))
+
+	assert.Equal(t, Cancelled, o.State)
+	require.Len(t, o.Transitions(), 1)
+	assert.Equal(t, Processed, o.Transitions()[0].From)
+	assert.Equal(t, "admin:wrong receipt", o.Transitions()[0].Cause)
+}
+
+func TestOrder_FromHistory(t *testing.T) {
+	o := NewOrder("79927398713", "customer", &Metadata{MerchantID: "merchant-1"})
+	require.NoError(t, o.TransitionTo(Processing, CauseAccrualSystem))
+	require.NoError(t, o.TransitionTo(Processed, CauseAccrualSystem))
+	require.NoError(t, o.Credit(120))
+	require.NoError(t, o.Credit(120))
+	o.ForceCancel(AdminCause("fraud"))
+
+	history := o.Changes()
+	require.Len(t, history, 5)
+	assert.Equal(t, "credited", history[3].GetType())
+	assert.Equal(t, "cancelled", history[4].GetType())
+
+	restored := FromHistory(history)
+	assert.Equal(t, o.ID, restored.ID)
+	assert.Equal(t, Cancelled, restored.State)
+	assert.Equal(t, 120.0, restored.Credited)
+	assert.Equal(t, "merchant-1", restored.Metadata.MerchantID)
+	assert.Equal(t, 5, restored.Version)
+	assert.Empty(t, restored.Changes())
+}
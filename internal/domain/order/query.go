@@ -0,0 +1,10 @@
+package order
+
+import "github.com/sviatilnik/gophermart/internal/domain/pagination"
+
+// ListQuery - фильтры и страница выборки заказов пользователя
+type ListQuery struct {
+	CustomerID string
+	States     []State
+	Page       pagination.Page
+}
@@ -8,11 +8,19 @@ import (
 var (
 	ErrAlreadyExists                 = errors.New("order already exists")
 	ErrAlreadyCreatedByOtherCustomer = errors.New("order already created by other customer")
+	ErrVersionConflict               = errors.New("order version conflict")
 )
 
 type Repository interface {
+	// Get восстанавливает заказ по истории событий
 	Get(ctx context.Context, number Number) (*Order, error)
+	GetHistory(ctx context.Context, number Number) ([]Event, error)
+	// Save дописывает новые события заказа и обновляет проекцию в orders
 	Save(ctx context.Context, order *Order) error
-	GetForCustomer(ctx context.Context, customerID string, limit uint64, offset uint64) ([]*Order, error)
+	// SaveBatch вставляет новые заказы одной транзакцией и возвращает
+	// владельцев тех номеров, которые уже были загружены ранее
+	SaveBatch(ctx context.Context, orders []*Order) (map[Number]string, error)
+	List(ctx context.Context, query ListQuery) ([]*Order, error)
 	GetByStates(ctx context.Context, state []State, limit uint64, offset uint64) ([]*Order, error)
+	GetTransitions(ctx context.Context, orderID string) ([]*Transition, error)
 }
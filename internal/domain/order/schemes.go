@@ -0,0 +1,133 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidNumberScheme = errors.New("invalid order number scheme")
+
+// DefaultSource - источник заказа, для которого схема не указана явно
+const DefaultSource = "default"
+
+// NumberSchemes - схемы проверки номеров заказов по источникам (мерчантам)
+type NumberSchemes struct {
+	schemes map[string]NumberValidator
+}
+
+func NewNumberSchemes() *NumberSchemes {
+	return &NumberSchemes{
+		schemes: map[string]NumberValidator{DefaultSource: DefaultValidator()},
+	}
+}
+
+func (s *NumberSchemes) Set(source string, validator NumberValidator) {
+	s.schemes[source] = validator
+}
+
+// For возвращает схему источника, а если она не настроена - схему по умолчанию
+func (s *NumberSchemes) For(source string) NumberValidator {
+	if v, ok := s.schemes[source]; ok {
+		return v
+	}
+
+	return s.schemes[DefaultSource]
+}
+
+// ParseAny принимает номер, подходящий хотя бы под одну схему.
+// Нужен для поиска уже загруженных заказов, когда источник неизвестен.
+func (s *NumberSchemes) ParseAny(number string) (Number, error) {
+	n, err := ParseNumber(number, s.schemes[DefaultSource])
+	if err == nil {
+		return n, nil
+	}
+
+	for source, v := range s.schemes {
+		if source == DefaultSource {
+			continue
+		}
+		if v.Validate(number) == nil {
+			return Number(number), nil
+		}
+	}
+
+	return "", err
+}
+
+// ParseNumberSchemes разбирает описание схем вида
+// "default=luhn;merchant-a=length:12-16,prefix:42|43,iso7064".
+// Номер всегда должен состоять из цифр, остальные правила применяются в указанном порядке.
+// Если длина в схеме не задана, номер ограничен MaxNumberLength.
+func ParseNumberSchemes(spec string) (*NumberSchemes, error) {
+	schemes := NewNumberSchemes()
+
+	for _, scheme := range strings.Split(spec, ";") {
+		scheme = strings.TrimSpace(scheme)
+		if scheme == "" {
+			continue
+		}
+
+		source, rules, ok := strings.Cut(scheme, "=")
+		if !ok || strings.TrimSpace(source) == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidNumberScheme, scheme)
+		}
+
+		validators := []NumberValidator{DigitsValidator{}}
+		hasLength := false
+		for _, rule := range strings.Split(rules, ",") {
+			v, err := parseRule(strings.TrimSpace(rule))
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := v.(LengthValidator); ok {
+				hasLength = true
+			}
+			validators = append(validators, v)
+		}
+		if !hasLength {
+			validators = append(validators, LengthValidator{Min: 1, Max: MaxNumberLength})
+		}
+
+		schemes.Set(strings.TrimSpace(source), NewChainValidator(validators...))
+	}
+
+	return schemes, nil
+}
+
+func parseRule(rule string) (NumberValidator, error) {
+	name, args, _ := strings.Cut(rule, ":")
+
+	switch name {
+	case "digits":
+		return DigitsValidator{}, nil
+	case "luhn":
+		return LuhnValidator{}, nil
+	case "iso7064":
+		return Mod97Validator{}, nil
+	case "prefix":
+		if args == "" {
+			break
+		}
+		return PrefixValidator{Prefixes: strings.Split(args, "|")}, nil
+	case "length":
+		minLen, maxLen, _ := strings.Cut(args, "-")
+		lower, err := strconv.Atoi(minLen)
+		if err != nil {
+			break
+		}
+		upper := 0
+		if maxLen != "" {
+			if upper, err = strconv.Atoi(maxLen); err != nil || upper < lower {
+				break
+			}
+		}
+		if lower < 0 {
+			break
+		}
+		return LengthValidator{Min: lower, Max: upper}, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown rule %q", ErrInvalidNumberScheme, rule)
+}
@@ -7,4 +7,40 @@ const (
 	Processing State = "PROCESSING"
 	Invalid    State = "INVALID"
 	Processed  State = "PROCESSED"
+	Cancelled  State = "CANCELLED"
 )
+
+// transitions - допустимые переходы между статусами заказа.
+// INVALID, PROCESSED и CANCELLED конечные, из них перейти никуда нельзя.
+// Пользователь может отменить заказ, только пока он NEW.
+var transitions = map[State][]State{
+	New:        {Processing, Invalid, Processed, Cancelled},
+	Processing: {Invalid, Processed},
+	Invalid:    {},
+	Processed:  {},
+	Cancelled:  {},
+}
+
+// AwaitingAccrual - статусы заказов, по которым ещё ждём ответа системы начислений
+func AwaitingAccrual() []State {
+	return []State{New, Processing}
+}
+
+func (s State) IsKnown() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+func (s State) IsFinal() bool {
+	return s.IsKnown() && len(transitions[s]) == 0
+}
+
+func (s State) CanTransitionTo(to State) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,28 @@
+package order
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Transition - запись истории смены статуса заказа
+type Transition struct {
+	ID         string
+	OrderID    string
+	From       State
+	To         State
+	Cause      string
+	OccurredAt time.Time
+}
+
+func newTransition(orderID string, from State, to State, cause string, occurredAt time.Time) *Transition {
+	return &Transition{
+		ID:         uuid.NewString(),
+		OrderID:    orderID,
+		From:       from,
+		To:         to,
+		Cause:      cause,
+		OccurredAt: occurredAt,
+	}
+}
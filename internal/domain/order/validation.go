@@ -0,0 +1,137 @@
+package order
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// NumberValidator - правило проверки номера заказа
+type NumberValidator interface {
+	Validate(number string) error
+}
+
+// MaxNumberLength - предельная длина номера для схем, в которых длина не задана явно
+const MaxNumberLength = 32
+
+// DefaultValidator - схема, которая применяется, если для источника заказа ничего не настроено
+func DefaultValidator() NumberValidator {
+	return NewChainValidator(DigitsValidator{}, LengthValidator{Min: 1, Max: MaxNumberLength}, LuhnValidator{})
+}
+
+// ChainValidator применяет правила по порядку и возвращает ошибку первого нарушенного
+type ChainValidator struct {
+	validators []NumberValidator
+}
+
+func NewChainValidator(validators ...NumberValidator) *ChainValidator {
+	return &ChainValidator{validators: validators}
+}
+
+func (c *ChainValidator) Validate(number string) error {
+	for _, v := range c.validators {
+		err := v.Validate(number)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DigitsValidator требует непустой номер только из цифр
+type DigitsValidator struct{}
+
+func (DigitsValidator) Validate(number string) error {
+	if number == "" {
+		return &ValidationError{Rule: "digits", Reason: "number is empty"}
+	}
+
+	for _, char := range number {
+		if !unicode.IsDigit(char) {
+			return &ValidationError{Rule: "digits", Reason: "number must contain only digits"}
+		}
+	}
+
+	return nil
+}
+
+type LuhnValidator struct{}
+
+func (LuhnValidator) Validate(number string) error {
+	sum := 0
+	parity := len(number) % 2
+
+	for i, char := range number {
+		if char < '0' || char > '9' {
+			return &ValidationError{Rule: "luhn", Reason: "number must contain only digits"}
+		}
+
+		digit := int(char - '0')
+		if i%2 == parity {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+
+	if number == "" || sum%10 != 0 {
+		return &ValidationError{Rule: "luhn", Reason: "checksum mismatch"}
+	}
+
+	return nil
+}
+
+// LengthValidator ограничивает длину номера; Max = 0 снимает верхнюю границу
+type LengthValidator struct {
+	Min int
+	Max int
+}
+
+func (v LengthValidator) Validate(number string) error {
+	if v.Max == 0 {
+		if len(number) < v.Min {
+			return &ValidationError{Rule: "length", Reason: fmt.Sprintf("length must be at least %d", v.Min)}
+		}
+		return nil
+	}
+
+	if len(number) < v.Min || len(number) > v.Max {
+		return &ValidationError{Rule: "length", Reason: fmt.Sprintf("length must be between %d and %d", v.Min, v.Max)}
+	}
+
+	return nil
+}
+
+type PrefixValidator struct {
+	Prefixes []string
+}
+
+func (v PrefixValidator) Validate(number string) error {
+	for _, prefix := range v.Prefixes {
+		if strings.HasPrefix(number, prefix) {
+			return nil
+		}
+	}
+
+	return &ValidationError{Rule: "prefix", Reason: "number must start with one of " + strings.Join(v.Prefixes, ", ")}
+}
+
+// Mod97Validator - контрольная сумма ISO/IEC 7064 MOD 97-10: остаток от деления номера на 97 равен 1
+type Mod97Validator struct{}
+
+func (Mod97Validator) Validate(number string) error {
+	n, ok := new(big.Int).SetString(number, 10)
+	if !ok || n.Sign() <= 0 {
+		return &ValidationError{Rule: "iso7064", Reason: "number must contain only digits"}
+	}
+
+	if new(big.Int).Mod(n, big.NewInt(97)).Int64() != 1 {
+		return &ValidationError{Rule: "iso7064", Reason: "checksum mismatch"}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package order
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOrderNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		number   string
+		wantRule string
+	}{
+		{name: "valid luhn", number: "79927398713"},
+		{name: "empty", number: "", wantRule: "digits"},
+		{name: "letters", number: "7992739871a", wantRule: "digits"},
+		{name: "wrong checksum", number: "79927398710", wantRule: "luhn"},
+		{name: "too long", number: "799273987137992739871379927398713", wantRule: "length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewOrderNumber(tt.number)
+			if tt.wantRule == "" {
+				require.NoError(t, err)
+				assert.Equal(t, Number(tt.number), n)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.wantRule, validationErr.Rule)
+			assert.ErrorIs(t, err, ErrOrderNumberNotValid)
+		})
+	}
+}
+
+func TestParseNumberSchemes(t *testing.T) {
+	schemes, err := ParseNumberSchemes("merchant-a=length:8-12,prefix:42|43,luhn; partner=iso7064")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		source   string
+		number   string
+		wantRule string
+	}{
+		{name: "unknown source uses default", source: "web", number: "79927398713"},
+		{name: "merchant number", source: "merchant-a", number: "4200000000"},
+		{name: "merchant number too short", source: "merchant-a", number: "42000", wantRule: "length"},
+		{name: "merchant wrong prefix", source: "merchant-a", number: "79927398713", wantRule: "prefix"},
+		{name: "iso 7064", source: "partner", number: "3214282912345698765432161182"},
+		{name: "iso 7064 mismatch", source: "partner", number: "79927398713", wantRule: "iso7064"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseNumber(tt.number, schemes.For(tt.source))
+			if tt.wantRule == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.wantRule, validationErr.Rule)
+		})
+	}
+
+	_, err = schemes.ParseAny("3214282912345698765432161182")
+	assert.NoError(t, err)
+
+	_, err = ParseNumberSchemes("merchant=crc32")
+	assert.ErrorIs(t, err, ErrInvalidNumberScheme)
+
+	_, err = ParseNumberSchemes("merchant=length:12-8")
+	assert.ErrorIs(t, err, ErrInvalidNumberScheme)
+}
+
+func TestLengthValidator(t *testing.T) {
+	err := LengthValidator{Min: 12}.Validate("42")
+	assert.EqualError(t, err, "order number not valid: length: length must be at least 12")
+
+	err = LengthValidator{Min: 2, Max: 4}.Validate("42424")
+	assert.EqualError(t, err, "order number not valid: length: length must be between 2 and 4")
+}
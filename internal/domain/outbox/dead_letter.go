@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusProcessing Status = "processing"
+	StatusProcessed  Status = "processed"
+	// StatusDeadLetter - подписчики не справились за max_retries попыток, событие ждёт решения администратора
+	StatusDeadLetter Status = "dead_letter"
+	// StatusDiscarded - администратор отказался от доставки события
+	StatusDiscarded Status = "discarded"
+)
+
+// Failure - одна неудачная попытка доставки события
+type Failure struct {
+	Attempt    int
+	Error      string
+	OccurredAt time.Time
+}
+
+// DeadLetter - событие outbox, которое не удалось доставить
+type DeadLetter struct {
+	ID             int64
+	EventID        string
+	EventType      string
+	AggregateType  string
+	AggregateID    string
+	Payload        json.RawMessage
+	Headers        map[string]any
+	Retries        int
+	LastError      string
+	OccurredAt     time.Time
+	DeadLetteredAt time.Time
+	Failures       []*Failure
+}
+
+// Filter отбирает события для просмотра и массовых операций. Пустые поля не ограничивают выборку.
+type Filter struct {
+	IDs           []int64
+	EventType     string
+	From          *time.Time
+	To            *time.Time
+	ErrorContains string
+}
+
+func (f Filter) IsEmpty() bool {
+	return len(f.IDs) == 0 && f.EventType == "" && f.From == nil && f.To == nil && f.ErrorContains == ""
+}
@@ -0,0 +1,8 @@
+package outbox
+
+import "errors"
+
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrEmptyFilter        = errors.New("bulk operation requires at least one filter")
+)
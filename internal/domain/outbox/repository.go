@@ -0,0 +1,12 @@
+package outbox
+
+import "context"
+
+type DeadLetterRepository interface {
+	List(ctx context.Context, filter Filter, limit uint64, offset uint64) ([]*DeadLetter, error)
+	Get(ctx context.Context, id int64) (*DeadLetter, error)
+	UpdateHeaders(ctx context.Context, id int64, headers map[string]any) error
+	// Replay возвращает события в очередь доставки и сообщает, сколько их было
+	Replay(ctx context.Context, filter Filter) (int64, error)
+	Discard(ctx context.Context, filter Filter) (int64, error)
+}
@@ -0,0 +1,121 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidDirection = errors.New("invalid sort direction")
+)
+
+type Direction string
+
+const (
+	Desc Direction = "desc"
+	Asc  Direction = "asc"
+)
+
+func NewDirection(direction string) (Direction, error) {
+	switch Direction(strings.ToLower(direction)) {
+	case "", Desc:
+		return Desc, nil
+	case Asc:
+		return Asc, nil
+	}
+
+	return "", ErrInvalidDirection
+}
+
+// Cursor - позиция в выборке, упорядоченной по (created_at, id).
+// Клиенту отдаётся в непрозрачном виде.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func (c *Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor разбирает курсор; пустая строка означает начало выборки
+func DecodeCursor(encoded string) (*Cursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found || id == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: t, ID: id}, nil
+}
+
+// Page - параметры запроса одной страницы
+type Page struct {
+	After     *Cursor
+	Limit     uint64
+	Direction Direction
+	From      *time.Time
+	To        *time.Time
+}
+
+const (
+	DefaultLimit uint64 = 20
+	MaxLimit     uint64 = 100
+)
+
+// NewPage проверяет параметры страницы; нулевой лимит заменяется значением по умолчанию
+func NewPage(cursor string, limit uint64, direction string, from *time.Time, to *time.Time) (Page, error) {
+	after, err := DecodeCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+
+	dir, err := NewDirection(direction)
+	if err != nil {
+		return Page{}, err
+	}
+
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	return Page{
+		After:     after,
+		Limit:     limit,
+		Direction: dir,
+		From:      from,
+		To:        to,
+	}, nil
+}
+
+// Trim отрезает лишнюю запись, запрошенную сверх лимита, и возвращает курсор следующей страницы
+func Trim[T any](items []T, limit uint64, cursorOf func(item T) Cursor) ([]T, string) {
+	if uint64(len(items)) <= limit {
+		return items, ""
+	}
+
+	items = items[:limit]
+	next := cursorOf(items[len(items)-1])
+
+	return items, next.Encode()
+}
@@ -0,0 +1,61 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_EncodeDecode(t *testing.T) {
+	cursor := &Cursor{CreatedAt: time.Date(2025, 5, 1, 10, 30, 0, 123456789, time.UTC), ID: "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d"}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, cursor.ID, decoded.ID)
+
+	empty, err := DecodeCursor("")
+	assert.NoError(t, err)
+	assert.Nil(t, empty)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, encoded := range []string{"%%%", "bm90LWEtY3Vyc29y", "MjAyNS0wNS0wMXw"} {
+		_, err := DecodeCursor(encoded)
+		assert.ErrorIs(t, err, ErrInvalidCursor, encoded)
+	}
+}
+
+func TestNewDirection(t *testing.T) {
+	d, err := NewDirection("")
+	assert.NoError(t, err)
+	assert.Equal(t, Desc, d)
+
+	d, err = NewDirection("ASC")
+	assert.NoError(t, err)
+	assert.Equal(t, Asc, d)
+
+	_, err = NewDirection("sideways")
+	assert.ErrorIs(t, err, ErrInvalidDirection)
+}
+
+func TestTrim(t *testing.T) {
+	now := time.Now()
+	items := []int{1, 2, 3}
+	cursorOf := func(i int) Cursor {
+		return Cursor{CreatedAt: now, ID: string(rune('0' + i))}
+	}
+
+	page, next := Trim(items, 2, cursorOf)
+	assert.Equal(t, []int{1, 2}, page)
+
+	cursor, err := DecodeCursor(next)
+	require.NoError(t, err)
+	assert.Equal(t, "2", cursor.ID)
+
+	page, next = Trim(items, 3, cursorOf)
+	assert.Equal(t, items, page)
+	assert.Empty(t, next)
+}
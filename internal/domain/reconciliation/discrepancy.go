@@ -0,0 +1,76 @@
+package reconciliation
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Kind string
+
+const (
+	MissingDeposit   Kind = "MISSING_DEPOSIT"
+	DuplicateDeposit Kind = "DUPLICATE_DEPOSIT"
+	AmountMismatch   Kind = "AMOUNT_MISMATCH"
+	MissingAccrual   Kind = "MISSING_ACCRUAL"
+	StuckOrder       Kind = "STUCK_ORDER"
+)
+
+type Status string
+
+const (
+	Open     Status = "OPEN"
+	Repaired Status = "REPAIRED"
+	Resolved Status = "RESOLVED"
+)
+
+type Discrepancy struct {
+	ID             string
+	Kind           Kind
+	Status         Status
+	OrderNumber    string
+	CustomerID     string
+	OrderState     string
+	ExpectedAmount float64
+	ActualAmount   float64
+	Deposits       int
+	DetectedAt     time.Time
+	LastSeenAt     time.Time
+	RepairedAt     *time.Time
+}
+
+func NewDiscrepancy(kind Kind, facts *OrderFacts) *Discrepancy {
+	now := time.Now()
+
+	return &Discrepancy{
+		ID:             uuid.NewString(),
+		Kind:           kind,
+		Status:         Open,
+		OrderNumber:    facts.OrderNumber,
+		CustomerID:     facts.CustomerID,
+		OrderState:     facts.OrderState,
+		ExpectedAmount: facts.AccrualAmount,
+		ActualAmount:   facts.DepositedAmount,
+		Deposits:       facts.Deposits,
+		DetectedAt:     now,
+		LastSeenAt:     now,
+	}
+}
+
+// IsSafeToRepair - расхождение можно исправить автоматически:
+// начисление подтверждено, а в кошелёк так ничего и не попало.
+func (d *Discrepancy) IsSafeToRepair() bool {
+	return d.Status == Open && d.Kind == MissingDeposit && d.ExpectedAmount > 0
+}
+
+func (d *Discrepancy) MarkRepaired() error {
+	if !d.IsSafeToRepair() {
+		return ErrNotRepairable
+	}
+
+	now := time.Now()
+	d.Status = Repaired
+	d.RepairedAt = &now
+
+	return nil
+}
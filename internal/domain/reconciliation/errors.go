@@ -0,0 +1,9 @@
+package reconciliation
+
+import "errors"
+
+var (
+	ErrDiscrepancyNotFound = errors.New("discrepancy not found")
+	ErrNotRepairable       = errors.New("discrepancy can not be repaired automatically")
+	ErrNotOpen             = errors.New("discrepancy is already handled")
+)
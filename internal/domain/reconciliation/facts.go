@@ -0,0 +1,65 @@
+package reconciliation
+
+import (
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/domain/order"
+)
+
+// amountEpsilon - допустимая погрешность при сравнении сумм во float
+const amountEpsilon = 0.000001
+
+// OrderFacts - сведения о заказе, собранные из трёх источников:
+// таблицы заказов, начислений и событий кошелька.
+type OrderFacts struct {
+	OrderNumber     string
+	CustomerID      string
+	OrderState      string
+	CreatedAt       time.Time
+	HasAccrual      bool
+	AccrualAmount   float64
+	Deposits        int
+	DepositedAmount float64
+	// UnattributedDeposits - депозиты клиента без номера заказа, записанные до того, как депозиты стали его хранить.
+	// История кошелька не переписывается, поэтому такие депозиты нельзя отнести к конкретному заказу
+	UnattributedDeposits int
+}
+
+// CheckProcessed проверяет обработанный заказ и возвращает найденные расхождения.
+func CheckProcessed(facts *OrderFacts) []*Discrepancy {
+	if !facts.HasAccrual {
+		return []*Discrepancy{NewDiscrepancy(MissingAccrual, facts)}
+	}
+
+	switch {
+	case facts.Deposits == 0:
+		// начисление могло попасть в кошелёк одним из депозитов без номера заказа,
+		// повторное зачисление было бы двойным
+		if facts.AccrualAmount > amountEpsilon && facts.UnattributedDeposits == 0 {
+			return []*Discrepancy{NewDiscrepancy(MissingDeposit, facts)}
+		}
+	case facts.Deposits > 1:
+		return []*Discrepancy{NewDiscrepancy(DuplicateDeposit, facts)}
+	default:
+		diff := facts.AccrualAmount - facts.DepositedAmount
+		if diff > amountEpsilon || diff < -amountEpsilon {
+			return []*Discrepancy{NewDiscrepancy(AmountMismatch, facts)}
+		}
+	}
+
+	return nil
+}
+
+// CheckStuck проверяет, не завис ли заказ в промежуточном статусе дольше threshold.
+func CheckStuck(facts *OrderFacts, now time.Time, threshold time.Duration) []*Discrepancy {
+	state := order.State(facts.OrderState)
+	if state != order.New && state != order.Processing {
+		return nil
+	}
+
+	if now.Sub(facts.CreatedAt) < threshold {
+		return nil
+	}
+
+	return []*Discrepancy{NewDiscrepancy(StuckOrder, facts)}
+}
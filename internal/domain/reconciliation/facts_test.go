@@ -0,0 +1,86 @@
+package reconciliation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckProcessed(t *testing.T) {
+	tests := []struct {
+		name  string
+		facts *OrderFacts
+		want  []Kind
+	}{
+		{
+			name:  "consistent",
+			facts: &OrderFacts{OrderNumber: "79927398713", HasAccrual: true, AccrualAmount: 500, Deposits: 1, DepositedAmount: 500},
+			want:  nil,
+		},
+		{
+			name:  "zero accrual without deposit",
+			facts: &OrderFacts{OrderNumber: "79927398713", HasAccrual: true, AccrualAmount: 0},
+			want:  nil,
+		},
+		{
+			name:  "missing accrual",
+			facts: &OrderFacts{OrderNumber: "79927398713"},
+			want:  []Kind{MissingAccrual},
+		},
+		{
+			name:  "missing deposit",
+			facts: &OrderFacts{OrderNumber: "79927398713", HasAccrual: true, AccrualAmount: 500},
+			want:  []Kind{MissingDeposit},
+		},
+		{
+			name:  "missing deposit with unattributed deposits",
+			facts: &OrderFacts{OrderNumber: "79927398713", HasAccrual: true, AccrualAmount: 500, UnattributedDeposits: 1},
+			want:  nil,
+		},
+		{
+			name:  "duplicate deposit",
+			facts: &OrderFacts{OrderNumber: "79927398713", HasAccrual: true, AccrualAmount: 500, Deposits: 2, DepositedAmount: 1000},
+			want:  []Kind{DuplicateDeposit},
+		},
+		{
+			name:  "amount mismatch",
+			facts: &OrderFacts{OrderNumber: "79927398713", HasAccrual: true, AccrualAmount: 500, Deposits: 1, DepositedAmount: 50},
+			want:  []Kind{AmountMismatch},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckProcessed(tt.facts)
+
+			kinds := make([]Kind, 0, len(got))
+			for _, d := range got {
+				kinds = append(kinds, d.Kind)
+				assert.Equal(t, Open, d.Status)
+				assert.Equal(t, tt.facts.OrderNumber, d.OrderNumber)
+			}
+
+			assert.ElementsMatch(t, tt.want, kinds)
+		})
+	}
+}
+
+func TestCheckStuck(t *testing.T) {
+	now := time.Now()
+
+	assert.Len(t, CheckStuck(&OrderFacts{OrderState: "NEW", CreatedAt: now.Add(-2 * time.Hour)}, now, time.Hour), 1)
+	assert.Len(t, CheckStuck(&OrderFacts{OrderState: "PROCESSING", CreatedAt: now.Add(-2 * time.Hour)}, now, time.Hour), 1)
+	assert.Empty(t, CheckStuck(&OrderFacts{OrderState: "NEW", CreatedAt: now.Add(-time.Minute)}, now, time.Hour))
+	assert.Empty(t, CheckStuck(&OrderFacts{OrderState: "PROCESSED", CreatedAt: now.Add(-2 * time.Hour)}, now, time.Hour))
+}
+
+func TestDiscrepancy_MarkRepaired(t *testing.T) {
+	missing := NewDiscrepancy(MissingDeposit, &OrderFacts{AccrualAmount: 100})
+	assert.NoError(t, missing.MarkRepaired())
+	assert.Equal(t, Repaired, missing.Status)
+	assert.NotNil(t, missing.RepairedAt)
+	assert.ErrorIs(t, missing.MarkRepaired(), ErrNotRepairable)
+
+	duplicate := NewDiscrepancy(DuplicateDeposit, &OrderFacts{AccrualAmount: 100})
+	assert.ErrorIs(t, duplicate.MarkRepaired(), ErrNotRepairable)
+}
@@ -0,0 +1,19 @@
+package reconciliation
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	ProcessedOrders(ctx context.Context) ([]*OrderFacts, error)
+	// OrderFacts - сведения об одном обработанном заказе; nil, если заказ не в PROCESSED
+	OrderFacts(ctx context.Context, orderNumber string) (*OrderFacts, error)
+	StuckOrders(ctx context.Context, createdBefore time.Time) ([]*OrderFacts, error)
+	Save(ctx context.Context, discrepancy *Discrepancy) error
+	// UpdateStatus сохраняет статус открытого расхождения; ErrNotOpen, если его уже обработали
+	UpdateStatus(ctx context.Context, discrepancy *Discrepancy) error
+	Get(ctx context.Context, id string) (*Discrepancy, error)
+	List(ctx context.Context, statuses []Status, limit uint64, offset uint64) ([]*Discrepancy, error)
+	ResolveNotSeenSince(ctx context.Context, since time.Time) error
+}
@@ -0,0 +1,9 @@
+package transaction
+
+import "context"
+
+// Transactor выполняет fn в одной транзакции. Репозитории и outbox, получившие ctx из fn,
+// пишут в эту транзакцию, поэтому изменения агрегатов и их события фиксируются вместе.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
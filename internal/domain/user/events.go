@@ -3,9 +3,15 @@ package user
 import "time"
 
 type Registered struct {
-	UserID string
-	Email  string
-	time   time.Time
+	UserID     string
+	Email      string
+	OccurredAt time.Time
 }
 
 func (e *Registered) GetName() string { return "user.registered" }
+
+func (e *Registered) AggregateType() string { return "user" }
+
+func (e *Registered) AggregateID() string { return e.UserID }
+
+func (e *Registered) PartitionKey() string { return e.UserID }
@@ -6,14 +6,16 @@ type Command interface {
 }
 
 type DepositCommand struct {
-	CustomerID string
-	Amount     float64
+	CustomerID  string
+	Amount      float64
+	OrderNumber string
 }
 
-func NewDepositCommand(customerID string, amount float64) *DepositCommand {
+func NewDepositCommand(customerID string, orderNumber string, amount float64) *DepositCommand {
 	return &DepositCommand{
-		CustomerID: customerID,
-		Amount:     amount,
+		CustomerID:  customerID,
+		Amount:      amount,
+		OrderNumber: orderNumber,
 	}
 }
 
@@ -23,17 +25,13 @@ type WithdrawCommand struct {
 	OrderNumber order.Number
 }
 
-func NewWithdrawCommand(customerID string, orderNumber string, amount float64) (*WithdrawCommand, error) {
-	n, err := order.NewOrderNumber(orderNumber)
-	if err != nil {
-		return nil, err
-	}
-
+// NewWithdrawCommand принимает уже проверенный номер: схема проверки задаётся настройками приложения
+func NewWithdrawCommand(customerID string, orderNumber order.Number, amount float64) *WithdrawCommand {
 	return &WithdrawCommand{
 		CustomerID:  customerID,
 		Amount:      amount,
-		OrderNumber: n,
-	}, nil
+		OrderNumber: orderNumber,
+	}
 }
 
 type CreateCommand struct {
@@ -45,3 +43,17 @@ func NewCreateCommand(customerID string) *CreateCommand {
 		CustomerID: customerID,
 	}
 }
+
+type ReverseCommand struct {
+	CustomerID  string
+	OrderNumber string
+	Reason      string
+}
+
+func NewReverseCommand(customerID string, orderNumber string, reason string) *ReverseCommand {
+	return &ReverseCommand{
+		CustomerID:  customerID,
+		OrderNumber: orderNumber,
+		Reason:      reason,
+	}
+}
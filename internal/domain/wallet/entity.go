@@ -13,6 +13,9 @@ type Wallet struct {
 	CreatedAt  time.Time
 	events     []Event
 	version    int
+	// credited - сколько сейчас зачислено по каждому заказу с учётом сторнирования.
+	// Заказ остаётся в карте и после сторнирования: по нему уже зачисляли
+	credited map[string]float64
 }
 
 func NewWallet(customerID string) *Wallet {
@@ -23,6 +26,7 @@ func NewWallet(customerID string) *Wallet {
 		Withdrawn:  0,
 		CreatedAt:  time.Now(),
 		events:     make([]Event, 0),
+		credited:   make(map[string]float64),
 	}
 
 	return wallet
@@ -35,7 +39,13 @@ func (w *Wallet) HandleCommand(cmd Command) error {
 		//w.addEvent(&Deposited{CustomerID: w.CustomerID, Amount: 100, Timestamp: time.Now()})
 		return nil
 	case *DepositCommand:
-		w.addEvent(&Deposited{CustomerID: w.CustomerID, Amount: c.Amount, Timestamp: time.Now()})
+		// по заказу уже зачисляли - повторная доставка начисления не должна зачислить его второй раз,
+		// в том числе после сторнирования при отмене заказа
+		if _, deposited := w.credited[c.OrderNumber]; c.OrderNumber != "" && deposited {
+			return nil
+		}
+
+		w.addEvent(&Deposited{CustomerID: w.CustomerID, Amount: c.Amount, OrderNumber: c.OrderNumber, Timestamp: time.Now()})
 		return nil
 	case *WithdrawCommand:
 		if w.Balance < c.Amount {
@@ -44,6 +54,15 @@ func (w *Wallet) HandleCommand(cmd Command) error {
 
 		w.addEvent(&Withdrawn{CustomerID: w.CustomerID, Amount: c.Amount, Timestamp: time.Now(), OrderNumber: string(c.OrderNumber)})
 		return nil
+	case *ReverseCommand:
+		amount := w.credited[c.OrderNumber]
+		if amount <= 0 {
+			return ErrNothingToReverse
+		}
+
+		// баланс может уйти в минус, если начисление уже потрачено
+		w.addEvent(&Reversed{CustomerID: w.CustomerID, Amount: amount, OrderNumber: c.OrderNumber, Reason: c.Reason, Timestamp: time.Now()})
+		return nil
 	}
 	return nil
 }
@@ -52,6 +71,10 @@ func (w *Wallet) ApplyEvent(event Event) {
 	switch e := event.(type) {
 	case *Deposited:
 		w.Balance += e.Amount
+		w.credited[e.OrderNumber] += e.Amount
+	case *Reversed:
+		w.Balance -= e.Amount
+		w.credited[e.OrderNumber] -= e.Amount
 	case *Withdrawn:
 		w.Balance -= e.Amount
 		w.Withdrawn += e.Amount
@@ -75,3 +98,38 @@ func (w *Wallet) Events() []Event {
 func (w *Wallet) Version() int {
 	return w.version
 }
+
+// Snapshot - состояние кошелька на версии Version, чтобы не проигрывать при загрузке всю историю
+type Snapshot struct {
+	Version   int
+	Balance   float64
+	Withdrawn float64
+	Credited  map[string]float64
+}
+
+func (w *Wallet) Snapshot() Snapshot {
+	credited := make(map[string]float64, len(w.credited))
+	for orderNumber, amount := range w.credited {
+		credited[orderNumber] = amount
+	}
+
+	return Snapshot{
+		Version:   w.version,
+		Balance:   w.Balance,
+		Withdrawn: w.Withdrawn,
+		Credited:  credited,
+	}
+}
+
+// FromSnapshot восстанавливает кошелёк из снимка; события после снимка применяются через ApplyEvent
+func FromSnapshot(customerID string, snapshot Snapshot) *Wallet {
+	wallet := NewWallet(customerID)
+	wallet.Balance = snapshot.Balance
+	wallet.Withdrawn = snapshot.Withdrawn
+	wallet.version = snapshot.Version
+	for orderNumber, amount := range snapshot.Credited {
+		wallet.credited[orderNumber] = amount
+	}
+
+	return wallet
+}
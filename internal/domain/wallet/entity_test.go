@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWallet_Reverse(t *testing.T) {
+	w := NewWallet("customer")
+	require.NoError(t, w.HandleCommand(NewDepositCommand("customer", "79927398713", 500)))
+	require.NoError(t, w.HandleCommand(NewDepositCommand("customer", "12345678903", 100)))
+
+	require.NoError(t, w.HandleCommand(NewReverseCommand("customer", "79927398713", "admin")))
+	assert.Equal(t, 100.0, w.Balance)
+
+	reversed, ok := w.Events()[2].(*Reversed)
+	require.True(t, ok)
+	assert.Equal(t, 500.0, reversed.Amount)
+
+	assert.ErrorIs(t, w.HandleCommand(NewReverseCommand("customer", "79927398713", "admin")), ErrNothingToReverse)
+	assert.ErrorIs(t, w.HandleCommand(NewReverseCommand("customer", "5555555555554444", "admin")), ErrNothingToReverse)
+}
+
+func TestWallet_FromSnapshot(t *testing.T) {
+	w := NewWallet("customer")
+	require.NoError(t, w.HandleCommand(NewDepositCommand("customer", "79927398713", 500)))
+	require.NoError(t, w.HandleCommand(NewWithdrawCommand("customer", "12345678903", 100)))
+
+	restored := FromSnapshot("customer", w.Snapshot())
+	assert.Equal(t, w.Balance, restored.Balance)
+	assert.Equal(t, w.Withdrawn, restored.Withdrawn)
+	assert.Equal(t, w.Version(), restored.Version())
+	assert.Empty(t, restored.Events())
+
+	require.NoError(t, restored.HandleCommand(NewReverseCommand("customer", "79927398713", "admin")))
+	assert.Equal(t, -100.0, restored.Balance)
+}
+
+func TestWallet_DepositOnce(t *testing.T) {
+	w := NewWallet("customer")
+	require.NoError(t, w.HandleCommand(NewDepositCommand("customer", "79927398713", 500)))
+	require.NoError(t, w.HandleCommand(NewDepositCommand("customer", "79927398713", 500)))
+	assert.Equal(t, 500.0, w.Balance)
+	assert.Len(t, w.Events(), 1)
+
+	// повтор order.processed после отмены заказа не зачисляет его снова
+	require.NoError(t, w.HandleCommand(NewReverseCommand("customer", "79927398713", "admin")))
+	require.NoError(t, w.HandleCommand(NewDepositCommand("customer", "79927398713", 500)))
+	assert.Equal(t, 0.0, w.Balance)
+
+	// то же после восстановления из снимка
+	restored := FromSnapshot("customer", w.Snapshot())
+	require.NoError(t, restored.HandleCommand(NewDepositCommand("customer", "79927398713", 500)))
+	assert.Empty(t, restored.Events())
+}
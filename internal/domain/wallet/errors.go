@@ -5,4 +5,5 @@ import "errors"
 var (
 	ErrInsufficientFunds = errors.New("insufficient funds")
 	ErrVersionConflict   = errors.New("version conflict")
+	ErrNothingToReverse  = errors.New("nothing credited for order")
 )
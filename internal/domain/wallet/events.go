@@ -18,9 +18,10 @@ func (c *Created) GetType() string {
 }
 
 type Deposited struct {
-	CustomerID string
-	Amount     float64
-	Timestamp  time.Time
+	CustomerID  string
+	Amount      float64
+	OrderNumber string
+	Timestamp   time.Time
 }
 
 func (d *Deposited) GetType() string {
@@ -37,3 +38,44 @@ type Withdrawn struct {
 func (w *Withdrawn) GetType() string {
 	return "withdrawn"
 }
+
+// Reversed - компенсирующее списание начисления по отменённому заказу
+type Reversed struct {
+	CustomerID  string
+	Amount      float64
+	OrderNumber string
+	Reason      string
+	Timestamp   time.Time
+}
+
+func (r *Reversed) GetType() string {
+	return "reversed"
+}
+
+// AggregateType - тип агрегата кошелька в outbox
+const AggregateType = "wallet"
+
+// PointsWithdrawn публикуется в шину событий после успешного списания баллов.
+// В отличие от Withdrawn, это не часть истории кошелька, а уведомление для других модулей.
+type PointsWithdrawn struct {
+	CustomerID  string
+	OrderNumber string
+	Amount      float64
+	WithdrawnAt time.Time
+}
+
+func (e *PointsWithdrawn) GetName() string {
+	return "wallet.withdrawn"
+}
+
+func (e *PointsWithdrawn) AggregateType() string {
+	return AggregateType
+}
+
+func (e *PointsWithdrawn) AggregateID() string {
+	return e.CustomerID
+}
+
+func (e *PointsWithdrawn) PartitionKey() string {
+	return e.CustomerID
+}
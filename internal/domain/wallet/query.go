@@ -0,0 +1,9 @@
+package wallet
+
+import "github.com/sviatilnik/gophermart/internal/domain/pagination"
+
+// WithdrawsQuery - страница выборки списаний пользователя
+type WithdrawsQuery struct {
+	CustomerID string
+	Page       pagination.Page
+}
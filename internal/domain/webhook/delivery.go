@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryFailed - попытки исчерпаны, событие партнёру не доставлено
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// Delivery - отправка одного события одной подписке
+type Delivery struct {
+	ID             int64
+	SubscriptionID string
+	EventID        string
+	EventType      string
+	// Body - тело запроса, собирается один раз при постановке в очередь
+	Body          json.RawMessage
+	Status        DeliveryStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+
+	// URL и Secret подписки заполняются при выборке на отправку
+	URL    string
+	Secret string
+
+	Log []*Attempt
+}
+
+// Attempt - запись журнала об одной попытке отправки
+type Attempt struct {
+	DeliveryID int64
+	Number     int
+	// RequestSnippet и ResponseSnippet обрезаются до SnippetLength байт
+	RequestSnippet  string
+	ResponseStatus  int
+	ResponseSnippet string
+	Error           string
+	Duration        time.Duration
+	OccurredAt      time.Time
+}
+
+// SnippetLength - сколько байт запроса и ответа попадает в журнал
+const SnippetLength = 1024
+
+func Snippet(data []byte) string {
+	if len(data) > SnippetLength {
+		data = data[:SnippetLength]
+	}
+
+	return string(data)
+}
+
+func (a *Attempt) Succeeded() bool {
+	return a.Error == "" && a.ResponseStatus >= 200 && a.ResponseStatus < 300
+}
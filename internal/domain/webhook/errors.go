@@ -0,0 +1,12 @@
+package webhook
+
+import "errors"
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrEmptyPartner         = errors.New("partner name is required")
+	ErrInvalidURL           = errors.New("webhook url must be an absolute http or https url")
+	ErrNoEventTypes         = errors.New("at least one event type is required")
+	ErrUnsupportedEvent     = errors.New("event type is not available for webhooks")
+	ErrWeakSecret           = errors.New("webhook secret must be at least 16 characters long")
+)
@@ -0,0 +1,28 @@
+package webhook
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	Create(ctx context.Context, s *Subscription) error
+	Get(ctx context.Context, id string) (*Subscription, error)
+	List(ctx context.Context) ([]*Subscription, error)
+	Delete(ctx context.Context, id string) error
+	// Enable снова включает подписку и сбрасывает счётчик ошибок
+	Enable(ctx context.Context, id string) error
+	// Matching возвращает активные подписки на событие
+	Matching(ctx context.Context, eventType string) ([]*Subscription, error)
+
+	// Enqueue ставит отправки в очередь; повторная постановка того же события игнорируется
+	Enqueue(ctx context.Context, deliveries []*Delivery) error
+	// Claim забирает отправки активных подписок, время которых пришло, и продлевает их на lease
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error)
+	// RecordSuccess пишет попытку в журнал, закрывает отправку и сбрасывает счётчик ошибок подписки
+	RecordSuccess(ctx context.Context, d *Delivery, attempt *Attempt) error
+	// RecordFailure пишет попытку в журнал и откладывает отправку до retryAt; nil означает, что попытки исчерпаны.
+	// Подписка отключается, когда число ошибок подряд достигает disableAfter; возвращает true, если это произошло.
+	RecordFailure(ctx context.Context, d *Delivery, attempt *Attempt, retryAt *time.Time, disableAfter int) (bool, error)
+	Deliveries(ctx context.Context, subscriptionID string, limit uint64) ([]*Delivery, error)
+}
@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Заголовки, с которыми уходит каждый запрос
+const (
+	DeliveryHeader  = "X-Gophermart-Delivery"
+	EventHeader     = "X-Gophermart-Event"
+	TimestampHeader = "X-Gophermart-Timestamp"
+	SignatureHeader = "X-Gophermart-Signature"
+)
+
+// Sign подписывает "<unix timestamp>.<body>". Время входит в подпись, чтобы партнёр мог отбросить
+// перехваченный и повторно отправленный запрос.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify - проверка подписи на стороне получателя
+func Verify(secret string, timestamp time.Time, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
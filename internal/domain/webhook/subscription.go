@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Events - события, на которые партнёр может подписаться
+var Events = []string{"order.processed", "wallet.withdrawn"}
+
+// MinSecretLength - секрет короче легко подобрать по перехваченным подписям
+const MinSecretLength = 16
+
+// Subscription - адрес партнёра, на который отправляются выбранные события
+type Subscription struct {
+	ID         string
+	Partner    string
+	URL        string
+	EventTypes []string
+	// Secret - ключ подписи HMAC-SHA256, известный только партнёру и нам
+	Secret string
+	Active bool
+	// ConsecutiveFailures - неудачные попытки подряд, после успешной сбрасывается
+	ConsecutiveFailures int
+	DisabledAt          *time.Time
+	DisabledReason      string
+	CreatedAt           time.Time
+}
+
+func NewSubscription(partner string, rawURL string, eventTypes []string, secret string) (*Subscription, error) {
+	if partner == "" {
+		return nil, ErrEmptyPartner
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	if len(eventTypes) == 0 {
+		return nil, ErrNoEventTypes
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(Events, eventType) {
+			return nil, ErrUnsupportedEvent
+		}
+	}
+
+	if len(secret) < MinSecretLength {
+		return nil, ErrWeakSecret
+	}
+
+	types := slices.Clone(eventTypes)
+	slices.Sort(types)
+
+	return &Subscription{
+		ID:         uuid.NewString(),
+		Partner:    partner,
+		URL:        u.String(),
+		EventTypes: slices.Compact(types),
+		Secret:     secret,
+		Active:     true,
+		CreatedAt:  time.Now(),
+	}, nil
+}
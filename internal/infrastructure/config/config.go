@@ -1,21 +1,124 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
 type Config struct {
 	Host                 string
 	DatabaseDSN          string
 	AccrualSystemAddress string
 	AccessTokenSecret    string
+
+	AccrualAPIKeyHeader string
+	AccrualAPIKey       string
+	AccrualHMACKeyID    string
+	AccrualHMACSecret   string
+	AccrualTLSCertFile  string
+	AccrualTLSKeyFile   string
+	AccrualTLSCAFile    string
+	// AccrualRegisterOrders - передавать системе начислений позиции чека (POST /api/orders)
+	AccrualRegisterOrders bool
+
+	AdminToken          string
+	ReconcileInterval   time.Duration
+	ReconcileStuckAfter time.Duration
+	ReconcileAutoRepair bool
+	InstanceID          string
+	LeaderLeaseTTL      time.Duration
+	// LeaderRenewInterval - как часто лидер продлевает аренду, должен быть меньше LeaderLeaseTTL
+	LeaderRenewInterval time.Duration
+
+	AnomalyMaxAmount      float64
+	AnomalyCustomerFactor float64
+	AnomalyProviderFactor float64
+	AnomalyMinHistorySize int
+
+	// правила вознаграждения, акции и уровни клиентов в JSON
+	AccrualRewardRules string
+	AccrualPromotions  string
+	AccrualTiers       string
+
+	OrderBatchMaxSize  int
+	OrderNumberSchemes string
+	// OrderMerchantKeys - ключи мерчантов и их источники заказов, "key=merchant;..."
+	OrderMerchantKeys string
+
+	// IngestDir - каталог с файлами заказов от партнёров, пустое значение выключает загрузку
+	IngestDir      string
+	IngestInterval time.Duration
+
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+	OutboxMaxBackoff   time.Duration
+	// OutboxPartitions - сколько ключей событий доставляется параллельно, OutboxQueueSize - глубина очереди партиции
+	OutboxPartitions int
+	OutboxQueueSize  int
+
+	// EventHandlerTimeout - сколько может работать один подписчик события
+	EventHandlerTimeout       time.Duration
+	EventHandlerRetryAttempts int
+	EventHandlerRetryBackoff  time.Duration
+
+	WebhookPollInterval time.Duration
+	// WebhookTimeout - сколько ждать ответа партнёра на один запрос
+	WebhookTimeout     time.Duration
+	WebhookMaxAttempts int
+	WebhookMaxBackoff  time.Duration
+	// WebhookDisableAfter - после стольких ошибок подряд подписка отключается
+	WebhookDisableAfter int
+
+	// EventSinkDir - каталог файловой выгрузки событий, пустое значение её выключает
+	EventSinkDir         string
+	EventSinkMaxFileSize int
+	EventSinkStdout      bool
+
+	// errs - ошибки разбора значений из источников настроек
+	errs []error
 }
 
 func NewConfig(providers ...Provider) Config {
 	conf := Config{}
 	for _, provider := range providers {
-		_ = conf.setValues(provider)
+		if err := conf.setValues(provider); err != nil {
+			conf.errs = append(conf.errs, err)
+		}
 	}
 
 	return conf
 }
 
+// Validate возвращает ошибки разбора настроек и недопустимые сочетания значений
+func (c *Config) Validate() error {
+	errs := append([]error{}, c.errs...)
+
+	if c.LeaderRenewInterval <= 0 || c.LeaderLeaseTTL <= c.LeaderRenewInterval {
+		errs = append(errs, fmt.Errorf("leader lease ttl %s must be greater than renew interval %s, and both positive",
+			c.LeaderLeaseTTL, c.LeaderRenewInterval))
+	}
+
+	intervals := map[string]time.Duration{
+		"reconcile interval":    c.ReconcileInterval,
+		"ingest interval":       c.IngestInterval,
+		"outbox poll interval":  c.OutboxPollInterval,
+		"webhook poll interval": c.WebhookPollInterval,
+	}
+	for name, interval := range intervals {
+		if interval <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", name, interval))
+		}
+	}
+
+	if c.OutboxPartitions <= 0 || c.OutboxQueueSize <= 0 {
+		errs = append(errs, fmt.Errorf("outbox partitions %d and queue size %d must be positive",
+			c.OutboxPartitions, c.OutboxQueueSize))
+	}
+
+	return errors.Join(errs...)
+}
+
 func (c *Config) setValues(provider Provider) error {
 	return provider.setValues(c)
 }
@@ -23,6 +23,7 @@ func TestConfig(t *testing.T) {
 			HostFlagName:                 "tta",
 			DatabaseDSNFlagName:          "ttd",
 			AccrualSystemAddressFlagName: "ttr",
+			ReconcileAutoRepairFlagName:  "ttrr",
 		},
 		NewEnvProvider(getMockEnvGetter(t)),
 	)
@@ -42,3 +43,21 @@ func getMockEnvGetter(t *testing.T) EnvGetter {
 
 	return m
 }
+
+func TestConfig_Validate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_config.NewMockEnvGetter(ctrl)
+	m.EXPECT().LookupEnv("LEADER_LEASE_TTL").Return("15", true).AnyTimes()
+	m.EXPECT().LookupEnv("LEADER_RENEW_INTERVAL").Return("20s", true).AnyTimes()
+	m.EXPECT().LookupEnv(gomock.Any()).Return("", false).AnyTimes()
+
+	defaults := NewConfig(&DefaultProvider{})
+	assert.NoError(t, defaults.Validate())
+
+	config := NewConfig(&DefaultProvider{}, NewEnvProvider(m))
+	err := config.Validate()
+	assert.ErrorContains(t, err, "LEADER_LEASE_TTL")
+	assert.ErrorContains(t, err, "must be greater than renew interval")
+}
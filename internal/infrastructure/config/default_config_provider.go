@@ -1,5 +1,11 @@
 package config
 
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
 type DefaultProvider struct{}
 
 func NewDefaultProvider() *DefaultProvider {
@@ -11,5 +17,44 @@ func (d *DefaultProvider) setValues(c *Config) error {
 	c.DatabaseDSN = ""
 	c.AccrualSystemAddress = "localhost:8080"
 	c.AccessTokenSecret = "my_secret_key"
+	c.AccrualAPIKeyHeader = "X-API-Key"
+	c.AdminToken = ""
+	c.ReconcileInterval = 10 * time.Minute
+	c.ReconcileStuckAfter = time.Hour
+	c.ReconcileAutoRepair = false
+	c.InstanceID = defaultInstanceID()
+	c.LeaderLeaseTTL = 15 * time.Second
+	c.LeaderRenewInterval = 5 * time.Second
+	// правила по сумме выключены, пока не заданы пороги
+	c.AnomalyMaxAmount = 0
+	c.AnomalyCustomerFactor = 0
+	c.AnomalyProviderFactor = 0
+	c.AnomalyMinHistorySize = 10
+	c.OrderBatchMaxSize = 500
+	c.IngestDir = ""
+	c.IngestInterval = time.Minute
+	c.OutboxPollInterval = time.Second
+	c.OutboxBatchSize = 100
+	c.OutboxMaxBackoff = 10 * time.Minute
+	c.OutboxPartitions = 4
+	c.OutboxQueueSize = 100
+	c.EventHandlerTimeout = 5 * time.Second
+	c.EventHandlerRetryAttempts = 2
+	c.EventHandlerRetryBackoff = 100 * time.Millisecond
+	c.WebhookPollInterval = 5 * time.Second
+	c.WebhookTimeout = 10 * time.Second
+	c.WebhookMaxAttempts = 10
+	c.WebhookMaxBackoff = time.Hour
+	c.WebhookDisableAfter = 20
+	c.EventSinkMaxFileSize = 64 << 20
 	return nil
 }
+
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "gophermart"
+	}
+
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
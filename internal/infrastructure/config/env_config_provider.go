@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type EnvGetter interface {
@@ -21,6 +25,8 @@ func (O *OSEnvGetter) LookupEnv(key string) (string, bool) {
 
 type EnvProvider struct {
 	getter EnvGetter
+	// errs - значения, которые не удалось разобрать; вместо них остаются прежние
+	errs []error
 }
 
 func NewEnvProvider(getter EnvGetter) *EnvProvider {
@@ -30,6 +36,8 @@ func NewEnvProvider(getter EnvGetter) *EnvProvider {
 }
 
 func (env *EnvProvider) setValues(c *Config) error {
+	env.errs = nil
+
 	host, ok := env.getter.LookupEnv("RUN_ADDRESS")
 	if ok && strings.TrimSpace(host) != "" {
 		c.Host = host
@@ -45,5 +53,139 @@ func (env *EnvProvider) setValues(c *Config) error {
 		c.AccrualSystemAddress = accrualSystemAddress
 	}
 
-	return nil
+	c.AccrualAPIKeyHeader = env.string("ACCRUAL_API_KEY_HEADER", c.AccrualAPIKeyHeader)
+	c.AccrualAPIKey = env.string("ACCRUAL_API_KEY", c.AccrualAPIKey)
+	c.AccrualHMACKeyID = env.string("ACCRUAL_HMAC_KEY_ID", c.AccrualHMACKeyID)
+	c.AccrualHMACSecret = env.string("ACCRUAL_HMAC_SECRET", c.AccrualHMACSecret)
+	c.AccrualTLSCertFile = env.string("ACCRUAL_TLS_CERT_FILE", c.AccrualTLSCertFile)
+	c.AccrualTLSKeyFile = env.string("ACCRUAL_TLS_KEY_FILE", c.AccrualTLSKeyFile)
+	c.AccrualTLSCAFile = env.string("ACCRUAL_TLS_CA_FILE", c.AccrualTLSCAFile)
+	c.AccrualRegisterOrders = env.bool("ACCRUAL_REGISTER_ORDERS", c.AccrualRegisterOrders)
+
+	adminToken, ok := env.getter.LookupEnv("ADMIN_TOKEN")
+	if ok && strings.TrimSpace(adminToken) != "" {
+		c.AdminToken = adminToken
+	}
+
+	instanceID, ok := env.getter.LookupEnv("INSTANCE_ID")
+	if ok && strings.TrimSpace(instanceID) != "" {
+		c.InstanceID = instanceID
+	}
+
+	c.LeaderLeaseTTL = env.duration("LEADER_LEASE_TTL", c.LeaderLeaseTTL)
+	c.LeaderRenewInterval = env.duration("LEADER_RENEW_INTERVAL", c.LeaderRenewInterval)
+	c.ReconcileInterval = env.duration("RECONCILE_INTERVAL", c.ReconcileInterval)
+	c.ReconcileStuckAfter = env.duration("RECONCILE_STUCK_AFTER", c.ReconcileStuckAfter)
+	c.ReconcileAutoRepair = env.bool("RECONCILE_AUTO_REPAIR", c.ReconcileAutoRepair)
+
+	c.AnomalyMaxAmount = env.float("ANOMALY_MAX_AMOUNT", c.AnomalyMaxAmount)
+	c.AnomalyCustomerFactor = env.float("ANOMALY_CUSTOMER_FACTOR", c.AnomalyCustomerFactor)
+	c.AnomalyProviderFactor = env.float("ANOMALY_PROVIDER_FACTOR", c.AnomalyProviderFactor)
+	c.AnomalyMinHistorySize = env.int("ANOMALY_MIN_HISTORY_SIZE", c.AnomalyMinHistorySize)
+
+	c.AccrualRewardRules = env.string("ACCRUAL_REWARD_RULES", c.AccrualRewardRules)
+	c.AccrualPromotions = env.string("ACCRUAL_PROMOTIONS", c.AccrualPromotions)
+	c.AccrualTiers = env.string("ACCRUAL_TIERS", c.AccrualTiers)
+
+	c.OrderBatchMaxSize = env.int("ORDER_BATCH_MAX_SIZE", c.OrderBatchMaxSize)
+	c.OrderNumberSchemes = env.string("ORDER_NUMBER_SCHEMES", c.OrderNumberSchemes)
+	c.OrderMerchantKeys = env.string("ORDER_MERCHANT_KEYS", c.OrderMerchantKeys)
+
+	c.IngestDir = env.string("INGEST_DIR", c.IngestDir)
+	c.IngestInterval = env.duration("INGEST_INTERVAL", c.IngestInterval)
+
+	c.OutboxPollInterval = env.duration("OUTBOX_POLL_INTERVAL", c.OutboxPollInterval)
+	c.OutboxBatchSize = env.int("OUTBOX_BATCH_SIZE", c.OutboxBatchSize)
+	c.OutboxMaxBackoff = env.duration("OUTBOX_MAX_BACKOFF", c.OutboxMaxBackoff)
+	c.OutboxPartitions = env.int("OUTBOX_PARTITIONS", c.OutboxPartitions)
+	c.OutboxQueueSize = env.int("OUTBOX_QUEUE_SIZE", c.OutboxQueueSize)
+
+	c.EventHandlerTimeout = env.duration("EVENT_HANDLER_TIMEOUT", c.EventHandlerTimeout)
+	c.EventHandlerRetryAttempts = env.int("EVENT_HANDLER_RETRY_ATTEMPTS", c.EventHandlerRetryAttempts)
+	c.EventHandlerRetryBackoff = env.duration("EVENT_HANDLER_RETRY_BACKOFF", c.EventHandlerRetryBackoff)
+
+	c.WebhookPollInterval = env.duration("WEBHOOK_POLL_INTERVAL", c.WebhookPollInterval)
+	c.WebhookTimeout = env.duration("WEBHOOK_TIMEOUT", c.WebhookTimeout)
+	c.WebhookMaxAttempts = env.int("WEBHOOK_MAX_ATTEMPTS", c.WebhookMaxAttempts)
+	c.WebhookMaxBackoff = env.duration("WEBHOOK_MAX_BACKOFF", c.WebhookMaxBackoff)
+	c.WebhookDisableAfter = env.int("WEBHOOK_DISABLE_AFTER", c.WebhookDisableAfter)
+
+	c.EventSinkDir = env.string("EVENT_SINK_DIR", c.EventSinkDir)
+	c.EventSinkMaxFileSize = env.int("EVENT_SINK_MAX_FILE_SIZE", c.EventSinkMaxFileSize)
+	c.EventSinkStdout = env.bool("EVENT_SINK_STDOUT", c.EventSinkStdout)
+
+	return errors.Join(env.errs...)
+}
+
+func (env *EnvProvider) invalid(key string, value string, err error) {
+	env.errs = append(env.errs, fmt.Errorf("env %s=%q: %w", key, value, err))
+}
+
+func (env *EnvProvider) string(key string, fallback string) string {
+	value, ok := env.getter.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return fallback
+	}
+
+	return value
+}
+
+func (env *EnvProvider) duration(key string, fallback time.Duration) time.Duration {
+	value, ok := env.getter.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		env.invalid(key, value, err)
+		return fallback
+	}
+
+	return d
+}
+
+func (env *EnvProvider) bool(key string, fallback bool) bool {
+	value, ok := env.getter.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		env.invalid(key, value, err)
+		return fallback
+	}
+
+	return b
+}
+
+func (env *EnvProvider) float(key string, fallback float64) float64 {
+	value, ok := env.getter.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return fallback
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		env.invalid(key, value, err)
+		return fallback
+	}
+
+	return f
+}
+
+func (env *EnvProvider) int(key string, fallback int) int {
+	value, ok := env.getter.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return fallback
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		env.invalid(key, value, err)
+		return fallback
+	}
+
+	return i
 }
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/sviatilnik/gophermart/internal/infrastructure/config/mock_config"
 	"go.uber.org/mock/gomock"
@@ -16,10 +18,17 @@ func TestEnvProvider(t *testing.T) {
 	m.EXPECT().LookupEnv("RUN_ADDRESS").Return("https://google.com", true).AnyTimes()
 	m.EXPECT().LookupEnv("DATABASE_URI").Return("database_dsn", true).AnyTimes()
 	m.EXPECT().LookupEnv("ACCRUAL_SYSTEM_ADDRESS").Return("https://google.com", true).AnyTimes()
+	m.EXPECT().LookupEnv("ADMIN_TOKEN").Return("admin_token", true).AnyTimes()
+	m.EXPECT().LookupEnv("RECONCILE_INTERVAL").Return("5m", true).AnyTimes()
+	m.EXPECT().LookupEnv("RECONCILE_AUTO_REPAIR").Return("true", true).AnyTimes()
+	m.EXPECT().LookupEnv(gomock.Any()).Return("", false).AnyTimes()
 
 	config := NewConfig(NewEnvProvider(m))
 
 	assert.Equal(t, "https://google.com", config.Host)
 	assert.Equal(t, "database_dsn", config.DatabaseDSN)
 	assert.Equal(t, "https://google.com", config.AccrualSystemAddress)
+	assert.Equal(t, "admin_token", config.AdminToken)
+	assert.Equal(t, 5*time.Minute, config.ReconcileInterval)
+	assert.True(t, config.ReconcileAutoRepair)
 }
@@ -9,6 +9,7 @@ type FlagProvider struct {
 	HostFlagName                 string
 	DatabaseDSNFlagName          string
 	AccrualSystemAddressFlagName string
+	ReconcileAutoRepairFlagName  string
 }
 
 func NewFlagProvider() *FlagProvider {
@@ -16,6 +17,7 @@ func NewFlagProvider() *FlagProvider {
 		HostFlagName:                 "a",
 		DatabaseDSNFlagName:          "d",
 		AccrualSystemAddressFlagName: "r",
+		ReconcileAutoRepairFlagName:  "reconcile-auto-repair",
 	}
 }
 
@@ -23,6 +25,7 @@ func (flagConf *FlagProvider) setValues(c *Config) error {
 	host := flag.String(flagConf.HostFlagName, "", "Адрес и порт запуска сервиса")
 	databaseDSN := flag.String(flagConf.DatabaseDSNFlagName, "", "Адрес подключения к базе данных")
 	accrualSystemAddress := flag.String(flagConf.AccrualSystemAddressFlagName, "", "Адрес системы расчёта начислений")
+	reconcileAutoRepair := flag.Bool(flagConf.ReconcileAutoRepairFlagName, false, "Автоматически исправлять безопасные расхождения при сверке начислений")
 	flag.Parse()
 
 	if strings.TrimSpace(*host) != "" {
@@ -37,5 +40,9 @@ func (flagConf *FlagProvider) setValues(c *Config) error {
 		c.AccrualSystemAddress = *accrualSystemAddress
 	}
 
+	if *reconcileAutoRepair {
+		c.ReconcileAutoRepair = true
+	}
+
 	return nil
 }
@@ -15,14 +15,17 @@ func TestFlagProvider(t *testing.T) {
 		"-ta=https://google.com",
 		"-td=database_dsn",
 		"-tr=https://short.google.com",
+		"-trr",
 	}
 	config := NewConfig(&FlagProvider{
 		HostFlagName:                 "ta",
 		DatabaseDSNFlagName:          "td",
 		AccrualSystemAddressFlagName: "tr",
+		ReconcileAutoRepairFlagName:  "trr",
 	})
 
 	assert.Equal(t, "https://google.com", config.Host)
 	assert.Equal(t, "https://short.google.com", config.AccrualSystemAddress)
 	assert.Equal(t, "database_dsn", config.DatabaseDSN)
+	assert.True(t, config.ReconcileAutoRepair)
 }
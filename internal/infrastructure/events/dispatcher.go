@@ -0,0 +1,258 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"time"
+
+	domenevents "github.com/sviatilnik/gophermart/internal/domain/events"
+	"go.uber.org/zap"
+)
+
+// deliveredHeader - имена подписчиков, уже успешно обработавших событие.
+// При повторной доставке они пропускаются, чтобы сбой одного подписчика не повторял работу остальных.
+// Порядок подписчиков между перезапусками может поменяться, поэтому храним имена, а не позиции.
+const deliveredHeader = "delivered"
+
+// HandlerSource - откуда диспетчер берёт подписчиков события
+type HandlerSource interface {
+	Subscriptions(event string) []Subscription
+}
+
+type DispatcherConfig struct {
+	BatchSize int
+	// Partitions - сколько ключей доставляется параллельно
+	Partitions int
+	// QueueSize - ёмкость очереди партиции; при заполнении раздача пачки ждёт, пока воркер её разгрузит
+	QueueSize    int
+	PollInterval time.Duration
+	// Lease - сколько событие считается занятым диспетчером, после этого его заберёт другой
+	Lease       time.Duration
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// delivery - событие из outbox вместе с результатом его декодирования
+type delivery struct {
+	claimed *ClaimedEvent
+	event   domenevents.Event
+	key     string
+	err     error
+}
+
+// Dispatcher забирает события из outbox и доставляет их подписчикам.
+// События пачки раскладываются по партициям по ключу события. Каждую партицию обслуживает один воркер,
+// поэтому события с одним ключом доставляются по очереди, а события с разными ключами - параллельно.
+// Если событие ушло на повтор, следующие события его ключа ждут, пока оно не будет доставлено.
+type Dispatcher struct {
+	store    OutboxStore
+	handlers HandlerSource
+	registry *Registry
+	sinks    []*SinkExporter
+	config   DispatcherConfig
+	logger   *zap.SugaredLogger
+}
+
+func NewDispatcher(store OutboxStore, handlers HandlerSource, registry *Registry, config DispatcherConfig, logger *zap.SugaredLogger) *Dispatcher {
+	return &Dispatcher{
+		store:    store,
+		handlers: handlers,
+		registry: registry,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// AddSink подключает выгрузку во внешний приёмник. Приёмники получают события после каждого прохода диспетчера.
+func (d *Dispatcher) AddSink(exporter *SinkExporter) {
+	d.sinks = append(d.sinks, exporter)
+}
+
+// Run доставляет события, пока не отменён контекст
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info("outbox: dispatcher shutting down")
+			return
+		case <-ticker.C:
+			// выбираем outbox до конца, не дожидаясь следующего тика
+			for {
+				n, err := d.DispatchBatch(ctx)
+				if err != nil {
+					d.logger.Error("outbox: dispatch failed", zap.Error(err))
+					break
+				}
+				if n < d.config.BatchSize {
+					break
+				}
+			}
+
+			for _, sink := range d.sinks {
+				sink.run(ctx)
+			}
+		}
+	}
+}
+
+// DispatchBatch доставляет одну пачку событий и возвращает её размер
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	claimed, err := d.store.Claim(ctx, d.config.BatchSize, d.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	queues := make([]chan delivery, max(d.config.Partitions, 1))
+	errs := make([]error, len(queues))
+
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan delivery, max(d.config.QueueSize, 0))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			held := make(map[string]bool)
+			for e := range queues[i] {
+				// после ошибки хранилища остальные события партиции не трогаем,
+				// их заберут повторно, когда истечёт аренда
+				if errs[i] != nil {
+					continue
+				}
+				// более раннее событие ключа ждёт повтора - это возвращаем в очередь,
+				// Claim не отдаст его, пока раннее не доставлено
+				if held[e.key] {
+					errs[i] = d.store.Release(ctx, e.claimed.ID)
+					continue
+				}
+
+				var retrying bool
+				retrying, errs[i] = d.dispatch(ctx, e)
+				if retrying {
+					held[e.key] = true
+				}
+			}
+		}()
+	}
+
+	for _, e := range claimed {
+		event, err := d.registry.Decode(e.EventType, e.SchemaVersion, e.Payload)
+		key := e.EventType
+		if err == nil {
+			key = partitionKey(event)
+		}
+
+		queues[partition(key, len(queues))] <- delivery{claimed: e, event: event, key: key, err: err}
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	if err = errors.Join(errs...); err != nil {
+		return 0, err
+	}
+
+	return len(claimed), nil
+}
+
+// dispatch доставляет событие подписчикам и сообщает, отложено ли оно на повтор
+func (d *Dispatcher) dispatch(ctx context.Context, de delivery) (bool, error) {
+	e := de.claimed
+	if e.Retries >= e.MaxRetries {
+		return false, d.store.MarkDeadLetter(ctx, e.ID, e.Headers, "retries exhausted")
+	}
+
+	if de.err != nil {
+		d.logger.Errorw("outbox: event can not be decoded", "id", e.ID, "type", e.EventType, "version", e.SchemaVersion, "error", de.err)
+		return false, d.store.MarkDeadLetter(ctx, e.ID, e.Headers, de.err.Error())
+	}
+
+	delivered := deliveredHandlers(e.Headers)
+	var errs []error
+	for _, sub := range d.handlers.Subscriptions(e.EventType) {
+		if slices.Contains(delivered, sub.Name) {
+			continue
+		}
+
+		handlerCtx := domenevents.WithTrace(ctx, e.Metadata.Caused())
+		handlerCtx = domenevents.WithDelivery(handlerCtx, domenevents.Delivery{
+			Metadata:  e.Metadata,
+			EventName: e.EventType,
+			Handler:   sub.Name,
+			Attempt:   e.Retries + 1,
+		})
+		err := sub.Handler(handlerCtx, de.event, d.logger)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delivered = append(delivered, sub.Name)
+	}
+
+	if len(errs) == 0 {
+		return false, d.store.MarkProcessed(ctx, e.ID)
+	}
+
+	e.Headers[deliveredHeader] = delivered
+	lastError := errors.Join(errs...).Error()
+
+	if e.Retries+1 >= e.MaxRetries {
+		d.logger.Errorw("outbox: event moved to dead letters", "id", e.ID, "type", e.EventType, "error", lastError)
+		return false, d.store.MarkDeadLetter(ctx, e.ID, e.Headers, lastError)
+	}
+
+	return true, d.store.Retry(ctx, e.ID, e.Headers, d.backoff(e.Retries), lastError)
+}
+
+// backoff - экспоненциальная задержка перед следующей попыткой
+func (d *Dispatcher) backoff(retries int) time.Duration {
+	delay := d.config.BaseBackoff << min(retries, 30)
+	if delay <= 0 || delay > d.config.MaxBackoff {
+		return d.config.MaxBackoff
+	}
+
+	return delay
+}
+
+// deliveredHandlers читает имена доставленных подписчиков. Индексы из старых заголовков
+// пропускаются: такое событие доставится всем подписчикам ещё раз.
+func deliveredHandlers(headers map[string]any) []string {
+	raw, _ := headers[deliveredHeader].([]any)
+
+	delivered := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if name, ok := v.(string); ok {
+			delivered = append(delivered, name)
+		}
+	}
+
+	return delivered
+}
+
+func partition(key string, partitions int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return int(h.Sum32() % uint32(partitions))
+}
+
+// partitionKey - ключ упорядочивания: явный ключ события, затем агрегат, иначе тип события
+func partitionKey(event domenevents.Event) string {
+	if partitioned, ok := event.(domenevents.PartitionedEvent); ok {
+		return partitioned.PartitionKey()
+	}
+
+	if aggregate, ok := event.(domenevents.AggregateEvent); ok {
+		return fmt.Sprintf("%s:%s", aggregate.AggregateType(), aggregate.AggregateID())
+	}
+
+	return event.GetName()
+}
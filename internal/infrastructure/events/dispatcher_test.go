@@ -0,0 +1,253 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	domenevents "github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/user"
+	"go.uber.org/zap"
+)
+
+type memoryOutboxStore struct {
+	mu          sync.Mutex
+	pending     []*ClaimedEvent
+	processed   []int64
+	retried     map[int64]time.Duration
+	released    []int64
+	deadLetters map[int64]string
+}
+
+func newMemoryOutboxStore(events ...*ClaimedEvent) *memoryOutboxStore {
+	return &memoryOutboxStore{
+		pending:     events,
+		retried:     make(map[int64]time.Duration),
+		deadLetters: make(map[int64]string),
+	}
+}
+
+func (s *memoryOutboxStore) Claim(_ context.Context, limit int, _ time.Duration) ([]*ClaimedEvent, error) {
+	n := min(limit, len(s.pending))
+	claimed := s.pending[:n]
+	s.pending = s.pending[n:]
+	return claimed, nil
+}
+
+func (s *memoryOutboxStore) MarkProcessed(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.processed = append(s.processed, id)
+	return nil
+}
+
+func (s *memoryOutboxStore) Retry(_ context.Context, id int64, _ map[string]any, after time.Duration, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.retried[id] = after
+	return nil
+}
+
+func (s *memoryOutboxStore) Release(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.released = append(s.released, id)
+	return nil
+}
+
+func (s *memoryOutboxStore) MarkDeadLetter(_ context.Context, id int64, _ map[string]any, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deadLetters[id] = lastError
+	return nil
+}
+
+// claimedRegistered - событие в формате версии 1, записанное до появления реестра схем
+func claimedRegistered(t *testing.T, id int64, userID string, retries int) *ClaimedEvent {
+	payload, err := json.Marshal(&user.Registered{UserID: userID, Email: userID + "@example.com"})
+	require.NoError(t, err)
+
+	return &ClaimedEvent{
+		ID:            id,
+		EventType:     "user.registered",
+		SchemaVersion: 1,
+		Payload:       payload,
+		Headers:       map[string]any{},
+		Retries:       retries,
+		MaxRetries:    3,
+	}
+}
+
+func TestDispatcher_DispatchBatch(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	config := DispatcherConfig{BatchSize: 10, BaseBackoff: time.Second, MaxBackoff: time.Minute}
+
+	t.Run("delivered to all handlers", func(t *testing.T) {
+		e := claimedRegistered(t, 1, "user", 0)
+		e.Metadata = domenevents.Metadata{EventID: "event-1", CorrelationID: "request-1", CausationID: "request-1", Actor: "user:user"}
+		store := newMemoryOutboxStore(e)
+		bus := NewOutboxBus(nil, nil, NewDomainRegistry(), logger)
+
+		var received []string
+		require.NoError(t, bus.Subscribe("user.registered", "trace", func(ctx context.Context, e domenevents.Event, _ *zap.SugaredLogger) error {
+			received = append(received, e.(*user.Registered).UserID)
+			// события, опубликованные подписчиком, продолжают цепочку запроса
+			assert.Equal(t, domenevents.Trace{CorrelationID: "request-1", CausationID: "event-1", Actor: "user:user"}, domenevents.TraceFrom(ctx))
+			return nil
+		}))
+
+		n, err := NewDispatcher(store, bus, NewDomainRegistry(), config, logger).DispatchBatch(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, []string{"user"}, received)
+		assert.Equal(t, []int64{1}, store.processed)
+	})
+
+	t.Run("failed handler is retried with backoff", func(t *testing.T) {
+		store := newMemoryOutboxStore(claimedRegistered(t, 1, "alice", 1), claimedRegistered(t, 2, "bob", 2))
+		bus := NewOutboxBus(nil, nil, NewDomainRegistry(), logger)
+		_ = bus.Subscribe("user.registered", "wallet.create", func(context.Context, domenevents.Event, *zap.SugaredLogger) error {
+			return errors.New("wallet is not available")
+		})
+
+		_, err := NewDispatcher(store, bus, NewDomainRegistry(), config, logger).DispatchBatch(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, store.processed)
+		assert.Equal(t, map[int64]time.Duration{1: 2 * time.Second}, store.retried)
+		assert.Contains(t, store.deadLetters[2], "wallet is not available")
+	})
+
+	t.Run("failed event holds back later events of its key", func(t *testing.T) {
+		store := newMemoryOutboxStore(claimedRegistered(t, 1, "alice", 0), claimedRegistered(t, 2, "alice", 0), claimedRegistered(t, 3, "bob", 0))
+		bus := NewOutboxBus(nil, nil, NewDomainRegistry(), logger)
+
+		calls := 0
+		_ = bus.Subscribe("user.registered", "wallet.create", func(_ context.Context, e domenevents.Event, _ *zap.SugaredLogger) error {
+			calls++
+			if e.(*user.Registered).UserID == "alice" {
+				return errors.New("wallet is not available")
+			}
+			return nil
+		})
+
+		_, err := NewDispatcher(store, bus, NewDomainRegistry(), config, logger).DispatchBatch(context.Background())
+		require.NoError(t, err)
+		// подписчик вызван для первого события alice и для bob
+		assert.Equal(t, 2, calls)
+		assert.Contains(t, store.retried, int64(1))
+		// второе событие alice не доставлено и возвращено в очередь без траты попытки
+		assert.Equal(t, []int64{2}, store.released)
+		assert.Equal(t, []int64{3}, store.processed)
+	})
+
+	t.Run("successful handlers are not repeated", func(t *testing.T) {
+		e := claimedRegistered(t, 1, "user", 1)
+		store := newMemoryOutboxStore(e)
+		bus := NewOutboxBus(nil, nil, NewDomainRegistry(), logger)
+
+		var calls []string
+		_ = bus.Subscribe("user.registered", "wallet.create", func(context.Context, domenevents.Event, *zap.SugaredLogger) error {
+			calls = append(calls, "wallet")
+			return nil
+		})
+		_ = bus.Subscribe("user.registered", "mail.welcome", func(context.Context, domenevents.Event, *zap.SugaredLogger) error {
+			calls = append(calls, "mail")
+			return nil
+		})
+
+		// второй подписчик уже обработал событие; старый индекс в заголовке не учитывается
+		e.Headers[deliveredHeader] = []any{"mail.welcome", float64(0)}
+
+		_, err := NewDispatcher(store, bus, NewDomainRegistry(), config, logger).DispatchBatch(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"wallet"}, calls)
+		assert.Equal(t, []int64{1}, store.processed)
+	})
+
+	t.Run("unknown event fails immediately", func(t *testing.T) {
+		store := newMemoryOutboxStore(&ClaimedEvent{ID: 1, EventType: "unknown", Payload: []byte(`{}`), Headers: map[string]any{}, MaxRetries: 3})
+
+		_, err := NewDispatcher(store, NewOutboxBus(nil, nil, NewDomainRegistry(), logger), NewDomainRegistry(), config, logger).DispatchBatch(context.Background())
+		require.NoError(t, err)
+		assert.Contains(t, store.deadLetters[1], ErrUnknownEventType.Error())
+	})
+
+	t.Run("unknown schema version fails immediately", func(t *testing.T) {
+		e := claimedRegistered(t, 1, "user", 0)
+		e.SchemaVersion = 99
+		store := newMemoryOutboxStore(e)
+
+		_, err := NewDispatcher(store, NewOutboxBus(nil, nil, NewDomainRegistry(), logger), NewDomainRegistry(), config, logger).DispatchBatch(context.Background())
+		require.NoError(t, err)
+		assert.Contains(t, store.deadLetters[1], ErrUnknownVersion.Error())
+	})
+}
+
+func TestDispatcher_OrderPerKey(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+
+	var claimed []*ClaimedEvent
+	users := []string{"alice", "bob", "carol"}
+	for i := range 5 {
+		for _, userID := range users {
+			payload, err := json.Marshal(&user.Registered{UserID: userID, Email: fmt.Sprintf("%d", i)})
+			require.NoError(t, err)
+
+			claimed = append(claimed, &ClaimedEvent{
+				ID:            int64(len(claimed) + 1),
+				EventType:     "user.registered",
+				SchemaVersion: 1,
+				Payload:       payload,
+				Headers:       map[string]any{},
+				MaxRetries:    3,
+			})
+		}
+	}
+	store := newMemoryOutboxStore(claimed...)
+	bus := NewOutboxBus(nil, nil, NewDomainRegistry(), logger)
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	require.NoError(t, bus.Subscribe("user.registered", "wallet.create", func(_ context.Context, e domenevents.Event, _ *zap.SugaredLogger) error {
+		registered := e.(*user.Registered)
+
+		mu.Lock()
+		defer mu.Unlock()
+		received[registered.UserID] = append(received[registered.UserID], registered.Email)
+		return nil
+	}))
+
+	// очередь в одно событие: раздача пачки ждёт воркеров, но ничего не теряет
+	config := DispatcherConfig{BatchSize: 100, Partitions: 4, QueueSize: 1, BaseBackoff: time.Second, MaxBackoff: time.Minute}
+	n, err := NewDispatcher(store, bus, NewDomainRegistry(), config, logger).DispatchBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, len(claimed), n)
+	assert.Len(t, store.processed, len(claimed))
+
+	for _, userID := range users {
+		assert.Equal(t, []string{"0", "1", "2", "3", "4"}, received[userID])
+	}
+}
+
+func TestOutboxBus_SubscriberNames(t *testing.T) {
+	bus := NewOutboxBus(nil, nil, NewDomainRegistry(), zap.NewNop().Sugar())
+	handler := func(context.Context, domenevents.Event, *zap.SugaredLogger) error { return nil }
+
+	require.NoError(t, bus.Subscribe("user.registered", "wallet.create", handler))
+	// одно имя на разные события допустимо
+	require.NoError(t, bus.Subscribe("order.processed", "wallet.create", handler))
+
+	assert.ErrorIs(t, bus.Subscribe("user.registered", "wallet.create", handler), ErrInvalidSubscriber)
+	assert.ErrorIs(t, bus.Subscribe("user.registered", "", handler), ErrInvalidSubscriber)
+	assert.Len(t, bus.Subscriptions("user.registered"), 1)
+}
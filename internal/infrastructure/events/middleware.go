@@ -0,0 +1,148 @@
+package events
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+	"go.uber.org/zap"
+)
+
+// Recover превращает панику подписчика в ошибку доставки
+func Recover() events.Middleware {
+	return func(next events.Handler) events.Handler {
+		return func(ctx context.Context, event events.Event, logger *zap.SugaredLogger) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("handler panic: %v", r)
+				}
+			}()
+
+			return next(ctx, event, logger)
+		}
+	}
+}
+
+// Logging добавляет в логгер подписчика событие, его идентификатор и имя подписчика
+// и пишет итог каждой доставки
+func Logging() events.Middleware {
+	return func(next events.Handler) events.Handler {
+		return func(ctx context.Context, event events.Event, logger *zap.SugaredLogger) error {
+			delivery, _ := events.DeliveryFrom(ctx)
+			logger = logger.With(
+				"event", event.GetName(),
+				"event_id", delivery.Metadata.EventID,
+				"correlation_id", delivery.Metadata.CorrelationID,
+				"causation_id", delivery.Metadata.CausationID,
+				"actor", delivery.Metadata.Actor,
+				"handler", delivery.Handler,
+			)
+
+			started := time.Now()
+			err := next(ctx, event, logger)
+			if err != nil {
+				logger.Errorw("event handler failed", "attempt", delivery.Attempt, "duration", time.Since(started), "error", err)
+				return err
+			}
+
+			logger.Debugw("event handled", "attempt", delivery.Attempt, "duration", time.Since(started))
+			return nil
+		}
+	}
+}
+
+// HandlerMetrics - счётчики подписчиков, публикуются через expvar
+type HandlerMetrics struct {
+	handlers *expvar.Map
+}
+
+func NewHandlerMetrics(name string) *HandlerMetrics {
+	// expvar не позволяет опубликовать имя дважды
+	if existing, ok := expvar.Get(name).(*expvar.Map); ok {
+		return &HandlerMetrics{handlers: existing}
+	}
+
+	return &HandlerMetrics{handlers: expvar.NewMap(name)}
+}
+
+func (m *HandlerMetrics) observe(handler string, duration time.Duration, err error) {
+	stats, ok := m.handlers.Get(handler).(*expvar.Map)
+	if !ok {
+		stats = new(expvar.Map).Init()
+		m.handlers.Set(handler, stats)
+	}
+
+	stats.Add("calls", 1)
+	stats.AddFloat("latency_seconds_total", duration.Seconds())
+	if err != nil {
+		stats.Add("failures", 1)
+	}
+}
+
+// Metrics считает вызовы, ошибки и суммарное время каждого подписчика
+func Metrics(metrics *HandlerMetrics) events.Middleware {
+	return func(next events.Handler) events.Handler {
+		return func(ctx context.Context, event events.Event, logger *zap.SugaredLogger) error {
+			delivery, _ := events.DeliveryFrom(ctx)
+
+			started := time.Now()
+			err := next(ctx, event, logger)
+			metrics.observe(delivery.Handler, time.Since(started), err)
+
+			return err
+		}
+	}
+}
+
+// Timeout ограничивает время работы подписчика
+func Timeout(timeout time.Duration) events.Middleware {
+	return func(next events.Handler) events.Handler {
+		return func(ctx context.Context, event events.Event, logger *zap.SugaredLogger) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			return next(ctx, event, logger)
+		}
+	}
+}
+
+type RetryPolicy struct {
+	// Attempts - сколько всего попыток, включая первую
+	Attempts int
+	// Backoff - пауза перед второй попыткой, дальше она удваивается
+	Backoff time.Duration
+	// Retryable решает, стоит ли повторять ошибку; nil повторяет любую
+	Retryable func(err error) bool
+}
+
+// Retry повторяет подписчика при ошибке, пока не кончатся попытки или контекст
+func Retry(policy RetryPolicy) events.Middleware {
+	return func(next events.Handler) events.Handler {
+		return func(ctx context.Context, event events.Event, logger *zap.SugaredLogger) error {
+			delivery, _ := events.DeliveryFrom(ctx)
+			backoff := policy.Backoff
+
+			var err error
+			for attempt := 1; ; attempt++ {
+				err = next(ctx, event, logger)
+				if err == nil || attempt >= policy.Attempts {
+					return err
+				}
+				if policy.Retryable != nil && !policy.Retryable(err) {
+					return err
+				}
+
+				logger.Warnw("event handler failed, retrying", "event", event.GetName(), "handler", delivery.Handler, "attempt", attempt, "error", err)
+
+				select {
+				case <-ctx.Done():
+					return err
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+			}
+		}
+	}
+}
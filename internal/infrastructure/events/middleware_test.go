@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+	domenevents "github.com/sviatilnik/gophermart/internal/domain/events"
+	"go.uber.org/zap"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	metrics := NewHandlerMetrics("test_event_handlers")
+	ctx := domenevents.WithDelivery(context.Background(), domenevents.Delivery{EventName: "accrual.created", Handler: "wallet.deposit"})
+
+	calls := 0
+	handler := domenevents.Chain(func(ctx context.Context, _ domenevents.Event, _ *zap.SugaredLogger) error {
+		calls++
+		if calls == 1 {
+			panic("wallet is locked")
+		}
+
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return nil
+	},
+		Logging(),
+		Metrics(metrics),
+		Retry(RetryPolicy{Attempts: 3, Backoff: time.Millisecond}),
+		Timeout(time.Second),
+		Recover(),
+	)
+
+	require.NoError(t, handler(ctx, &accrual.CreatedEvent{}, logger))
+	assert.Equal(t, 2, calls)
+
+	stats := metrics.handlers.Get("wallet.deposit").(*expvar.Map)
+	assert.Equal(t, "1", stats.Get("calls").String())
+	assert.Nil(t, stats.Get("failures"))
+}
+
+func TestRetry_NotRetryable(t *testing.T) {
+	permanent := errors.New("wallet not found")
+
+	calls := 0
+	handler := Retry(RetryPolicy{
+		Attempts:  5,
+		Backoff:   time.Millisecond,
+		Retryable: func(err error) bool { return !errors.Is(err, permanent) },
+	})(func(context.Context, domenevents.Event, *zap.SugaredLogger) error {
+		calls++
+		return permanent
+	})
+
+	assert.ErrorIs(t, handler(context.Background(), &accrual.CreatedEvent{}, zap.NewNop().Sugar()), permanent)
+	assert.Equal(t, 1, calls)
+}
@@ -3,53 +3,90 @@ package events
 import (
 	"context"
 	"database/sql"
-	"time"
+	"encoding/json"
 
+	"github.com/google/uuid"
 	domenevents "github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/transaction"
 	"go.uber.org/zap"
 )
 
 // OutboxBus реализует шину событий, которая при Publish пишет событие в outbox.
-// Subscribe делегирует во внутреннюю in-memory шину, чтобы обработчики были зарегистрированы.
+// Подписчиков вызывает Dispatcher, когда забирает событие из outbox.
 type OutboxBus struct {
-	db     *sql.DB
-	inner  domenevents.Bus
-	writer OutboxWriter
-	logger *zap.SugaredLogger
+	db          *sql.DB
+	writer      OutboxWriter
+	registry    *Registry
+	subscribers *subscribers
+	logger      *zap.SugaredLogger
 }
 
-func NewOutboxBus(db *sql.DB, inner domenevents.Bus, logger *zap.SugaredLogger, writer OutboxWriter) *OutboxBus {
-	return &OutboxBus{db: db, inner: inner, writer: writer, logger: logger}
+func NewOutboxBus(db *sql.DB, writer OutboxWriter, registry *Registry, logger *zap.SugaredLogger) *OutboxBus {
+	return &OutboxBus{
+		db:          db,
+		writer:      writer,
+		registry:    registry,
+		subscribers: newSubscribers(),
+		logger:      logger,
+	}
 }
 
-func (b *OutboxBus) Publish(event domenevents.Event) error {
-	// Пишем в outbox вне внешней транзакции (упрощённый вариант).
-	// Диспетчер доставит до inner позже.
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Publish пишет событие в outbox. Если в контексте открыта транзакция, событие попадает в неё
+// и фиксируется вместе с изменениями агрегата.
+// Publish не ждёт подписчиков и не ограничивает поток записи: outbox - таблица, а ограничение очереди
+// (DispatcherConfig.QueueSize) действует только между диспетчером и воркерами партиций.
+func (b *OutboxBus) Publish(ctx context.Context, event domenevents.Event) error {
+	version, payload, err := b.registry.Encode(event)
+	if err != nil {
+		return err
+	}
 
-	tx, err := b.db.BeginTx(ctx, nil)
+	tx, err := transaction.Begin(ctx, b.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	aggregateType, aggregateID := "system", ""
+	if aggregate, ok := event.(domenevents.AggregateEvent); ok {
+		aggregateType, aggregateID = aggregate.AggregateType(), aggregate.AggregateID()
+	}
+
+	md := domenevents.NewMetadata(ctx)
 	out := OutboxEvent{
-		AggregateType: "system",
-		AggregateID:   "",
+		EventID:       uuid.MustParse(md.EventID),
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		PartitionKey:  partitionKey(event),
 		EventType:     event.GetName(),
-		Payload:       event,
-		Headers:       map[string]any{},
-		OccurredAt:    time.Now(),
+		SchemaVersion: version,
+		Payload:       json.RawMessage(payload),
+		Headers:       metadataHeaders(md),
+		OccurredAt:    md.OccurredAt,
 	}
 
-	if err := b.writer.InsertEvent(ctx, tx, out); err != nil {
+	err = b.writer.InsertEvent(ctx, tx.Tx, out)
+	if err != nil {
 		return err
 	}
 
 	return tx.Commit()
 }
 
-func (b *OutboxBus) Subscribe(event string, handler domenevents.Handler) error {
-	return b.inner.Subscribe(event, handler)
+func (b *OutboxBus) Subscribe(event string, name string, handler domenevents.Handler) error {
+	return b.subscribers.subscribe(event, name, handler)
+}
+
+func (b *OutboxBus) Use(middlewares ...domenevents.Middleware) {
+	b.subscribers.use(middlewares...)
+}
+
+// Events возвращает имена событий, на которые есть подписчики
+func (b *OutboxBus) Events() []string {
+	return b.subscribers.events()
+}
+
+// Subscriptions возвращает подписчиков события в порядке подписки
+func (b *OutboxBus) Subscriptions(event string) []Subscription {
+	return b.subscribers.list(event)
 }
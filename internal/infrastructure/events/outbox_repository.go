@@ -8,13 +8,17 @@ import (
 
 	"github.com/google/uuid"
 	domenevents "github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/outbox"
 )
 
 type OutboxEvent struct {
 	EventID       uuid.UUID
 	AggregateType string
 	AggregateID   string
+	// PartitionKey - ключ упорядочивания доставки, см. partitionKey
+	PartitionKey  string
 	EventType     string
+	SchemaVersion int
 	Payload       any
 	Headers       map[string]any
 	OccurredAt    time.Time
@@ -24,6 +28,29 @@ type OutboxWriter interface {
 	InsertEvent(ctx context.Context, tx *sql.Tx, e OutboxEvent) error
 }
 
+// ClaimedEvent - строка outbox, взятая диспетчером в обработку
+type ClaimedEvent struct {
+	ID            int64
+	Metadata      domenevents.Metadata
+	EventType     string
+	SchemaVersion int
+	Payload       []byte
+	Headers       map[string]any
+	Retries       int
+	MaxRetries    int
+}
+
+// OutboxStore - операции диспетчера над таблицей outbox
+type OutboxStore interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*ClaimedEvent, error)
+	MarkProcessed(ctx context.Context, id int64) error
+	Retry(ctx context.Context, id int64, headers map[string]any, after time.Duration, lastError string) error
+	// Release возвращает взятое событие в очередь без попытки доставки
+	Release(ctx context.Context, id int64) error
+	// MarkDeadLetter переводит событие в dead letter, ошибка попадает в историю
+	MarkDeadLetter(ctx context.Context, id int64, headers map[string]any, lastError string) error
+}
+
 type PostgresOutboxRepository struct {
 	db *sql.DB
 }
@@ -45,22 +72,204 @@ func (r *PostgresOutboxRepository) InsertEvent(ctx context.Context, tx *sql.Tx,
 
 	_, err = tx.ExecContext(ctx, `
         INSERT INTO outbox_events (
-            event_id, aggregate_type, aggregate_id, event_type, payload, headers, occurred_at
-        ) VALUES ($1,$2,$3,$4,$5,$6,$7)
-    `, e.EventID, e.AggregateType, e.AggregateID, e.EventType, payloadBytes, headersBytes, e.OccurredAt)
+            event_id, aggregate_type, aggregate_id, partition_key, event_type, schema_version, payload, headers, occurred_at
+        ) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+    `, e.EventID, e.AggregateType, e.AggregateID, e.PartitionKey, e.EventType, e.SchemaVersion, payloadBytes, headersBytes, e.OccurredAt)
 	return err
 }
 
+// Ключи заголовков outbox, в которых хранится конверт события
+const (
+	correlationIDHeader = "correlation_id"
+	causationIDHeader   = "causation_id"
+	actorHeader         = "actor"
+)
+
+func metadataHeaders(md domenevents.Metadata) map[string]any {
+	return map[string]any{
+		correlationIDHeader: md.CorrelationID,
+		causationIDHeader:   md.CausationID,
+		actorHeader:         md.Actor,
+	}
+}
+
+func readMetadataHeaders(headers map[string]any, md *domenevents.Metadata) {
+	md.CorrelationID, _ = headers[correlationIDHeader].(string)
+	md.CausationID, _ = headers[causationIDHeader].(string)
+	md.Actor, _ = headers[actorHeader].(string)
+}
+
 // Helper to convert domain event into OutboxEvent
 func ToOutboxEvent(aggregateType string, aggregateID string, event domenevents.Event, payload any, headers map[string]any) OutboxEvent {
 	return OutboxEvent{
 		EventID:       uuid.New(),
 		AggregateType: aggregateType,
 		AggregateID:   aggregateID,
+		PartitionKey:  partitionKey(event),
 		EventType:     event.GetName(),
+		SchemaVersion: 1,
 		Payload:       payload,
 		Headers:       headers,
 		OccurredAt:    time.Now(),
 	}
 }
 
+// Claim забирает готовые к доставке события. SKIP LOCKED позволяет нескольким диспетчерам не мешать друг другу,
+// а аренда возвращает в очередь события, диспетчер которых упал посреди обработки.
+// Событие не берётся, пока более раннее событие того же ключа ждёт повтора или занято другим диспетчером,
+// иначе события одного ключа доставлялись бы не по порядку.
+func (r *PostgresOutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*ClaimedEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE outbox_events SET
+			status = $1,
+			retries = CASE WHEN status = $1 THEN retries + 1 ELSE retries END,
+			process_after = NOW() + $2 * INTERVAL '1 millisecond',
+			updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM outbox_events e
+			WHERE status IN ($3, $1) AND process_after <= NOW()
+			  AND NOT EXISTS (
+				SELECT 1 FROM outbox_events earlier
+				WHERE e.partition_key <> ''
+				  AND earlier.partition_key = e.partition_key
+				  AND earlier.id < e.id
+				  AND earlier.status IN ($3, $1)
+				  AND earlier.process_after > NOW()
+			  )
+			ORDER BY id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_id, occurred_at, event_type, schema_version, payload, headers, retries, max_retries`,
+		outbox.StatusProcessing, lease.Milliseconds(), outbox.StatusPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	claimed := make([]*ClaimedEvent, 0)
+	for rows.Next() {
+		e := &ClaimedEvent{}
+		var headers []byte
+		err = rows.Scan(&e.ID, &e.Metadata.EventID, &e.Metadata.OccurredAt, &e.EventType, &e.SchemaVersion, &e.Payload, &headers, &e.Retries, &e.MaxRetries)
+		if err != nil {
+			return nil, err
+		}
+
+		e.Headers = make(map[string]any)
+		if len(headers) > 0 {
+			if err = json.Unmarshal(headers, &e.Headers); err != nil {
+				return nil, err
+			}
+		}
+		readMetadataHeaders(e.Headers, &e.Metadata)
+
+		claimed = append(claimed, e)
+	}
+
+	return claimed, rows.Err()
+}
+
+func (r *PostgresOutboxRepository) MarkProcessed(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE outbox_events SET status = $1, last_error = NULL, updated_at = NOW() WHERE id = $2`,
+		outbox.StatusProcessed, id)
+	return err
+}
+
+// Retry откладывает следующую попытку и сохраняет ошибку в историю
+func (r *PostgresOutboxRepository) Retry(ctx context.Context, id int64, headers map[string]any, after time.Duration, lastError string) error {
+	headersBytes, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		WITH updated AS (
+			UPDATE outbox_events SET
+				status = $1,
+				retries = retries + 1,
+				process_after = NOW() + $2 * INTERVAL '1 millisecond',
+				headers = $3,
+				last_error = $4,
+				updated_at = NOW()
+			WHERE id = $5
+			RETURNING id, retries
+		)
+		INSERT INTO outbox_event_errors (outbox_event_id, attempt, error)
+		SELECT id, retries, $4 FROM updated`,
+		outbox.StatusPending, after.Milliseconds(), headersBytes, lastError, id)
+	return err
+}
+
+// Release снимает аренду, не засчитывая попытку: событие ждало, пока доставится более раннее событие его ключа
+func (r *PostgresOutboxRepository) Release(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE outbox_events SET status = $1, process_after = NOW(), updated_at = NOW() WHERE id = $2`,
+		outbox.StatusPending, id)
+	return err
+}
+
+func (r *PostgresOutboxRepository) MarkDeadLetter(ctx context.Context, id int64, headers map[string]any, lastError string) error {
+	headersBytes, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		WITH updated AS (
+			UPDATE outbox_events SET
+				status = $1,
+				headers = $2,
+				last_error = $3,
+				dead_lettered_at = NOW(),
+				updated_at = NOW()
+			WHERE id = $4
+			RETURNING id, retries
+		)
+		INSERT INTO outbox_event_errors (outbox_event_id, attempt, error)
+		SELECT id, retries + 1, $3 FROM updated`,
+		outbox.StatusDeadLetter, headersBytes, lastError, id)
+	return err
+}
+
+// ReadAfter читает события в порядке транзакций независимо от статуса доставки подписчикам.
+// Транзакции младше xmin текущего снимка завершены, новых событий с такими номерами транзакций не появится.
+func (r *PostgresOutboxRepository) ReadAfter(ctx context.Context, position SinkPosition, limit int) ([]SinkRecord, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT xact_id::text::bigint, id, event_id, occurred_at, event_type, schema_version, aggregate_type, aggregate_id, payload, headers
+		FROM outbox_events
+		WHERE (xact_id, id) > ($1::bigint::text::xid8, $2)
+		  AND xact_id < pg_snapshot_xmin(pg_current_snapshot())
+		ORDER BY xact_id, id
+		LIMIT $3`,
+		position.Xact, position.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]SinkRecord, 0)
+	for rows.Next() {
+		var record SinkRecord
+		var payload, headers []byte
+		err = rows.Scan(&record.Xact, &record.Position, &record.EventID, &record.OccurredAt, &record.EventType, &record.SchemaVersion,
+			&record.AggregateType, &record.AggregateID, &payload, &headers)
+		if err != nil {
+			return nil, err
+		}
+
+		h := make(map[string]any)
+		if len(headers) > 0 {
+			if err = json.Unmarshal(headers, &h); err != nil {
+				return nil, err
+			}
+		}
+		readMetadataHeaders(h, &record.Metadata)
+		record.Payload = payload
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	domenevents "github.com/sviatilnik/gophermart/internal/domain/events"
+)
+
+var (
+	ErrUnknownEventType = errors.New("unknown event type")
+	ErrUnknownVersion   = errors.New("unknown event schema version")
+	ErrSchemaMismatch   = errors.New("event does not match its schema")
+)
+
+// schema - одна версия формата события на проводе
+type schema struct {
+	// encode нет у устаревших версий: их можно только прочитать
+	encode func(event domenevents.Event) (any, error)
+	decode func(payload []byte) (domenevents.Event, error)
+}
+
+// Registry сопоставляет имена событий с Go-типами и версиями их формата.
+// Outbox, внешние приёмники и инструменты переотправки кодируют события только через реестр.
+type Registry struct {
+	schemas map[string]map[int]*schema
+	latest  map[string]int
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas: make(map[string]map[int]*schema),
+		latest:  make(map[string]int),
+	}
+}
+
+// Register добавляет версию формата события name. Событие E переводится в payload P и обратно;
+// новые события кодируются последней зарегистрированной версией.
+func Register[E domenevents.Event, P any](r *Registry, name string, version int, encode func(E) P, decode func(P) E) {
+	r.add(name, version, &schema{
+		encode: func(event domenevents.Event) (any, error) {
+			typed, ok := event.(E)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s got %T", ErrSchemaMismatch, name, event)
+			}
+
+			return encode(typed), nil
+		},
+		decode: func(payload []byte) (domenevents.Event, error) {
+			var p P
+			decoder := json.NewDecoder(bytes.NewReader(payload))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&p); err != nil {
+				return nil, fmt.Errorf("%w: %s v%d: %v", ErrSchemaMismatch, name, version, err)
+			}
+
+			return decode(p), nil
+		},
+	})
+}
+
+// RegisterLegacy добавляет версию, которую можно только прочитать: payload в ней - json.Marshal самого события
+func RegisterLegacy[E domenevents.Event](r *Registry, name string, version int, factory func() E) {
+	r.add(name, version, &schema{
+		decode: func(payload []byte) (domenevents.Event, error) {
+			event := factory()
+			if err := json.Unmarshal(payload, event); err != nil {
+				return nil, fmt.Errorf("%w: %s v%d: %v", ErrSchemaMismatch, name, version, err)
+			}
+
+			return event, nil
+		},
+	})
+}
+
+func (r *Registry) add(name string, version int, s *schema) {
+	if _, ok := r.schemas[name]; !ok {
+		r.schemas[name] = make(map[int]*schema)
+	}
+	if _, ok := r.schemas[name][version]; ok {
+		panic(fmt.Sprintf("events: schema %s v%d registered twice", name, version))
+	}
+
+	r.schemas[name][version] = s
+	if s.encode != nil && version > r.latest[name] {
+		r.latest[name] = version
+	}
+}
+
+// Encode кодирует событие последней версией его формата
+func (r *Registry) Encode(event domenevents.Event) (int, []byte, error) {
+	name := event.GetName()
+	version, ok := r.latest[name]
+	if !ok {
+		return 0, nil, fmt.Errorf("%w: %s", ErrUnknownEventType, name)
+	}
+
+	payload, err := r.schemas[name][version].encode(event)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return version, data, nil
+}
+
+// Decode восстанавливает типизированное событие из payload указанной версии
+func (r *Registry) Decode(name string, version int, payload []byte) (domenevents.Event, error) {
+	versions, ok := r.schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, name)
+	}
+
+	s, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s v%d", ErrUnknownVersion, name, version)
+	}
+
+	return s.decode(payload)
+}
+
+// Names - события, которые реестр умеет кодировать
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.latest))
+	for name := range r.latest {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Check проверяет, что все перечисленные события зарегистрированы. Вызывается при старте,
+// чтобы незарегистрированное событие не обнаружилось только при первой публикации.
+func (r *Registry) Check(names ...string) error {
+	var errs []error
+	for _, name := range names {
+		if _, ok := r.latest[name]; !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownEventType, name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
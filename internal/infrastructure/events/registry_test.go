@@ -0,0 +1,133 @@
+package events
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+	domenevents "github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/order"
+	"github.com/sviatilnik/gophermart/internal/domain/user"
+	"github.com/sviatilnik/gophermart/internal/domain/wallet"
+)
+
+type unregisteredEvent struct{}
+
+func (e *unregisteredEvent) GetName() string { return "test.unregistered" }
+
+func TestDomainRegistry_RoundTrip(t *testing.T) {
+	uploadedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	uploaded := &order.Uploaded{
+		OrderID:     "order-1",
+		OrderNumber: "12345678903",
+		CustomerID:  "customer-1",
+		UploadedAt:  uploadedAt,
+		Items:       []order.LineItem{{Name: "coffee", Price: 3.5}},
+	}
+
+	tests := []struct {
+		name    string
+		event   domenevents.Event
+		version int
+	}{
+		{name: "user registered", event: &user.Registered{UserID: "user-1", Email: "user@example.com", OccurredAt: uploadedAt}, version: 2},
+		{name: "accrual created", event: &accrual.CreatedEvent{OrderNumber: "12345678903", Amount: 10, Status: "PROCESSED", CustomerID: "customer-1"}, version: 2},
+		{name: "order uploaded", event: uploaded, version: 2},
+		{name: "order batch uploaded", event: &order.BatchUploaded{Orders: []*order.Uploaded{uploaded}}, version: 2},
+		{name: "order processed", event: &order.ProcessedEvent{OrderID: "order-1", OrderNumber: "12345678903", CustomerID: "customer-1", Accrual: 10}, version: 2},
+		{name: "order cancelled", event: &order.CancelledEvent{OrderID: "order-1", OrderNumber: "12345678903", CustomerID: "customer-1", PreviousState: order.New, Cause: "customer request"}, version: 2},
+		{name: "wallet withdrawn", event: &wallet.PointsWithdrawn{CustomerID: "customer-1", OrderNumber: "2377225624", Amount: 5, WithdrawnAt: uploadedAt}, version: 1},
+	}
+
+	registry := NewDomainRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, payload, err := registry.Encode(tt.event)
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, version)
+
+			decoded, err := registry.Decode(tt.event.GetName(), version, payload)
+			require.NoError(t, err)
+			assert.Equal(t, tt.event, decoded)
+		})
+	}
+}
+
+func TestDomainRegistry_DecodeLegacy(t *testing.T) {
+	event := &order.ProcessedEvent{OrderID: "order-1", OrderNumber: "12345678903", CustomerID: "customer-1", Accrual: 10}
+	payload, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	decoded, err := NewDomainRegistry().Decode("order.processed", 1, payload)
+	require.NoError(t, err)
+	assert.Equal(t, event, decoded)
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	registry := NewDomainRegistry()
+
+	_, _, err := registry.Encode(&unregisteredEvent{})
+	assert.ErrorIs(t, err, ErrUnknownEventType)
+
+	_, err = registry.Decode("order.processed", 2, []byte(`{"order_id":"order-1","amount":10}`))
+	assert.ErrorIs(t, err, ErrSchemaMismatch)
+
+	_, err = registry.Decode("order.processed", 3, []byte(`{}`))
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+
+	assert.NoError(t, registry.Check("user.registered", "order.processed"))
+	assert.ErrorIs(t, registry.Check("order.processed", "test.unregistered"), ErrUnknownEventType)
+
+	assert.Panics(t, func() {
+		RegisterLegacy(registry, "order.processed", 1, func() *order.ProcessedEvent { return &order.ProcessedEvent{} })
+	})
+}
+
+// TestPublishedEvents_Registered ищет в доменных пакетах все события (методы GetName) и проверяет,
+// что каждое есть в списке публикуемых и у каждого есть схема
+func TestPublishedEvents_Registered(t *testing.T) {
+	var domain []string
+	err := filepath.WalkDir("../../domain", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			return err
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "GetName" || fn.Body == nil || len(fn.Body.List) != 1 {
+				continue
+			}
+			ret, ok := fn.Body.List[0].(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != 1 {
+				continue
+			}
+			if lit, ok := ret.Results[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				name, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					return err
+				}
+				domain = append(domain, name)
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, domain)
+
+	assert.ElementsMatch(t, domain, PublishedEvents())
+	assert.NoError(t, NewDomainRegistry().Check(PublishedEvents()...))
+}
@@ -0,0 +1,189 @@
+package events
+
+import (
+	"time"
+
+	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+	domenevents "github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/order"
+	"github.com/sviatilnik/gophermart/internal/domain/user"
+	"github.com/sviatilnik/gophermart/internal/domain/wallet"
+)
+
+// Версия 1 - то, что outbox писал до появления схем: json.Marshal доменной структуры.
+// Версия 2 - явный формат с именами полей в snake_case, не зависящий от Go-структур.
+
+type userRegisteredV2 struct {
+	UserID     string    `json:"user_id"`
+	Email      string    `json:"email"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type accrualCreatedV2 struct {
+	OrderNumber string  `json:"order_number"`
+	CustomerID  string  `json:"customer_id"`
+	Status      string  `json:"status"`
+	Amount      float64 `json:"amount"`
+}
+
+type lineItemV2 struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+type orderUploadedV2 struct {
+	OrderID     string       `json:"order_id"`
+	OrderNumber string       `json:"order_number"`
+	CustomerID  string       `json:"customer_id"`
+	UploadedAt  time.Time    `json:"uploaded_at"`
+	Items       []lineItemV2 `json:"items,omitempty"`
+}
+
+type orderBatchUploadedV2 struct {
+	Orders []orderUploadedV2 `json:"orders"`
+}
+
+type orderProcessedV2 struct {
+	OrderID     string  `json:"order_id"`
+	OrderNumber string  `json:"order_number"`
+	CustomerID  string  `json:"customer_id"`
+	Accrual     float64 `json:"accrual"`
+}
+
+type orderCancelledV2 struct {
+	OrderID       string `json:"order_id"`
+	OrderNumber   string `json:"order_number"`
+	CustomerID    string `json:"customer_id"`
+	PreviousState string `json:"previous_state"`
+	Cause         string `json:"cause"`
+}
+
+type walletWithdrawnV1 struct {
+	CustomerID  string    `json:"customer_id"`
+	OrderNumber string    `json:"order_number"`
+	Amount      float64   `json:"amount"`
+	WithdrawnAt time.Time `json:"withdrawn_at"`
+}
+
+// publishedEvents - все события, которые приложение пишет в outbox, в том числе без подписчиков
+var publishedEvents = []domenevents.Event{
+	&user.Registered{},
+	&accrual.CreatedEvent{},
+	&order.Uploaded{},
+	&order.BatchUploaded{},
+	&order.ProcessedEvent{},
+	&order.CancelledEvent{},
+	&wallet.PointsWithdrawn{},
+}
+
+// PublishedEvents - имена событий, которые публикует приложение. При старте их схемы проверяются в реестре.
+func PublishedEvents() []string {
+	names := make([]string, len(publishedEvents))
+	for i, event := range publishedEvents {
+		names[i] = event.GetName()
+	}
+
+	return names
+}
+
+// NewDomainRegistry - реестр со всеми событиями, которые публикует приложение
+func NewDomainRegistry() *Registry {
+	r := NewRegistry()
+
+	RegisterLegacy(r, "user.registered", 1, func() *user.Registered { return &user.Registered{} })
+	Register(r, "user.registered", 2,
+		func(e *user.Registered) userRegisteredV2 {
+			return userRegisteredV2{UserID: e.UserID, Email: e.Email, OccurredAt: e.OccurredAt}
+		},
+		func(p userRegisteredV2) *user.Registered {
+			return &user.Registered{UserID: p.UserID, Email: p.Email, OccurredAt: p.OccurredAt}
+		})
+
+	RegisterLegacy(r, "accrual.created", 1, func() *accrual.CreatedEvent { return &accrual.CreatedEvent{} })
+	Register(r, "accrual.created", 2,
+		func(e *accrual.CreatedEvent) accrualCreatedV2 {
+			return accrualCreatedV2{OrderNumber: e.OrderNumber, CustomerID: e.CustomerID, Status: e.Status, Amount: e.Amount}
+		},
+		func(p accrualCreatedV2) *accrual.CreatedEvent {
+			return &accrual.CreatedEvent{OrderNumber: p.OrderNumber, CustomerID: p.CustomerID, Status: p.Status, Amount: p.Amount}
+		})
+
+	RegisterLegacy(r, "order.uploaded", 1, func() *order.Uploaded { return &order.Uploaded{} })
+	Register(r, "order.uploaded", 2, toOrderUploadedV2, fromOrderUploadedV2)
+
+	RegisterLegacy(r, "order.batch_uploaded", 1, func() *order.BatchUploaded { return &order.BatchUploaded{} })
+	Register(r, "order.batch_uploaded", 2,
+		func(e *order.BatchUploaded) orderBatchUploadedV2 {
+			p := orderBatchUploadedV2{Orders: make([]orderUploadedV2, len(e.Orders))}
+			for i, uploaded := range e.Orders {
+				p.Orders[i] = toOrderUploadedV2(uploaded)
+			}
+			return p
+		},
+		func(p orderBatchUploadedV2) *order.BatchUploaded {
+			e := &order.BatchUploaded{Orders: make([]*order.Uploaded, len(p.Orders))}
+			for i, uploaded := range p.Orders {
+				e.Orders[i] = fromOrderUploadedV2(uploaded)
+			}
+			return e
+		})
+
+	RegisterLegacy(r, "order.processed", 1, func() *order.ProcessedEvent { return &order.ProcessedEvent{} })
+	Register(r, "order.processed", 2,
+		func(e *order.ProcessedEvent) orderProcessedV2 {
+			return orderProcessedV2{OrderID: e.OrderID, OrderNumber: e.OrderNumber, CustomerID: e.CustomerID, Accrual: e.Accrual}
+		},
+		func(p orderProcessedV2) *order.ProcessedEvent {
+			return &order.ProcessedEvent{OrderID: p.OrderID, OrderNumber: p.OrderNumber, CustomerID: p.CustomerID, Accrual: p.Accrual}
+		})
+
+	RegisterLegacy(r, "order.cancelled", 1, func() *order.CancelledEvent { return &order.CancelledEvent{} })
+	Register(r, "order.cancelled", 2,
+		func(e *order.CancelledEvent) orderCancelledV2 {
+			return orderCancelledV2{
+				OrderID:       e.OrderID,
+				OrderNumber:   e.OrderNumber,
+				CustomerID:    e.CustomerID,
+				PreviousState: string(e.PreviousState),
+				Cause:         e.Cause,
+			}
+		},
+		func(p orderCancelledV2) *order.CancelledEvent {
+			return &order.CancelledEvent{
+				OrderID:       p.OrderID,
+				OrderNumber:   p.OrderNumber,
+				CustomerID:    p.CustomerID,
+				PreviousState: order.State(p.PreviousState),
+				Cause:         p.Cause,
+			}
+		})
+
+	// событие появилось уже после реестра, устаревшего формата у него нет
+	Register(r, "wallet.withdrawn", 1,
+		func(e *wallet.PointsWithdrawn) walletWithdrawnV1 {
+			return walletWithdrawnV1{CustomerID: e.CustomerID, OrderNumber: e.OrderNumber, Amount: e.Amount, WithdrawnAt: e.WithdrawnAt}
+		},
+		func(p walletWithdrawnV1) *wallet.PointsWithdrawn {
+			return &wallet.PointsWithdrawn{CustomerID: p.CustomerID, OrderNumber: p.OrderNumber, Amount: p.Amount, WithdrawnAt: p.WithdrawnAt}
+		})
+
+	return r
+}
+
+func toOrderUploadedV2(e *order.Uploaded) orderUploadedV2 {
+	p := orderUploadedV2{OrderID: e.OrderID, OrderNumber: e.OrderNumber, CustomerID: e.CustomerID, UploadedAt: e.UploadedAt}
+	for _, item := range e.Items {
+		p.Items = append(p.Items, lineItemV2{Name: item.Name, Price: item.Price})
+	}
+
+	return p
+}
+
+func fromOrderUploadedV2(p orderUploadedV2) *order.Uploaded {
+	e := &order.Uploaded{OrderID: p.OrderID, OrderNumber: p.OrderNumber, CustomerID: p.CustomerID, UploadedAt: p.UploadedAt}
+	for _, item := range p.Items {
+		e.Items = append(e.Items, order.LineItem{Name: item.Name, Price: item.Price})
+	}
+
+	return e
+}
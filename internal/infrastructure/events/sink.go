@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	domenevents "github.com/sviatilnik/gophermart/internal/domain/events"
+)
+
+// SinkRecord - событие в том виде, в каком оно уходит во внешние системы
+type SinkRecord struct {
+	// Position - номер события в outbox
+	Position int64 `json:"position"`
+	// Xact - транзакция, записавшая событие; вместе с Position задаёт порядок выгрузки
+	Xact int64 `json:"-"`
+	domenevents.Metadata
+	EventType     string          `json:"event_type"`
+	SchemaVersion int             `json:"schema_version"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// EventSink - внешний приёмник событий: файл, stdout, брокер сообщений.
+// Доставка "хотя бы один раз": после сбоя пачка может прийти повторно,
+// приёмник отсеивает дубли по EventID или Position.
+type EventSink interface {
+	// Name - ключ контрольной точки, не должен меняться между запусками
+	Name() string
+	// Write возвращает nil, только когда пачка надёжно сохранена на стороне приёмника
+	Write(ctx context.Context, records []SinkRecord) error
+	Close() error
+}
+
+// SinkPosition - контрольная точка приёмника: последнее выгруженное событие
+// в порядке транзакций, которые его записали
+type SinkPosition struct {
+	Xact int64
+	ID   int64
+}
+
+// SinkSource - откуда экспорт читает события
+type SinkSource interface {
+	// ReadAfter возвращает события после position из уже завершившихся транзакций.
+	// Номера outbox выдаются до фиксации транзакции, поэтому событие с меньшим номером может появиться позже;
+	// порядок по транзакциям гарантирует, что после position новых событий уже не появится.
+	ReadAfter(ctx context.Context, position SinkPosition, limit int) ([]SinkRecord, error)
+}
+
+// SinkCheckpoints хранит, до какого события дошёл каждый приёмник
+type SinkCheckpoints interface {
+	Load(ctx context.Context, sink string) (SinkPosition, error)
+	Save(ctx context.Context, sink string, position SinkPosition) error
+}
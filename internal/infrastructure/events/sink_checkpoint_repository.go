@@ -0,0 +1,35 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type PostgresSinkCheckpoints struct {
+	db *sql.DB
+}
+
+func NewPostgresSinkCheckpoints(db *sql.DB) *PostgresSinkCheckpoints {
+	return &PostgresSinkCheckpoints{db: db}
+}
+
+// Load возвращает 0 для нового приёмника: он получит события с самого начала outbox
+func (r *PostgresSinkCheckpoints) Load(ctx context.Context, sink string) (SinkPosition, error) {
+	var position SinkPosition
+	err := r.db.QueryRowContext(ctx, `SELECT xact_id, position FROM event_sink_checkpoints WHERE sink = $1`, sink).
+		Scan(&position.Xact, &position.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return SinkPosition{}, nil
+	}
+
+	return position, err
+}
+
+func (r *PostgresSinkCheckpoints) Save(ctx context.Context, sink string, position SinkPosition) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO event_sink_checkpoints (sink, xact_id, position, updated_at) VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (sink) DO UPDATE SET xact_id = EXCLUDED.xact_id, position = EXCLUDED.position, updated_at = NOW()`,
+		sink, position.Xact, position.ID)
+	return err
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type SinkExporterConfig struct {
+	BatchSize int
+}
+
+// SinkExporter выгружает события outbox в один приёмник, продвигая его контрольную точку
+type SinkExporter struct {
+	sink        EventSink
+	source      SinkSource
+	checkpoints SinkCheckpoints
+	registry    *Registry
+	config      SinkExporterConfig
+	logger      *zap.SugaredLogger
+}
+
+func NewSinkExporter(sink EventSink, source SinkSource, checkpoints SinkCheckpoints, registry *Registry, config SinkExporterConfig, logger *zap.SugaredLogger) *SinkExporter {
+	return &SinkExporter{
+		sink:        sink,
+		source:      source,
+		checkpoints: checkpoints,
+		registry:    registry,
+		config:      config,
+		logger:      logger,
+	}
+}
+
+// ExportBatch выгружает одну пачку и возвращает её размер. Контрольная точка сохраняется
+// только после успешной записи, поэтому при сбое пачка будет выгружена повторно.
+func (e *SinkExporter) ExportBatch(ctx context.Context) (int, error) {
+	position, err := e.checkpoints.Load(ctx, e.sink.Name())
+	if err != nil {
+		return 0, err
+	}
+
+	records, err := e.source.ReadAfter(ctx, position, e.config.BatchSize)
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+
+	for i := range records {
+		e.normalize(&records[i])
+	}
+
+	err = e.sink.Write(ctx, records)
+	if err != nil {
+		return 0, err
+	}
+
+	last := records[len(records)-1]
+	err = e.checkpoints.Save(ctx, e.sink.Name(), SinkPosition{Xact: last.Xact, ID: last.Position})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(records), nil
+}
+
+// normalize переводит payload в последнюю версию схемы, чтобы внешние системы не разбирали устаревшие форматы.
+// Событие, которое реестр не может прочитать, выгружается как есть со своей версией.
+func (e *SinkExporter) normalize(record *SinkRecord) {
+	event, err := e.registry.Decode(record.EventType, record.SchemaVersion, record.Payload)
+	if err != nil {
+		e.logger.Warnw("event sink: exporting event in stored format", "sink", e.sink.Name(), "position", record.Position, "error", err)
+		return
+	}
+
+	version, payload, err := e.registry.Encode(event)
+	if err != nil {
+		e.logger.Warnw("event sink: exporting event in stored format", "sink", e.sink.Name(), "position", record.Position, "error", err)
+		return
+	}
+
+	record.SchemaVersion, record.Payload = version, payload
+}
+
+// run выгружает всё накопившееся; ошибка приёмника не мешает доставке подписчикам
+func (e *SinkExporter) run(ctx context.Context) {
+	for {
+		n, err := e.ExportBatch(ctx)
+		if err != nil {
+			e.logger.Errorw("event sink: export failed", "sink", e.sink.Name(), "error", err)
+			return
+		}
+		if n < e.config.BatchSize {
+			return
+		}
+	}
+}
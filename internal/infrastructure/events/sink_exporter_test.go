@@ -0,0 +1,117 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	domenevents "github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/domain/user"
+	"go.uber.org/zap"
+)
+
+type memorySinkSource []SinkRecord
+
+func (s memorySinkSource) ReadAfter(_ context.Context, position SinkPosition, limit int) ([]SinkRecord, error) {
+	result := make([]SinkRecord, 0)
+	for _, record := range s {
+		after := record.Xact > position.Xact || (record.Xact == position.Xact && record.Position > position.ID)
+		if after && len(result) < limit {
+			result = append(result, record)
+		}
+	}
+
+	return result, nil
+}
+
+type memorySinkCheckpoints map[string]SinkPosition
+
+func (c memorySinkCheckpoints) Load(_ context.Context, sink string) (SinkPosition, error) {
+	return c[sink], nil
+}
+
+func (c memorySinkCheckpoints) Save(_ context.Context, sink string, position SinkPosition) error {
+	c[sink] = position
+	return nil
+}
+
+// failingSink - приёмник, который теряет связь на первой записи
+type failingSink struct {
+	*WriterSink
+	fail bool
+}
+
+func (s *failingSink) Write(ctx context.Context, records []SinkRecord) error {
+	if s.fail {
+		s.fail = false
+		return errors.New("connection reset")
+	}
+
+	return s.WriterSink.Write(ctx, records)
+}
+
+func legacyRecord(t *testing.T, xact, position int64, userID string) SinkRecord {
+	payload, err := json.Marshal(&user.Registered{UserID: userID, Email: userID + "@example.com"})
+	require.NoError(t, err)
+
+	return SinkRecord{
+		Position:      position,
+		Xact:          xact,
+		Metadata:      domenevents.Metadata{EventID: userID, CorrelationID: "request-1"},
+		EventType:     "user.registered",
+		SchemaVersion: 1,
+		AggregateType: "user",
+		AggregateID:   userID,
+		Payload:       payload,
+	}
+}
+
+func TestSinkExporter_ExportBatch(t *testing.T) {
+	source := memorySinkSource{legacyRecord(t, 10, 1, "first"), legacyRecord(t, 10, 2, "second"), legacyRecord(t, 11, 5, "third")}
+	checkpoints := memorySinkCheckpoints{}
+	var out bytes.Buffer
+	sink := &failingSink{WriterSink: NewWriterSink("warehouse", &out), fail: true}
+
+	exporter := NewSinkExporter(sink, source, checkpoints, NewDomainRegistry(), SinkExporterConfig{BatchSize: 2}, zap.NewNop().Sugar())
+
+	// сбой приёмника не продвигает контрольную точку, пачка уйдёт повторно
+	_, err := exporter.ExportBatch(context.Background())
+	require.Error(t, err)
+	assert.Zero(t, checkpoints["warehouse"].ID)
+
+	n, err := exporter.ExportBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, SinkPosition{Xact: 10, ID: 2}, checkpoints["warehouse"])
+
+	n, err = exporter.ExportBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, SinkPosition{Xact: 11, ID: 5}, checkpoints["warehouse"])
+
+	// долгая транзакция получила номер 3 раньше, а завершилась позже: событие не теряется
+	exporter.source = append(source, legacyRecord(t, 12, 3, "late"))
+	n, err = exporter.ExportBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, SinkPosition{Xact: 12, ID: 3}, checkpoints["warehouse"])
+
+	decoder := json.NewDecoder(&out)
+	var exported []SinkRecord
+	for decoder.More() {
+		var record SinkRecord
+		require.NoError(t, decoder.Decode(&record))
+		exported = append(exported, record)
+	}
+
+	require.Len(t, exported, 4)
+	assert.Equal(t, "late", exported[3].AggregateID)
+	assert.Equal(t, "request-1", exported[0].CorrelationID)
+	// устаревший формат выгружается в последней версии схемы
+	assert.Equal(t, 2, exported[0].SchemaVersion)
+	assert.JSONEq(t, `{"user_id":"first","email":"first@example.com","occurred_at":"0001-01-01T00:00:00Z"}`, string(exported[0].Payload))
+}
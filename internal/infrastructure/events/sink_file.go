@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileSink пишет события в файлы JSON Lines и начинает новый файл, когда текущий вырастает до MaxSize.
+// Закрытые файлы называются <name>-<время ротации>.jsonl, текущий - <name>.jsonl.
+type FileSink struct {
+	name    string
+	dir     string
+	maxSize int64
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func NewFileSink(name string, dir string, maxSize int64) (*FileSink, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileSink{name: name, dir: dir, maxSize: maxSize}
+	err = s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileSink) Name() string {
+	return s.name
+}
+
+// Write дописывает пачку и сбрасывает её на диск: контрольная точка продвигается только после fsync
+func (s *FileSink) Write(_ context.Context, records []SinkRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size >= s.maxSize {
+		err := s.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := writeLines(s.file, records)
+	if err != nil {
+		return err
+	}
+
+	err = s.file.Sync()
+	if err != nil {
+		return err
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	s.size = info.Size()
+
+	return nil
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *FileSink) path() string {
+	return filepath.Join(s.dir, s.name+".jsonl")
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.file, s.size = file, info.Size()
+
+	return nil
+}
+
+func (s *FileSink) rotate() error {
+	err := s.file.Close()
+	if err != nil {
+		return err
+	}
+
+	rotated := filepath.Join(s.dir, fmt.Sprintf("%s-%s.jsonl", s.name, time.Now().UTC().Format("20060102T150405.000000000")))
+	err = os.Rename(s.path(), rotated)
+	if err != nil {
+		return err
+	}
+
+	return s.open()
+}
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSink_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileSink("events", dir, 100)
+	require.NoError(t, err)
+
+	record := SinkRecord{Position: 1, EventType: "user.registered", SchemaVersion: 2, Payload: []byte(`{"user_id":"user"}`)}
+	for range 3 {
+		require.NoError(t, sink.Write(context.Background(), []SinkRecord{record}))
+	}
+	require.NoError(t, sink.Close())
+
+	rotated, err := filepath.Glob(filepath.Join(dir, "events-*.jsonl"))
+	require.NoError(t, err)
+	assert.Len(t, rotated, 2)
+
+	current, err := os.ReadFile(filepath.Join(dir, "events.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(current), "\n"))
+}
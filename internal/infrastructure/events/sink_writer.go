@@ -0,0 +1,53 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// WriterSink пишет события в поток в формате JSON Lines
+type WriterSink struct {
+	name string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+func NewWriterSink(name string, w io.Writer) *WriterSink {
+	return &WriterSink{name: name, w: w}
+}
+
+// NewStdoutSink - приёмник для запуска в контейнере, где stdout собирает сборщик логов
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink("stdout", os.Stdout)
+}
+
+func (s *WriterSink) Name() string {
+	return s.name
+}
+
+func (s *WriterSink) Write(_ context.Context, records []SinkRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeLines(s.w, records)
+}
+
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+func writeLines(w io.Writer, records []SinkRecord) error {
+	buf := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return buf.Flush()
+}
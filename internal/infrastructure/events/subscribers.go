@@ -0,0 +1,81 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+)
+
+var ErrInvalidSubscriber = errors.New("invalid subscriber name")
+
+// Subscription - подписчик, уже обёрнутый в middleware шины
+type Subscription struct {
+	Name    string
+	Handler events.Handler
+}
+
+// subscribers - общие для шин подписчики и middleware
+type subscribers struct {
+	mu          sync.RWMutex
+	handlers    map[string][]Subscription
+	middlewares []events.Middleware
+}
+
+func newSubscribers() *subscribers {
+	return &subscribers{
+		handlers: make(map[string][]Subscription),
+	}
+}
+
+func (s *subscribers) subscribe(event string, name string, handler events.Handler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name == "" {
+		return fmt.Errorf("%w: empty name for %s", ErrInvalidSubscriber, event)
+	}
+	if slices.ContainsFunc(s.handlers[event], func(sub Subscription) bool { return sub.Name == name }) {
+		return fmt.Errorf("%w: %s is already subscribed to %s", ErrInvalidSubscriber, name, event)
+	}
+
+	s.handlers[event] = append(s.handlers[event], Subscription{Name: name, Handler: handler})
+
+	return nil
+}
+
+func (s *subscribers) use(middlewares ...events.Middleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
+// list возвращает подписчиков события в порядке подписки
+func (s *subscribers) list(event string) []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Subscription, len(s.handlers[event]))
+	for i, sub := range s.handlers[event] {
+		result[i] = Subscription{Name: sub.Name, Handler: events.Chain(sub.Handler, s.middlewares...)}
+	}
+
+	return result
+}
+
+// events возвращает отсортированные имена событий, у которых есть подписчики
+func (s *subscribers) events() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.handlers))
+	for name := range s.handlers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sviatilnik/gophermart/internal/application/accrual"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/http/middleware"
+)
+
+type AccrualPreviewHandler struct {
+	service *accrual.PreviewService
+}
+
+func NewAccrualPreviewHandler(service *accrual.PreviewService) *AccrualPreviewHandler {
+	return &AccrualPreviewHandler{
+		service: service,
+	}
+}
+
+func (h *AccrualPreviewHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req accrual.PreviewRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	customerID := r.Context().Value(middleware.RequestUserID).(string)
+
+	preview, err := h.service.Preview(r.Context(), customerID, req)
+	if err != nil {
+		if errors.Is(err, accrual.ErrEmptyPreview) || errors.Is(err, accrual.ErrInvalidPreviewItem) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(preview)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sviatilnik/gophermart/internal/application/accrual"
+)
+
+type AccrualReviewHandler struct {
+	service *accrual.ReviewService
+}
+
+func NewAccrualReviewHandler(service *accrual.ReviewService) *AccrualReviewHandler {
+	return &AccrualReviewHandler{
+		service: service,
+	}
+}
+
+func (h *AccrualReviewHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	statuses := make([]string, 0)
+	if status := r.URL.Query().Get("status"); status != "" {
+		statuses = strings.Split(strings.ToUpper(status), ",")
+	}
+
+	reviews, err := h.service.List(r.Context(), statuses, 100, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reviews)
+}
+
+func (h *AccrualReviewHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.service.Approve)
+}
+
+func (h *AccrualReviewHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.service.Reject)
+}
+
+type reviewDecision func(ctx context.Context, id string, decision accrual.ReviewDecisionDTO) (*accrual.ReviewDTO, error)
+
+func (h *AccrualReviewHandler) decide(w http.ResponseWriter, r *http.Request, decide reviewDecision) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var decision accrual.ReviewDecisionDTO
+	err := json.NewDecoder(r.Body).Decode(&decision)
+	if err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	review, err := decide(r.Context(), chi.URLParam(r, "id"), decision)
+	if err != nil {
+		switch {
+		case errors.Is(err, accrual.ErrReviewNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, accrual.ErrReviewAlreadyDecided):
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
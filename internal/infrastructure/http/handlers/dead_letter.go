@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sviatilnik/gophermart/internal/application/outbox"
+)
+
+type DeadLetterHandler struct {
+	service *outbox.DeadLetterService
+}
+
+func NewDeadLetterHandler(service *outbox.DeadLetterService) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		service: service,
+	}
+}
+
+func (h *DeadLetterHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	values := r.URL.Query()
+	filter := outbox.DeadLetterFilterDTO{
+		EventType: values.Get("event_type"),
+		Error:     values.Get("error"),
+	}
+
+	limit, offset := 100, 0
+	var err error
+	if v := values.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+	}
+	if v := values.Get("offset"); v != "" && err == nil {
+		offset, err = strconv.Atoi(v)
+	}
+	if err == nil {
+		filter.From, err = parseTimeParam(values.Get("from"), false)
+	}
+	if err == nil {
+		filter.To, err = parseTimeParam(values.Get("to"), true)
+	}
+	if err != nil || limit <= 0 || offset < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: errInvalidPageParams.Error()})
+		return
+	}
+
+	deadLetters, err := h.service.List(r.Context(), filter, limit, offset)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deadLetters)
+}
+
+func (h *DeadLetterHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	deadLetter, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deadLetter)
+}
+
+func (h *DeadLetterHandler) UpdateHeaders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	var headers map[string]any
+	err := json.NewDecoder(r.Body).Decode(&headers)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	deadLetter, err := h.service.UpdateHeaders(r.Context(), id, headers)
+	if err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deadLetter)
+}
+
+func (h *DeadLetterHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	h.one(w, r, h.service.Replay)
+}
+
+func (h *DeadLetterHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	h.one(w, r, h.service.Discard)
+}
+
+func (h *DeadLetterHandler) ReplayBulk(w http.ResponseWriter, r *http.Request) {
+	h.bulk(w, r, h.service.Replay)
+}
+
+func (h *DeadLetterHandler) DiscardBulk(w http.ResponseWriter, r *http.Request) {
+	h.bulk(w, r, h.service.Discard)
+}
+
+type deadLetterOperation func(ctx context.Context, filter outbox.DeadLetterFilterDTO) (*outbox.BulkResultDTO, error)
+
+func (h *DeadLetterHandler) one(w http.ResponseWriter, r *http.Request, apply deadLetterOperation) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := apply(r.Context(), outbox.DeadLetterFilterDTO{IDs: []int64{id}})
+	if err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *DeadLetterHandler) bulk(w http.ResponseWriter, r *http.Request, apply deadLetterOperation) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var filter outbox.DeadLetterFilterDTO
+	err := json.NewDecoder(r.Body).Decode(&filter)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := apply(r.Context(), filter)
+	if err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func deadLetterID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: "invalid dead letter id"})
+		return 0, false
+	}
+
+	return id, true
+}
+
+func writeDeadLetterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, outbox.ErrDeadLetterNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, outbox.ErrEmptyFilter):
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+}
@@ -1,22 +1,88 @@
 package handlers
 
 import (
+	"crypto/subtle"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
 	"github.com/sviatilnik/gophermart/internal/application/order"
 	order2 "github.com/sviatilnik/gophermart/internal/domain/order"
 	"github.com/sviatilnik/gophermart/internal/infrastructure/http/middleware"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
 )
 
+// MerchantKeyHeader - ключ мерчанта. По нему на сервере определяется источник заказа,
+// а с ним и схема проверки номера: сам клиент выбрать схему не может
+const MerchantKeyHeader = "X-Merchant-Key"
+
+var (
+	ErrInvalidMerchantKeys = errors.New("invalid merchant keys")
+	errUnknownMerchantKey  = errors.New("unknown merchant key")
+	errBatchNotArray       = errors.New("batch must be a JSON array of order numbers")
+)
+
+// MerchantKeys - источники заказов по ключам мерчантов
+type MerchantKeys map[string]string
+
+// ParseMerchantKeys разбирает описание вида "key-a=merchant-a;key-b=merchant-b"
+func ParseMerchantKeys(spec string) (MerchantKeys, error) {
+	keys := make(MerchantKeys)
+
+	for _, pair := range strings.Split(spec, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, source, ok := strings.Cut(pair, "=")
+		key, source = strings.TrimSpace(key), strings.TrimSpace(source)
+		if !ok || key == "" || source == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidMerchantKeys, pair)
+		}
+
+		keys[key] = source
+	}
+
+	return keys, nil
+}
+
+// Source возвращает источник заказа по ключу; без ключа заказ проверяется схемой по умолчанию
+func (k MerchantKeys) Source(key string) (string, bool) {
+	if key == "" {
+		return order2.DefaultSource, true
+	}
+
+	source, found := "", false
+	for known, merchant := range k {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(key)) == 1 {
+			source, found = merchant, true
+		}
+	}
+
+	return source, found
+}
+
+// batchLineBytes - сколько байт тела пакетной загрузки допускается на один номер:
+// сам номер, кавычки и разделители JSON или остальные колонки строки CSV
+const batchLineBytes = 256
+
 type OrderHandler struct {
-	service *order.Service
+	service      *order.Service
+	merchantKeys MerchantKeys
+	// maxBatchSize - сколько номеров принимается в одной пакетной загрузке, 0 - без ограничения
+	maxBatchSize int
 }
 
-func NewOrderHandler(service *order.Service) *OrderHandler {
+func NewOrderHandler(service *order.Service, merchantKeys MerchantKeys, maxBatchSize int) *OrderHandler {
 	return &OrderHandler{
-		service: service,
+		service:      service,
+		merchantKeys: merchantKeys,
+		maxBatchSize: maxBatchSize,
 	}
 }
 
@@ -26,16 +92,20 @@ func (h *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	orderNumber, err := io.ReadAll(r.Body)
-	if err != nil || string(orderNumber) == "" {
+	req, err := readCreateOrder(r)
+	if err != nil || req.Number == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	req.CustomerID = r.Context().Value(middleware.RequestUserID).(string)
 
-	req := order.CreateOrderDTO{
-		Number:     string(orderNumber),
-		CustomerID: r.Context().Value(middleware.RequestUserID).(string),
+	source, ok := h.merchantKeys.Source(r.Header.Get(MerchantKeyHeader))
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: errUnknownMerchantKey.Error()})
+		return
 	}
+	req.Source = source
 
 	newOrder, err := h.service.Create(r.Context(), req)
 	if err != nil {
@@ -45,12 +115,18 @@ func (h *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
 		}
 		if errors.Is(err, order2.ErrOrderNumberNotValid) {
 			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
 			return
 		}
 		if errors.Is(err, order.ErrAlreadyCreatedByOtherCustomer) {
 			w.WriteHeader(http.StatusConflict)
 			return
 		}
+		if errors.Is(err, order.ErrInvalidMetadata) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+			return
+		}
 
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
@@ -62,15 +138,206 @@ func (h *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(newOrder)
 }
 
+// readCreateOrder поддерживает text/plain с одним номером и JSON с номером и сведениями о покупке
+func readCreateOrder(r *http.Request) (order.CreateOrderDTO, error) {
+	var req order.CreateOrderDTO
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		err := json.NewDecoder(r.Body).Decode(&req)
+		return req, err
+	}
+
+	orderNumber, err := io.ReadAll(r.Body)
+	if err != nil {
+		return req, err
+	}
+	req.Number = string(orderNumber)
+
+	return req, nil
+}
+
+// CreateBatch принимает JSON-массив номеров или CSV, где номер - первая колонка
+func (h *OrderHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	source, ok := h.merchantKeys.Source(r.Header.Get(MerchantKeyHeader))
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: errUnknownMerchantKey.Error()})
+		return
+	}
+
+	if h.maxBatchSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, int64(h.maxBatchSize+1)*batchLineBytes)
+	}
+
+	numbers, err := readBatchNumbers(r, h.maxBatchSize)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.Is(err, order.ErrBatchTooLarge) || errors.As(err, &tooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(&ErrorResponse{Error: order.ErrBatchTooLarge.Error()})
+			return
+		}
+
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := h.service.CreateBatch(r.Context(), order.CreateOrdersBatchDTO{
+		Numbers:    numbers,
+		CustomerID: r.Context().Value(middleware.RequestUserID).(string),
+		Source:     source,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, order.ErrEmptyBatch):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, order.ErrBatchTooLarge):
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// readBatchNumbers читает номера из тела запроса и прекращает чтение, как только номеров больше limit
+func readBatchNumbers(r *http.Request, limit int) ([]string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType != "text/csv" {
+		return readJSONNumbers(r.Body, limit)
+	}
+
+	reader := csv.NewReader(r.Body)
+	reader.FieldsPerRecord = -1
+
+	numbers := make([]string, 0)
+	for i := 0; ; i++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return numbers, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		number := strings.TrimSpace(record[0])
+		// строка заголовка необязательна
+		if i == 0 && strings.EqualFold(number, "number") {
+			continue
+		}
+		if number == "" {
+			continue
+		}
+
+		numbers = append(numbers, number)
+		if limit > 0 && len(numbers) > limit {
+			return nil, order.ErrBatchTooLarge
+		}
+	}
+}
+
+// readJSONNumbers читает JSON-массив номеров по одному элементу
+func readJSONNumbers(body io.Reader, limit int) ([]string, error) {
+	decoder := json.NewDecoder(body)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, errBatchNotArray
+	}
+
+	numbers := make([]string, 0)
+	for decoder.More() {
+		var number string
+		if err = decoder.Decode(&number); err != nil {
+			return nil, err
+		}
+
+		numbers = append(numbers, number)
+		if limit > 0 && len(numbers) > limit {
+			return nil, order.ErrBatchTooLarge
+		}
+	}
+
+	_, err = decoder.Token()
+
+	return numbers, err
+}
+
 func (h *OrderHandler) GetList(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	page, err := h.service.GetOrders(r.Context(), order.ListOrdersDTO{
+		CustomerID: r.Context().Value(middleware.RequestUserID).(string),
+		Statuses:   parseListParam(r.URL.Query(), "status"),
+		From:       params.From,
+		To:         params.To,
+		Cursor:     params.Cursor,
+		Limit:      params.Limit,
+		Sort:       params.Sort,
+	})
+	if err != nil {
+		if errors.Is(err, order.ErrInvalidCursor) || errors.Is(err, order.ErrInvalidSort) || errors.Is(err, order.ErrUnknownState) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeNextPage(w, r, page.NextCursor)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page.Orders)
+}
+
+func (h *OrderHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
 	}
 
 	customerID := r.Context().Value(middleware.RequestUserID).(string)
 
-	orders, err := h.service.GetOrders(r.Context(), customerID, 20, 0)
+	details, err := h.service.GetOrderDetails(r.Context(), customerID, chi.URLParam(r, "number"))
 	if err != nil {
+		if errors.Is(err, order.ErrOrderNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, order2.ErrOrderNumberNotValid) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
 		return
@@ -78,5 +345,87 @@ func (h *OrderHandler) GetList(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(orders)
+	json.NewEncoder(w).Encode(details)
+}
+
+func (h *OrderHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	customerID := r.Context().Value(middleware.RequestUserID).(string)
+
+	cancelled, err := h.service.Cancel(r.Context(), customerID, chi.URLParam(r, "number"))
+	h.writeCancelled(w, cancelled, err)
+}
+
+// ForceCancel - отмена заказа администратором в любом статусе
+func (h *OrderHandler) ForceCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req order.CancelOrderDTO
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	cancelled, err := h.service.ForceCancel(r.Context(), chi.URLParam(r, "number"), req)
+	h.writeCancelled(w, cancelled, err)
+}
+
+// History - история заказа для администратора
+func (h *OrderHandler) History(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	history, err := h.service.GetHistory(r.Context(), chi.URLParam(r, "number"))
+	if err != nil {
+		switch {
+		case errors.Is(err, order.ErrOrderNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, order2.ErrOrderNumberNotValid):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+func (h *OrderHandler) writeCancelled(w http.ResponseWriter, cancelled *order.OrderDTO, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err != nil {
+		switch {
+		case errors.Is(err, order.ErrOrderNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, order2.ErrOrderNumberNotValid):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case errors.Is(err, order.ErrNotCancellable):
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(cancelled)
 }
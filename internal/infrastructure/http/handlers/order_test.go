@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/application/order"
+)
+
+func TestReadBatchNumbers(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []string
+		err         error
+	}{
+		{
+			name: "json",
+			body: `["79927398713", "12345678903"]`,
+			want: []string{"79927398713", "12345678903"},
+		},
+		{
+			name:        "csv with header",
+			contentType: "text/csv",
+			body:        "number,comment\n79927398713,first\n\n12345678903,second\n",
+			want:        []string{"79927398713", "12345678903"},
+		},
+		{
+			name: "json over limit",
+			body: `["1", "2", "3", "4"` + strings.Repeat(`, "5"`, 1000),
+			err:  order.ErrBatchTooLarge,
+		},
+		{
+			name:        "csv over limit",
+			contentType: "text/csv",
+			body:        "1\n2\n3\n4\n" + strings.Repeat("5\n", 1000),
+			err:         order.ErrBatchTooLarge,
+		},
+		{
+			name: "json object",
+			body: `{"numbers": []}`,
+			err:  errBatchNotArray,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/user/orders/batch", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			numbers, err := readBatchNumbers(r, 3)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, numbers)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	NextCursorHeader = "X-Next-Cursor"
+	dateLayout       = "2006-01-02"
+)
+
+var errInvalidPageParams = errors.New("invalid pagination parameters")
+
+// pageParams - параметры страницы из строки запроса
+type pageParams struct {
+	Cursor string
+	Limit  int
+	Sort   string
+	From   *time.Time
+	To     *time.Time
+}
+
+func parsePageParams(r *http.Request) (*pageParams, error) {
+	values := r.URL.Query()
+	params := &pageParams{
+		Cursor: values.Get("cursor"),
+		Sort:   values.Get("sort"),
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l <= 0 {
+			return nil, errInvalidPageParams
+		}
+		params.Limit = l
+	}
+
+	var err error
+	if params.From, err = parseTimeParam(values.Get("from"), false); err != nil {
+		return nil, err
+	}
+	if params.To, err = parseTimeParam(values.Get("to"), true); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// parseTimeParam принимает RFC3339 или дату; верхняя граница не включается, поэтому дата в to сдвигается на сутки
+func parseTimeParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, errInvalidPageParams
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return &t, nil
+}
+
+// parseListParam собирает значения вида ?status=A&status=B и ?status=A,B
+func parseListParam(values url.Values, key string) []string {
+	result := make([]string, 0)
+	for _, value := range values[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, strings.ToUpper(item))
+			}
+		}
+	}
+
+	return result
+}
+
+func writeNextPage(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+
+	next := *r.URL
+	query := next.Query()
+	query.Set("cursor", nextCursor)
+	next.RawQuery = query.Encode()
+
+	w.Header().Set(NextCursorHeader, nextCursor)
+	w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+}
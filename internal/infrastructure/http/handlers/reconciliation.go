@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sviatilnik/gophermart/internal/application/reconciliation"
+)
+
+type ReconciliationHandler struct {
+	service *reconciliation.Service
+}
+
+func NewReconciliationHandler(service *reconciliation.Service) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		service: service,
+	}
+}
+
+func (h *ReconciliationHandler) Run(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	report, err := h.service.Run(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *ReconciliationHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	statuses := make([]string, 0)
+	if status := r.URL.Query().Get("status"); status != "" {
+		statuses = strings.Split(strings.ToUpper(status), ",")
+	}
+
+	discrepancies, err := h.service.List(r.Context(), statuses, 100, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(discrepancies)
+}
+
+func (h *ReconciliationHandler) Repair(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	discrepancy, err := h.service.Repair(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, reconciliation.ErrDiscrepancyNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, reconciliation.ErrNotRepairable), errors.Is(err, reconciliation.ErrNotOpen):
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(discrepancy)
+}
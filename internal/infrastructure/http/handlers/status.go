@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sviatilnik/gophermart/internal/application/leader"
+)
+
+type StatusHandler struct {
+	elector *leader.Elector
+}
+
+func NewStatusHandler(elector *leader.Elector) *StatusHandler {
+	return &StatusHandler{
+		elector: elector,
+	}
+}
+
+func (h *StatusHandler) Leader(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	status, err := h.elector.Status(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
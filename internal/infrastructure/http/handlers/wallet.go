@@ -61,6 +61,8 @@ func (h *WalletHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if errors.Is(err, wallet.ErrNotEnoughFunds) {
 			w.WriteHeader(http.StatusPaymentRequired)
+		} else if errors.Is(err, wallet.ErrOrderNumberNotValid) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 		}
@@ -80,20 +82,38 @@ func (h *WalletHandler) Withdrawals(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	customerID := r.Context().Value(middleware.RequestUserID).(string)
+	params, err := parsePageParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
+		return
+	}
 
-	withdraws, err := h.service.GetWithdraws(r.Context(), customerID)
+	page, err := h.service.GetWithdraws(r.Context(), wallet.ListWithdrawsDTO{
+		CustomerID: r.Context().Value(middleware.RequestUserID).(string),
+		From:       params.From,
+		To:         params.To,
+		Cursor:     params.Cursor,
+		Limit:      params.Limit,
+		Sort:       params.Sort,
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		if errors.Is(err, wallet.ErrInvalidCursor) || errors.Is(err, wallet.ErrInvalidSort) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
 		json.NewEncoder(w).Encode(&ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	if len(withdraws) == 0 {
+	if len(page.Withdraws) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	writeNextPage(w, r, page.NextCursor)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(withdraws)
+	json.NewEncoder(w).Encode(page.Withdraws)
 }
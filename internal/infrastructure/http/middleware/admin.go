@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+)
+
+const AdminTokenHeader = "X-Admin-Token"
+
+type AdminMiddleware struct {
+	token string
+}
+
+func NewAdminMiddleware(token string) *AdminMiddleware {
+	return &AdminMiddleware{
+		token: token,
+	}
+}
+
+func (m *AdminMiddleware) Handle(nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// без настроенного токена админские методы недоступны
+		if m.token == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		token := r.Header.Get(AdminTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(m.token)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		nextHandler.ServeHTTP(w, r.WithContext(events.WithActor(r.Context(), "admin")))
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/sviatilnik/gophermart/internal/application/auth"
+	"github.com/sviatilnik/gophermart/internal/domain/events"
 	"net/http"
 	"strings"
 )
@@ -46,7 +47,8 @@ func (m *AuthMiddleware) Handle(nextHandler http.Handler) http.Handler {
 			return
 		}
 
-		r = r.WithContext(context.WithValue(r.Context(), RequestUserID, id))
+		ctx := context.WithValue(r.Context(), RequestUserID, id)
+		r = r.WithContext(events.WithActor(ctx, "user:"+id))
 		nextHandler.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+)
+
+// CorrelationIDHeader - заголовок, которым вызывающая сторона может продолжить свою цепочку
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// Trace связывает события, опубликованные при обработке запроса, с идентификатором запроса.
+// Должен стоять после middleware.RequestID.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := chiMiddleware.GetReqID(r.Context())
+
+		correlationID := r.Header.Get(CorrelationIDHeader)
+		if correlationID == "" {
+			correlationID = requestID
+		}
+		w.Header().Set(CorrelationIDHeader, correlationID)
+
+		ctx := events.WithTrace(r.Context(), events.Trace{
+			CorrelationID: correlationID,
+			CausationID:   requestID,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,4 @@
+begin;
+DROP INDEX IF EXISTS idx_wallet_events_deposit_order;
+DROP TABLE IF EXISTS reconciliation_discrepancies;
+commit;
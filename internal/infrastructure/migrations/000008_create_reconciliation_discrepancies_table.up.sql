@@ -0,0 +1,22 @@
+begin;
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id              uuid PRIMARY KEY,
+    kind            varchar(64) NOT NULL,
+    status          varchar(32) NOT NULL,
+    order_number    text NOT NULL,
+    customer_id     text NOT NULL,
+    order_state     varchar(255) NOT NULL,
+    expected_amount float NOT NULL DEFAULT 0,
+    actual_amount   float NOT NULL DEFAULT 0,
+    deposits        integer NOT NULL DEFAULT 0,
+    detected_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_seen_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    repaired_at     TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reconciliation_discrepancies_open
+    ON reconciliation_discrepancies (kind, order_number) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_reconciliation_discrepancies_status ON reconciliation_discrepancies (status, detected_at);
+CREATE INDEX IF NOT EXISTS idx_wallet_events_deposit_order
+    ON wallet_events ((event_data ->> 'OrderNumber')) WHERE event_type = 'deposited';
+commit;
@@ -0,0 +1,3 @@
+begin;
+DROP TABLE IF EXISTS leader_leases;
+commit;
@@ -0,0 +1,9 @@
+begin;
+CREATE TABLE IF NOT EXISTS leader_leases (
+    name        varchar(255) PRIMARY KEY,
+    holder_id   varchar(255) NOT NULL,
+    acquired_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    renewed_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL
+);
+commit;
@@ -0,0 +1,19 @@
+begin;
+CREATE TABLE IF NOT EXISTS accrual_reviews (
+    id             uuid PRIMARY KEY,
+    order_number   text NOT NULL,
+    customer_id    text NOT NULL,
+    provider_state varchar(100) NOT NULL,
+    amount         float NOT NULL DEFAULT 0,
+    reasons        JSONB NOT NULL DEFAULT '[]'::jsonb,
+    status         varchar(32) NOT NULL,
+    created_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+    decided_at     TIMESTAMP WITH TIME ZONE,
+    decided_by     text NOT NULL DEFAULT '',
+    comment        text NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accrual_reviews_pending
+    ON accrual_reviews (order_number) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_accrual_reviews_status ON accrual_reviews (status, created_at);
+commit;
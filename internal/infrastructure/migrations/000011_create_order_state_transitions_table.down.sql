@@ -0,0 +1,3 @@
+begin;
+DROP TABLE IF EXISTS order_state_transitions;
+commit;
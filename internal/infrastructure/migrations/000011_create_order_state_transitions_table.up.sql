@@ -0,0 +1,12 @@
+begin;
+CREATE TABLE IF NOT EXISTS order_state_transitions (
+    id          uuid PRIMARY KEY,
+    order_id    uuid NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    from_state  varchar(255) NOT NULL,
+    to_state    varchar(255) NOT NULL,
+    cause       text NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_state_transitions_order ON order_state_transitions (order_id, occurred_at);
+commit;
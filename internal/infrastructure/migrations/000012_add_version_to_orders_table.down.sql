@@ -0,0 +1,3 @@
+begin;
+ALTER TABLE orders DROP COLUMN IF EXISTS version;
+commit;
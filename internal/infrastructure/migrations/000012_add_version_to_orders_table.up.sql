@@ -0,0 +1,3 @@
+begin;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+commit;
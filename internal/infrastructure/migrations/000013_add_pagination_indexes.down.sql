@@ -0,0 +1,4 @@
+begin;
+DROP INDEX IF EXISTS idx_wallet_withdrawals_customer_timestamp;
+DROP INDEX IF EXISTS idx_orders_user_created;
+commit;
@@ -0,0 +1,4 @@
+begin;
+CREATE INDEX IF NOT EXISTS idx_orders_user_created ON orders (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_wallet_withdrawals_customer_timestamp ON wallet_withdrawals (customer_id, timestamp, id);
+commit;
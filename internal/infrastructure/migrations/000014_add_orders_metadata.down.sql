@@ -0,0 +1,7 @@
+begin;
+ALTER TABLE orders DROP COLUMN IF EXISTS line_items;
+ALTER TABLE orders DROP COLUMN IF EXISTS purchased_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS currency;
+ALTER TABLE orders DROP COLUMN IF EXISTS purchase_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS merchant_id;
+commit;
@@ -0,0 +1,7 @@
+begin;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS merchant_id varchar(255);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS purchase_amount numeric(14, 2);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency char(3);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS purchased_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS line_items jsonb;
+commit;
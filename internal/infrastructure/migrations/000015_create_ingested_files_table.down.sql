@@ -0,0 +1,3 @@
+begin;
+DROP TABLE IF EXISTS ingested_files;
+commit;
@@ -0,0 +1,12 @@
+begin;
+CREATE TABLE IF NOT EXISTS ingested_files (
+    checksum     char(64) PRIMARY KEY,
+    file_name    text NOT NULL,
+    status       varchar(32) NOT NULL,
+    lines        integer NOT NULL DEFAULT 0,
+    accepted     integer NOT NULL DEFAULT 0,
+    skipped      integer NOT NULL DEFAULT 0,
+    errors       JSONB NOT NULL DEFAULT '[]'::jsonb,
+    processed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+commit;
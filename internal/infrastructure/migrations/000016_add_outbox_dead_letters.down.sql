@@ -0,0 +1,6 @@
+begin;
+DROP TABLE IF EXISTS outbox_event_errors;
+DROP INDEX IF EXISTS idx_outbox_dead_letters;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS dead_lettered_at;
+UPDATE outbox_events SET status = 'failed' WHERE status IN ('dead_letter', 'discarded');
+commit;
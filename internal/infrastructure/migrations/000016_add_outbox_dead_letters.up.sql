@@ -0,0 +1,18 @@
+begin;
+UPDATE outbox_events SET status = 'dead_letter' WHERE status = 'failed';
+
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS dead_lettered_at TIMESTAMPTZ;
+UPDATE outbox_events SET dead_lettered_at = updated_at WHERE status = 'dead_letter';
+
+CREATE INDEX IF NOT EXISTS idx_outbox_dead_letters ON outbox_events (dead_lettered_at) WHERE status = 'dead_letter';
+
+CREATE TABLE IF NOT EXISTS outbox_event_errors (
+  id BIGSERIAL PRIMARY KEY,
+  outbox_event_id BIGINT NOT NULL REFERENCES outbox_events (id) ON DELETE CASCADE,
+  attempt INT NOT NULL,
+  error TEXT NOT NULL,
+  occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_event_errors_event ON outbox_event_errors (outbox_event_id);
+commit;
@@ -0,0 +1,4 @@
+begin;
+DROP INDEX IF EXISTS idx_wallet_events_correlation;
+ALTER TABLE wallet_events DROP COLUMN IF EXISTS metadata;
+commit;
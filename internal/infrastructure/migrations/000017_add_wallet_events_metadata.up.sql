@@ -0,0 +1,5 @@
+begin;
+ALTER TABLE wallet_events ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_wallet_events_correlation ON wallet_events ((metadata ->> 'correlation_id'));
+commit;
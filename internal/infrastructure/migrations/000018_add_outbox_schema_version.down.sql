@@ -0,0 +1,3 @@
+begin;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS schema_version;
+commit;
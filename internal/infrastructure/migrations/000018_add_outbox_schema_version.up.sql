@@ -0,0 +1,4 @@
+begin;
+-- Строки, записанные до появления реестра схем, хранят json.Marshal доменной структуры - это версия 1
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS schema_version INT NOT NULL DEFAULT 1;
+commit;
@@ -0,0 +1,3 @@
+begin;
+DROP TABLE IF EXISTS event_sink_checkpoints;
+commit;
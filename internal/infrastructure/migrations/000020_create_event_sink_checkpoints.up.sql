@@ -0,0 +1,7 @@
+begin;
+CREATE TABLE IF NOT EXISTS event_sink_checkpoints (
+  sink TEXT PRIMARY KEY,
+  position BIGINT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+commit;
@@ -0,0 +1,84 @@
+begin;
+CREATE TABLE IF NOT EXISTS order_events (
+  id BIGSERIAL PRIMARY KEY,
+  order_id UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+  version INT NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  event_data JSONB NOT NULL,
+  metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
+  occurred_at TIMESTAMPTZ NOT NULL,
+  UNIQUE (order_id, version)
+);
+
+-- История уже загруженных заказов восстанавливается из проекции и журнала переходов.
+-- Формат event_data совпадает с json.Marshal событий домена order.
+INSERT INTO order_events (order_id, version, event_type, event_data, occurred_at)
+SELECT o.id, 1, 'uploaded',
+       jsonb_build_object(
+         'OrderID', o.id,
+         'Number', o.number,
+         'CustomerID', o.user_id,
+         'Metadata', CASE
+           WHEN o.merchant_id IS NULL AND o.purchase_amount IS NULL AND o.currency IS NULL
+                AND o.purchased_at IS NULL AND o.line_items IS NULL THEN NULL
+           ELSE jsonb_build_object(
+             'MerchantID', COALESCE(o.merchant_id, ''),
+             'PurchaseAmount', COALESCE(o.purchase_amount, 0),
+             'Currency', COALESCE(TRIM(o.currency), ''),
+             'PurchasedAt', o.purchased_at,
+             'Items', (SELECT jsonb_agg(jsonb_build_object('Name', i ->> 'name', 'Price', (i ->> 'price')::float))
+                       FROM jsonb_array_elements(o.line_items) i))
+         END,
+         'OccurredAt', o.created_at),
+       o.created_at
+FROM orders o
+ON CONFLICT (order_id, version) DO NOTHING;
+
+INSERT INTO order_events (order_id, version, event_type, event_data, occurred_at)
+SELECT t.order_id,
+       1 + ROW_NUMBER() OVER (PARTITION BY t.order_id ORDER BY t.occurred_at, t.id),
+       CASE WHEN t.to_state = 'CANCELLED' THEN 'cancelled' ELSE 'status_changed' END,
+       CASE WHEN t.to_state = 'CANCELLED'
+         THEN jsonb_build_object('From', t.from_state, 'Cause', t.cause, 'OccurredAt', t.occurred_at)
+         ELSE jsonb_build_object('From', t.from_state, 'To', t.to_state, 'Cause', t.cause, 'OccurredAt', t.occurred_at)
+       END,
+       t.occurred_at
+FROM order_state_transitions t
+WHERE t.from_state <> ''
+ON CONFLICT (order_id, version) DO NOTHING;
+
+-- заказы, загруженные до появления журнала переходов, доводим до текущего статуса одним событием
+WITH last_transition AS (
+  SELECT DISTINCT ON (order_id) order_id, to_state
+  FROM order_state_transitions
+  ORDER BY order_id, occurred_at DESC, id DESC
+), streams AS (
+  SELECT order_id, MAX(version) AS version FROM order_events GROUP BY order_id
+)
+INSERT INTO order_events (order_id, version, event_type, event_data, occurred_at)
+SELECT o.id, s.version + 1,
+       CASE WHEN o.state = 'CANCELLED' THEN 'cancelled' ELSE 'status_changed' END,
+       jsonb_build_object('From', COALESCE(l.to_state, 'NEW'), 'To', o.state, 'Cause', 'migration', 'OccurredAt', o.created_at),
+       o.created_at
+FROM orders o
+JOIN streams s ON s.order_id = o.id
+LEFT JOIN last_transition l ON l.order_id = o.id
+WHERE COALESCE(l.to_state, 'NEW') <> o.state;
+
+WITH streams AS (
+  SELECT order_id, MAX(version) AS version FROM order_events GROUP BY order_id
+)
+INSERT INTO order_events (order_id, version, event_type, event_data, occurred_at)
+SELECT o.id, s.version + 1, 'credited',
+       jsonb_build_object('Amount', a.amount, 'OccurredAt', a.created),
+       a.created
+FROM orders o
+JOIN streams s ON s.order_id = o.id
+JOIN accruals a ON a.order_number = o.number
+WHERE o.state = 'PROCESSED' AND a.amount > 0;
+
+-- версия проекции теперь равна числу событий в истории заказа
+UPDATE orders o SET version = s.version
+FROM (SELECT order_id, MAX(version) AS version FROM order_events GROUP BY order_id) s
+WHERE s.order_id = o.id;
+commit;
@@ -0,0 +1,6 @@
+begin;
+DROP TABLE IF EXISTS wallet_snapshots;
+DROP INDEX IF EXISTS idx_wallet_events_position;
+ALTER TABLE wallet_events DROP COLUMN IF EXISTS position;
+DROP SEQUENCE IF EXISTS wallet_events_position_seq;
+commit;
@@ -0,0 +1,31 @@
+begin;
+CREATE SEQUENCE IF NOT EXISTS wallet_events_position_seq;
+
+ALTER TABLE wallet_events ADD COLUMN IF NOT EXISTS position BIGINT;
+
+-- существующим событиям номера выдаются в порядке записи
+UPDATE wallet_events e
+SET position = ordered.position
+FROM (
+    SELECT event_id, ROW_NUMBER() OVER (ORDER BY timestamp, aggregate_id, version) AS position
+    FROM wallet_events
+) ordered
+WHERE e.event_id = ordered.event_id;
+
+SELECT setval('wallet_events_position_seq', COALESCE((SELECT MAX(position) FROM wallet_events), 0) + 1, false);
+
+ALTER TABLE wallet_events
+    ALTER COLUMN position SET DEFAULT nextval('wallet_events_position_seq'),
+    ALTER COLUMN position SET NOT NULL;
+
+ALTER SEQUENCE wallet_events_position_seq OWNED BY wallet_events.position;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallet_events_position ON wallet_events (position);
+
+CREATE TABLE IF NOT EXISTS wallet_snapshots (
+    aggregate_id TEXT PRIMARY KEY,
+    version      INTEGER NOT NULL,
+    state        JSONB NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+commit;
@@ -0,0 +1,5 @@
+begin;
+ALTER TABLE event_sink_checkpoints DROP COLUMN IF EXISTS xact_id;
+DROP INDEX IF EXISTS idx_outbox_events_xact_id;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS xact_id;
+commit;
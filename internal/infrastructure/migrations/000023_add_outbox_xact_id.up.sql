@@ -0,0 +1,13 @@
+begin;
+-- транзакция, записавшая событие. Выгрузка читает только события транзакций, которые уже завершились,
+-- поэтому событие долгой транзакции с меньшим номером не будет пропущено.
+-- Существующие события получают номер этой миграции.
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS xact_id XID8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_xact_id ON outbox_events (xact_id, id);
+
+-- приёмники продолжают с того же события: все прежние события записаны одной транзакцией миграции
+ALTER TABLE event_sink_checkpoints ADD COLUMN IF NOT EXISTS xact_id BIGINT NOT NULL DEFAULT 0;
+
+UPDATE event_sink_checkpoints SET xact_id = pg_current_xact_id()::text::bigint;
+commit;
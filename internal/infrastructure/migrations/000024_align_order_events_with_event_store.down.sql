@@ -0,0 +1,8 @@
+begin;
+DROP INDEX IF EXISTS idx_order_events_event_id;
+ALTER TABLE order_events DROP COLUMN IF EXISTS event_id;
+
+ALTER TABLE order_events RENAME COLUMN timestamp TO occurred_at;
+ALTER TABLE order_events RENAME COLUMN aggregate_id TO order_id;
+ALTER TABLE order_events RENAME COLUMN position TO id;
+commit;
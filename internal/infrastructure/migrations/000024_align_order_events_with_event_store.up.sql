@@ -0,0 +1,11 @@
+begin;
+-- история заказов хранится в общем формате хранилища событий, как и история кошельков
+ALTER TABLE order_events RENAME COLUMN id TO position;
+ALTER TABLE order_events RENAME COLUMN order_id TO aggregate_id;
+ALTER TABLE order_events RENAME COLUMN occurred_at TO timestamp;
+
+ALTER TABLE order_events ADD COLUMN IF NOT EXISTS event_id TEXT NOT NULL DEFAULT gen_random_uuid()::text;
+ALTER TABLE order_events ALTER COLUMN event_id DROP DEFAULT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_events_event_id ON order_events (event_id);
+commit;
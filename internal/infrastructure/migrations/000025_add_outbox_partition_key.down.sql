@@ -0,0 +1,4 @@
+begin;
+DROP INDEX IF EXISTS idx_outbox_events_partition_key;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS partition_key;
+commit;
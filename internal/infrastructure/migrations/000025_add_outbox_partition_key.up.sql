@@ -0,0 +1,7 @@
+begin;
+-- ключ упорядочивания события. Диспетчер не берёт событие, пока более раннее событие того же ключа ждёт повтора.
+-- Ключ события вычисляется в коде, поэтому у событий, записанных до миграции, он пустой и их порядок не удерживается.
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS partition_key TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_partition_key ON outbox_events (partition_key, id) WHERE status IN ('pending', 'processing');
+commit;
@@ -0,0 +1,7 @@
+begin;
+DROP INDEX IF EXISTS idx_order_events_xact_id;
+ALTER TABLE order_events DROP COLUMN IF EXISTS xact_id;
+
+DROP INDEX IF EXISTS idx_wallet_events_xact_id;
+ALTER TABLE wallet_events DROP COLUMN IF EXISTS xact_id;
+commit;
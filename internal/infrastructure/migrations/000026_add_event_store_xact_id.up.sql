@@ -0,0 +1,9 @@
+begin;
+-- транзакция, записавшая событие. ReadAll читает события только завершившихся транзакций, поэтому событие
+-- долгой транзакции с меньшим номером не будет пропущено. Существующие события получают номер этой миграции.
+ALTER TABLE wallet_events ADD COLUMN IF NOT EXISTS xact_id XID8 NOT NULL DEFAULT pg_current_xact_id();
+CREATE INDEX IF NOT EXISTS idx_wallet_events_xact_id ON wallet_events (xact_id, position);
+
+ALTER TABLE order_events ADD COLUMN IF NOT EXISTS xact_id XID8 NOT NULL DEFAULT pg_current_xact_id();
+CREATE INDEX IF NOT EXISTS idx_order_events_xact_id ON order_events (xact_id, position);
+commit;
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"github.com/Masterminds/squirrel"
 	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/transaction"
 	"time"
 )
 
@@ -21,11 +22,6 @@ func NewPostgresRepository(db *sql.DB) *PostgresRepository {
 }
 
 func (p *PostgresRepository) Save(ctx context.Context, accrual *accrual.Accrual) error {
-	exists, _ := p.Get(ctx, accrual.OrderNumber)
-	if exists != nil {
-		return nil
-	}
-
 	query, _, err := p.builder.Insert("accruals").
 		Columns("order_number", "state", "amount", "created").
 		Values("?", "?", "?", "?").ToSql()
@@ -33,7 +29,10 @@ func (p *PostgresRepository) Save(ctx context.Context, accrual *accrual.Accrual)
 		return err
 	}
 
-	_, err = p.db.ExecContext(ctx, query, accrual.OrderNumber, accrual.State, accrual.Amount, time.Now())
+	// система начислений присылает статусы по мере обработки, храним последний
+	query += " ON CONFLICT (order_number) DO UPDATE SET state = EXCLUDED.state, amount = EXCLUDED.amount, created = EXCLUDED.created"
+
+	_, err = transaction.ExecutorFrom(ctx, p.db).ExecContext(ctx, query, accrual.OrderNumber, accrual.State, accrual.Amount, time.Now())
 	if err != nil {
 		return err
 	}
@@ -90,3 +89,41 @@ func (p *PostgresRepository) GetForOrders(ctx context.Context, orderNumbers []st
 
 	return accruals, nil
 }
+
+func (p *PostgresRepository) CustomerStats(ctx context.Context, customerID string) (*accrual.Stats, error) {
+	query, _, err := p.builder.Select("COUNT(*)", "COALESCE(AVG(a.amount), 0)", "COALESCE(MAX(a.amount), 0)").
+		From("accruals a").
+		Join("orders o ON o.number = a.order_number").
+		Where("o.user_id = ?").
+		Where("a.state = ?").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &accrual.Stats{}
+	err = p.db.QueryRowContext(ctx, query, customerID, accrual.Processed).Scan(&stats.Count, &stats.Average, &stats.Max)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (p *PostgresRepository) ProviderStats(ctx context.Context) (*accrual.Stats, error) {
+	query, _, err := p.builder.Select("COUNT(*)", "COALESCE(AVG(amount), 0)", "COALESCE(MAX(amount), 0)").
+		From("accruals").
+		Where("state = ?").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &accrual.Stats{}
+	err = p.db.QueryRowContext(ctx, query, accrual.Processed).Scan(&stats.Count, &stats.Average, &stats.Max)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,171 @@
+package accrual
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/transaction"
+)
+
+var reviewColumns = []string{
+	"id", "order_number", "customer_id", "provider_state", "amount", "reasons",
+	"status", "created_at", "decided_at", "decided_by", "comment",
+}
+
+type ReviewPostgresRepository struct {
+	db      *sql.DB
+	builder squirrel.StatementBuilderType
+}
+
+func NewReviewPostgresRepository(db *sql.DB) *ReviewPostgresRepository {
+	return &ReviewPostgresRepository{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *ReviewPostgresRepository) Save(ctx context.Context, review *accrual.Review) error {
+	reasons, err := json.Marshal(review.Reasons)
+	if err != nil {
+		return err
+	}
+
+	query, _, err := r.builder.Insert("accrual_reviews").
+		Columns(reviewColumns...).
+		Values("?", "?", "?", "?", "?", "?", "?", "?", "?", "?", "?").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	query += ` ON CONFLICT (id) DO UPDATE SET
+		status = EXCLUDED.status,
+		decided_at = EXCLUDED.decided_at,
+		decided_by = EXCLUDED.decided_by,
+		comment = EXCLUDED.comment`
+
+	_, err = transaction.ExecutorFrom(ctx, r.db).ExecContext(ctx, query,
+		review.ID, review.OrderNumber, review.CustomerID, review.ProviderState, review.Amount, reasons,
+		review.Status, review.CreatedAt, review.DecidedAt, review.DecidedBy, review.Comment)
+
+	return err
+}
+
+func (r *ReviewPostgresRepository) Decide(ctx context.Context, review *accrual.Review) error {
+	query, args, err := r.builder.Update("accrual_reviews").
+		Set("status", review.Status).
+		Set("decided_at", review.DecidedAt).
+		Set("decided_by", review.DecidedBy).
+		Set("comment", review.Comment).
+		Where(squirrel.Eq{"id": review.ID, "status": accrual.ReviewPending}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	// решение принимается один раз: параллельный запрос ждёт блокировку строки и уже не застаёт её в PENDING
+	result, err := transaction.ExecutorFrom(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return accrual.ErrReviewAlreadyDecided
+	}
+
+	return nil
+}
+
+func (r *ReviewPostgresRepository) Get(ctx context.Context, id string) (*accrual.Review, error) {
+	query, _, err := r.builder.Select(reviewColumns...).
+		From("accrual_reviews").
+		Where("id = ?").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := scanReview(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, accrual.ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func (r *ReviewPostgresRepository) List(ctx context.Context, statuses []accrual.ReviewStatus, limit uint64, offset uint64) ([]*accrual.Review, error) {
+	q := r.builder.Select(reviewColumns...).
+		From("accrual_reviews").
+		OrderBy("created_at ASC").
+		Limit(limit).
+		Offset(offset)
+
+	if len(statuses) > 0 {
+		st := make([]string, len(statuses))
+		for i, s := range statuses {
+			st[i] = string(s)
+		}
+		q = q.Where(squirrel.Eq{"status": st})
+	}
+
+	rows, err := q.RunWith(r.db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	result := make([]*accrual.Review, 0)
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, review)
+	}
+
+	return result, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReview(row rowScanner) (*accrual.Review, error) {
+	review := &accrual.Review{}
+	var (
+		reasons   []byte
+		decidedAt sql.NullTime
+	)
+
+	err := row.Scan(&review.ID, &review.OrderNumber, &review.CustomerID, &review.ProviderState, &review.Amount, &reasons,
+		&review.Status, &review.CreatedAt, &decidedAt, &review.DecidedBy, &review.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	if decidedAt.Valid {
+		review.DecidedAt = &decidedAt.Time
+	}
+
+	err = json.Unmarshal(reasons, &review.Reasons)
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
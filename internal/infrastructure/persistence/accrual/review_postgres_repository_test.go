@@ -0,0 +1,45 @@
+package accrual
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+)
+
+func TestReviewPostgresRepository_Decide(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected int64
+		err      error
+	}{
+		{name: "pending review", affected: 1},
+		{name: "already decided", affected: 0, err: accrual.ErrReviewAlreadyDecided},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			decidedAt := time.Now()
+			review := &accrual.Review{ID: "r1", Status: accrual.ReviewApproved, DecidedAt: &decidedAt, DecidedBy: "admin"}
+
+			mock.ExpectExec("^UPDATE accrual_reviews SET status = \\$1, decided_at = \\$2, decided_by = \\$3, comment = \\$4 WHERE id = \\$5 AND status = \\$6$").
+				WithArgs(accrual.ReviewApproved, &decidedAt, "admin", "", "r1", accrual.ReviewPending).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			err = NewReviewPostgresRepository(db).Decide(context.Background(), review)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package eventstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// Codec переводит события агрегата в хранимый вид и обратно
+type Codec[E any] interface {
+	Encode(event E) (eventType string, data []byte, err error)
+	Decode(eventType string, data []byte) (E, error)
+}
+
+// JSONCodec хранит события в JSON. Тип события берётся из самого события,
+// а для чтения каждый тип регистрируется с фабрикой пустого события.
+type JSONCodec[E any] struct {
+	typeOf    func(E) string
+	factories map[string]func() E
+}
+
+func NewJSONCodec[E any](typeOf func(E) string) *JSONCodec[E] {
+	return &JSONCodec[E]{
+		typeOf:    typeOf,
+		factories: make(map[string]func() E),
+	}
+}
+
+// Register добавляет тип события. Фабрика должна возвращать указатель.
+func (c *JSONCodec[E]) Register(eventType string, factory func() E) *JSONCodec[E] {
+	c.factories[eventType] = factory
+	return c
+}
+
+func (c *JSONCodec[E]) Encode(event E) (string, []byte, error) {
+	eventType := c.typeOf(event)
+	if _, ok := c.factories[eventType]; !ok {
+		return "", nil, fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return eventType, data, nil
+}
+
+func (c *JSONCodec[E]) Decode(eventType string, data []byte) (E, error) {
+	factory, ok := c.factories[eventType]
+	if !ok {
+		var zero E
+		return zero, fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+	}
+
+	event := factory()
+	if err := json.Unmarshal(data, event); err != nil {
+		var zero E
+		return zero, err
+	}
+
+	return event, nil
+}
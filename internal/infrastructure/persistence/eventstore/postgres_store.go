@@ -0,0 +1,345 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/sviatilnik/gophermart/internal/domain/events"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/transaction"
+)
+
+var (
+	ErrVersionConflict   = errors.New("version conflict")
+	ErrSnapshotsDisabled = errors.New("snapshots are disabled")
+)
+
+var recordColumns = []string{
+	"position", "event_id", "aggregate_id", "version", "event_type", "event_data", "metadata", "timestamp",
+}
+
+// streamColumns - recordColumns вместе с транзакцией, записавшей событие
+var streamColumns = append([]string{"xact_id::text::bigint"}, recordColumns...)
+
+// Tables - таблицы хранилища одного типа агрегата.
+// Таблица событий: position, event_id, aggregate_id, event_type, event_data, version, timestamp, metadata, xact_id
+// с уникальностью (aggregate_id, version). Таблица снимков: aggregate_id, version, state, created_at.
+type Tables struct {
+	Events string
+	// Snapshots - пустая строка отключает снимки
+	Snapshots string
+}
+
+// Record - сохранённое событие агрегата
+type Record[E any] struct {
+	// Position - сквозной номер события среди всех агрегатов таблицы
+	Position int64
+	// Xact - транзакция, записавшая событие; заполняется только при чтении через ReadAll
+	Xact        int64
+	EventID     string
+	AggregateID string
+	Version     int
+	Type        string
+	Event       E
+	Metadata    events.Trace
+	Timestamp   time.Time
+}
+
+// StreamPosition - место в общем потоке событий: события упорядочены по транзакции, затем по сквозному номеру.
+// Номера выдаются при вставке, поэтому порядок одних номеров не совпадает с порядком фиксации.
+type StreamPosition struct {
+	Xact     int64
+	Position int64
+}
+
+// StreamPosition - позиция, с которой ReadAll продолжит чтение после этого события
+func (r Record[E]) StreamPosition() StreamPosition {
+	return StreamPosition{Xact: r.Xact, Position: r.Position}
+}
+
+// Snapshot - состояние агрегата на момент версии Version в виде, понятном самому агрегату
+type Snapshot struct {
+	Version int
+	State   []byte
+}
+
+// Stream - история агрегата: последний снимок, если он есть, и события после него
+type Stream[E any] struct {
+	Snapshot *Snapshot
+	Events   []Record[E]
+	// Version - версия агрегата с учётом снимка; 0, если агрегата нет
+	Version int
+}
+
+type PostgresStore[E any] struct {
+	db      *sql.DB
+	tables  Tables
+	codec   Codec[E]
+	builder squirrel.StatementBuilderType
+}
+
+func NewPostgresStore[E any](db *sql.DB, tables Tables, codec Codec[E]) *PostgresStore[E] {
+	return &PostgresStore[E]{
+		db:      db,
+		tables:  tables,
+		codec:   codec,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Append дописывает события агрегата, если его текущая версия равна expected.
+// Работает в транзакции из контекста, если она есть.
+func (s *PostgresStore[E]) Append(ctx context.Context, aggregateID string, expected int, evts []E) ([]Record[E], error) {
+	if len(evts) == 0 {
+		return nil, nil
+	}
+
+	tx, err := transaction.Begin(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := s.version(ctx, tx.Tx, aggregateID)
+	if err != nil {
+		return nil, err
+	}
+	if current != expected {
+		return nil, ErrVersionConflict
+	}
+
+	trace := events.TraceFrom(ctx)
+	metadata, err := json.Marshal(trace)
+	if err != nil {
+		return nil, err
+	}
+
+	// параллельная запись той же версии не вставится, это тоже конфликт версий
+	query, _, err := s.builder.Insert(s.tables.Events).
+		Columns("event_id", "aggregate_id", "event_type", "event_data", "version", "timestamp", "metadata").
+		Values("?", "?", "?", "?", "?", "?", "?").
+		Suffix("ON CONFLICT (aggregate_id, version) DO NOTHING RETURNING position").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	records := make([]Record[E], 0, len(evts))
+	for i, event := range evts {
+		eventType, data, err := s.codec.Encode(event)
+		if err != nil {
+			return nil, err
+		}
+
+		record := Record[E]{
+			EventID:     uuid.NewString(),
+			AggregateID: aggregateID,
+			Version:     expected + i + 1,
+			Type:        eventType,
+			Event:       event,
+			Metadata:    trace,
+			Timestamp:   now,
+		}
+
+		err = tx.QueryRowContext(ctx, query,
+			record.EventID, aggregateID, eventType, data, record.Version, now, metadata,
+		).Scan(&record.Position)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVersionConflict
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (s *PostgresStore[E]) version(ctx context.Context, tx *sql.Tx, aggregateID string) (int, error) {
+	query, args, err := s.builder.Select("COALESCE(MAX(version), 0)").
+		From(s.tables.Events).
+		Where(squirrel.Eq{"aggregate_id": aggregateID}).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&version)
+
+	return version, err
+}
+
+// Load возвращает последний снимок агрегата и события после него.
+// Для несуществующего агрегата возвращается пустая история.
+func (s *PostgresStore[E]) Load(ctx context.Context, aggregateID string) (*Stream[E], error) {
+	stream := &Stream[E]{Events: make([]Record[E], 0)}
+
+	if s.tables.Snapshots != "" {
+		snapshot, err := s.loadSnapshot(ctx, aggregateID)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			stream.Snapshot = snapshot
+			stream.Version = snapshot.Version
+		}
+	}
+
+	query, args, err := s.builder.Select(recordColumns...).
+		From(s.tables.Events).
+		Where(squirrel.Eq{"aggregate_id": aggregateID}).
+		Where(squirrel.Gt{"version": stream.Version}).
+		OrderBy("version ASC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.query(ctx, false, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	stream.Events = records
+	if len(records) > 0 {
+		stream.Version = records[len(records)-1].Version
+	}
+
+	return stream, nil
+}
+
+func (s *PostgresStore[E]) loadSnapshot(ctx context.Context, aggregateID string) (*Snapshot, error) {
+	query, args, err := s.builder.Select("version", "state").
+		From(s.tables.Snapshots).
+		Where(squirrel.Eq{"aggregate_id": aggregateID}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	err = transaction.ExecutorFrom(ctx, s.db).QueryRowContext(ctx, query, args...).Scan(&snapshot.Version, &snapshot.State)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// SaveSnapshot сохраняет снимок агрегата. Более старый снимок не затирает более новый.
+func (s *PostgresStore[E]) SaveSnapshot(ctx context.Context, aggregateID string, snapshot Snapshot) error {
+	if s.tables.Snapshots == "" {
+		return ErrSnapshotsDisabled
+	}
+
+	query, args, err := s.builder.Insert(s.tables.Snapshots).
+		Columns("aggregate_id", "version", "state", "created_at").
+		Values(aggregateID, snapshot.Version, snapshot.State, time.Now()).
+		Suffix("ON CONFLICT (aggregate_id) DO UPDATE SET version = EXCLUDED.version, state = EXCLUDED.state, created_at = EXCLUDED.created_at").
+		Suffix("WHERE " + s.tables.Snapshots + ".version < EXCLUDED.version").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.ExecutorFrom(ctx, s.db).ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// ReadAll читает события всех агрегатов после позиции after в порядке фиксации транзакций.
+// Транзакции младше xmin текущего снимка завершены, новых событий с такими номерами транзакций не появится.
+func (s *PostgresStore[E]) ReadAll(ctx context.Context, after StreamPosition, limit int) ([]Record[E], error) {
+	query, args, err := s.builder.Select(streamColumns...).
+		From(s.tables.Events).
+		Where("(xact_id, position) > (?::bigint::text::xid8, ?)", after.Xact, after.Position).
+		Where("xact_id < pg_snapshot_xmin(pg_current_snapshot())").
+		OrderBy("xact_id ASC", "position ASC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.query(ctx, true, query, args...)
+}
+
+func (s *PostgresStore[E]) Exists(ctx context.Context, aggregateID string) (bool, error) {
+	query, args, err := s.builder.Select("1").
+		From(s.tables.Events).
+		Where(squirrel.Eq{"aggregate_id": aggregateID}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	var one int
+	err = transaction.ExecutorFrom(ctx, s.db).QueryRowContext(ctx, query, args...).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// query читает события; withXact - первой колонкой выбрана транзакция события (streamColumns)
+func (s *PostgresStore[E]) query(ctx context.Context, withXact bool, query string, args ...any) ([]Record[E], error) {
+	rows, err := transaction.ExecutorFrom(ctx, s.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]Record[E], 0)
+	for rows.Next() {
+		var (
+			record   Record[E]
+			data     []byte
+			metadata []byte
+		)
+
+		dest := []any{&record.Position, &record.EventID, &record.AggregateID, &record.Version,
+			&record.Type, &data, &metadata, &record.Timestamp}
+		if withXact {
+			dest = append([]any{&record.Xact}, dest...)
+		}
+
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		record.Event, err = s.codec.Decode(record.Type, data)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(metadata) > 0 {
+			if err = json.Unmarshal(metadata, &record.Metadata); err != nil {
+				return nil, err
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,164 @@
+package eventstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent interface {
+	GetType() string
+}
+
+type added struct {
+	Amount int
+}
+
+func (a *added) GetType() string {
+	return "added"
+}
+
+func newTestStore(t *testing.T, tables Tables) (*PostgresStore[testEvent], sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	codec := NewJSONCodec(testEvent.GetType).
+		Register("added", func() testEvent { return &added{} })
+
+	return NewPostgresStore[testEvent](db, tables, codec), mock
+}
+
+func TestPostgresStore_Append(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  int
+		mockSetup func(mock sqlmock.Sqlmock)
+		positions []int64
+		err       error
+	}{
+		{
+			name:     "success",
+			expected: 2,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("^SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM events").
+					WithArgs("a1").
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+				mock.ExpectQuery("^INSERT INTO events (.+) ON CONFLICT \\(aggregate_id, version\\) DO NOTHING RETURNING position$").
+					WithArgs(sqlmock.AnyArg(), "a1", "added", []byte(`{"Amount":1}`), 3, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(10))
+				mock.ExpectQuery("^INSERT INTO events").
+					WithArgs(sqlmock.AnyArg(), "a1", "added", []byte(`{"Amount":2}`), 4, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(12))
+				mock.ExpectCommit()
+			},
+			positions: []int64{10, 12},
+		},
+		{
+			name:     "stale version",
+			expected: 1,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("^SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM events").
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+				mock.ExpectRollback()
+			},
+			err: ErrVersionConflict,
+		},
+		{
+			name:     "concurrent append",
+			expected: 2,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("^SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM events").
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+				mock.ExpectQuery("^INSERT INTO events").
+					WillReturnRows(sqlmock.NewRows([]string{"position"}))
+				mock.ExpectRollback()
+			},
+			err: ErrVersionConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, mock := newTestStore(t, Tables{Events: "events"})
+			tt.mockSetup(mock)
+
+			records, err := store.Append(context.Background(), "a1", tt.expected, []testEvent{&added{Amount: 1}, &added{Amount: 2}})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, records, len(tt.positions))
+				for i, record := range records {
+					assert.Equal(t, tt.positions[i], record.Position)
+					assert.Equal(t, tt.expected+i+1, record.Version)
+					assert.NotEmpty(t, record.EventID)
+				}
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresStore_Load(t *testing.T) {
+	store, mock := newTestStore(t, Tables{Events: "events", Snapshots: "snapshots"})
+
+	mock.ExpectQuery("^SELECT version, state FROM snapshots WHERE aggregate_id = \\$1$").
+		WithArgs("a1").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "state"}).AddRow(50, []byte(`{"sum":100}`)))
+	mock.ExpectQuery("^SELECT (.+) FROM events WHERE aggregate_id = \\$1 AND version > \\$2 ORDER BY version ASC$").
+		WithArgs("a1", 50).
+		WillReturnRows(sqlmock.NewRows(recordColumns).
+			AddRow(70, "e51", "a1", 51, "added", []byte(`{"Amount":5}`), []byte(`{"actor":"system"}`), time.Now()))
+
+	stream, err := store.Load(context.Background(), "a1")
+	require.NoError(t, err)
+
+	require.NotNil(t, stream.Snapshot)
+	assert.Equal(t, 50, stream.Snapshot.Version)
+	assert.Equal(t, 51, stream.Version)
+	require.Len(t, stream.Events, 1)
+	assert.Equal(t, &added{Amount: 5}, stream.Events[0].Event)
+	assert.Equal(t, int64(70), stream.Events[0].Position)
+	assert.Equal(t, "system", stream.Events[0].Metadata.Actor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStore_ReadAll(t *testing.T) {
+	store, mock := newTestStore(t, Tables{Events: "events"})
+
+	// событие с меньшим номером, записанное более поздней транзакцией, идёт после
+	mock.ExpectQuery("^SELECT xact_id::text::bigint, (.+) FROM events "+
+		"WHERE \\(xact_id, position\\) > \\(\\$1::bigint::text::xid8, \\$2\\) "+
+		"AND xact_id < pg_snapshot_xmin\\(pg_current_snapshot\\(\\)\\) "+
+		"ORDER BY xact_id ASC, position ASC LIMIT 10$").
+		WithArgs(int64(100), int64(5)).
+		WillReturnRows(sqlmock.NewRows(streamColumns).
+			AddRow(100, 7, "e7", "a1", 1, "added", []byte(`{"Amount":1}`), []byte(`{}`), time.Now()).
+			AddRow(101, 6, "e6", "a2", 1, "added", []byte(`{"Amount":2}`), []byte(`{}`), time.Now()))
+
+	records, err := store.ReadAll(context.Background(), StreamPosition{Xact: 100, Position: 5}, 10)
+	require.NoError(t, err)
+
+	require.Len(t, records, 2)
+	assert.Equal(t, &added{Amount: 2}, records[1].Event)
+	assert.Equal(t, StreamPosition{Xact: 101, Position: 6}, records[1].StreamPosition())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJSONCodec_UnknownType(t *testing.T) {
+	codec := NewJSONCodec(testEvent.GetType)
+
+	_, _, err := codec.Encode(&added{})
+	assert.ErrorIs(t, err, ErrUnknownEventType)
+
+	_, err = codec.Decode("added", []byte(`{}`))
+	assert.ErrorIs(t, err, ErrUnknownEventType)
+}
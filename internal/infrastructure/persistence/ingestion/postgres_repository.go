@@ -0,0 +1,59 @@
+package ingestion
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/sviatilnik/gophermart/internal/domain/ingestion"
+)
+
+type PostgresRepository struct {
+	db      *sql.DB
+	builder squirrel.StatementBuilderType
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *PostgresRepository) Exists(ctx context.Context, checksum string) (bool, error) {
+	query, args, err := r.builder.Select("count(*)").
+		From("ingested_files").
+		Where(squirrel.Eq{"checksum": checksum}).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	var count int
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *PostgresRepository) Save(ctx context.Context, file *ingestion.File) error {
+	errs, err := json.Marshal(file.Errors)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := r.builder.Insert("ingested_files").
+		Columns("checksum", "file_name", "status", "lines", "accepted", "skipped", "errors", "processed_at").
+		Values(file.Checksum, file.Name, string(file.Status), file.Lines, file.Accepted, file.Skipped, errs, file.ProcessedAt).
+		Suffix("ON CONFLICT (checksum) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+	return err
+}
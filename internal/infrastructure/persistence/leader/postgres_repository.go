@@ -0,0 +1,86 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/sviatilnik/gophermart/internal/domain/leader"
+)
+
+type PostgresRepository struct {
+	db      *sql.DB
+	builder squirrel.StatementBuilderType
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *PostgresRepository) TryAcquire(ctx context.Context, name string, holderID string, ttl time.Duration) (bool, error) {
+	// время берём из базы, чтобы расхождение часов между репликами не влияло на аренду
+	query := `
+		INSERT INTO leader_leases (name, holder_id, acquired_at, renewed_at, expires_at)
+		VALUES ($1, $2, NOW(), NOW(), NOW() + $3 * INTERVAL '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET
+			holder_id = EXCLUDED.holder_id,
+			acquired_at = CASE
+				WHEN leader_leases.holder_id = EXCLUDED.holder_id THEN leader_leases.acquired_at
+				ELSE EXCLUDED.acquired_at
+			END,
+			renewed_at = EXCLUDED.renewed_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE leader_leases.holder_id = EXCLUDED.holder_id OR leader_leases.expires_at < NOW()
+		RETURNING holder_id`
+
+	var holder string
+	err := r.db.QueryRowContext(ctx, query, name, holderID, ttl.Milliseconds()).Scan(&holder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return holder == holderID, nil
+}
+
+func (r *PostgresRepository) Release(ctx context.Context, name string, holderID string) error {
+	query, _, err := r.builder.Delete("leader_leases").
+		Where("name = ?").
+		Where("holder_id = ?").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query, name, holderID)
+	return err
+}
+
+func (r *PostgresRepository) Get(ctx context.Context, name string) (*leader.Lease, error) {
+	query, _, err := r.builder.Select("name", "holder_id", "acquired_at", "renewed_at", "expires_at").
+		From("leader_leases").
+		Where("name = ?").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	lease := &leader.Lease{}
+	err = r.db.QueryRowContext(ctx, query, name).
+		Scan(&lease.Name, &lease.HolderID, &lease.AcquiredAt, &lease.RenewedAt, &lease.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, leader.ErrLeaseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return lease, nil
+}
@@ -3,75 +3,362 @@ package order
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+
 	"github.com/Masterminds/squirrel"
 	"github.com/sviatilnik/gophermart/internal/domain/order"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/eventstore"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/pagination"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/transaction"
 )
 
+var orderColumns = []string{
+	"id", "number", "user_id", "created_at", "state", "version",
+	"merchant_id", "purchase_amount", "currency", "purchased_at", "line_items",
+}
+
+// PostgresRepository хранит историю заказа в хранилище событий, а текущее состояние - в проекции orders
 type PostgresRepository struct {
-	db      *sql.DB
-	builder squirrel.StatementBuilderType
+	db         *sql.DB
+	store      *eventstore.PostgresStore[order.Event]
+	transactor *transaction.PostgresTransactor
+	builder    squirrel.StatementBuilderType
 }
 
 func NewOrderPostgresRepository(db *sql.DB) *PostgresRepository {
 	return &PostgresRepository{
-		db:      db,
-		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		db:         db,
+		store:      eventstore.NewPostgresStore(db, eventstore.Tables{Events: "order_events"}, newCodec()),
+		transactor: transaction.NewPostgresTransactor(db),
+		builder:    squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
 	}
 }
 
+// newCodec - кодек событий истории заказа
+func newCodec() *eventstore.JSONCodec[order.Event] {
+	return eventstore.NewJSONCodec(order.Event.GetType).
+		Register("uploaded", func() order.Event { return &order.OrderUploaded{} }).
+		Register("status_changed", func() order.Event { return &order.OrderStatusChanged{} }).
+		Register("cancelled", func() order.Event { return &order.OrderCancelled{} }).
+		Register("credited", func() order.Event { return &order.OrderCredited{} })
+}
+
+// Get восстанавливает заказ по его истории событий
 func (r *PostgresRepository) Get(ctx context.Context, number order.Number) (*order.Order, error) {
-	query, _, err := r.builder.Select("id", "number", "user_id", "created_at", "state").
+	history, err := r.GetHistory(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return order.FromHistory(history), nil
+}
+
+// GetHistory возвращает историю заказа по порядку версий; sql.ErrNoRows, если заказа нет
+func (r *PostgresRepository) GetHistory(ctx context.Context, number order.Number) ([]order.Event, error) {
+	query, args, err := r.builder.Select("id").
 		From("orders").
-		Where("number = ?").
+		Where(squirrel.Eq{"number": string(number)}).
 		ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	ordr := &order.Order{}
-	err = r.db.QueryRowContext(ctx, query, string(number)).
-		Scan(&ordr.ID, &ordr.Number, &ordr.CustomerID, &ordr.CreatedAt, &ordr.State)
+	var orderID string
+	err = transaction.ExecutorFrom(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&orderID)
 	if err != nil {
 		return nil, err
 	}
 
-	return ordr, nil
+	stream, err := r.store.Load(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stream.Events) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	history := make([]order.Event, len(stream.Events))
+	for i, record := range stream.Events {
+		history[i] = record.Event
+	}
+
+	return history, nil
+}
+
+// Save дописывает новые события в историю заказа и обновляет проекцию в orders.
+// Конфликт версий обнаруживается по версии проекции. Владелец заказа никогда не меняется.
+func (r *PostgresRepository) Save(ctx context.Context, ordr *order.Order) error {
+	changes := ordr.Changes()
+	if len(changes) == 0 {
+		return nil
+	}
+
+	err := r.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		expected := ordr.Version - len(changes)
+
+		var err error
+		if expected == 0 {
+			err = r.insert(ctx, ordr)
+		} else {
+			err = r.update(ctx, ordr, expected)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = r.appendEvents(ctx, []*order.Order{ordr})
+		if err != nil {
+			return err
+		}
+
+		return r.saveTransitions(ctx, ordr.Transitions())
+	})
+	if err != nil {
+		return err
+	}
+
+	ordr.ClearChanges()
+
+	return nil
+}
+
+func (r *PostgresRepository) SaveBatch(ctx context.Context, orders []*order.Order) (map[order.Number]string, error) {
+	existing := make(map[order.Number]string)
+	if len(orders) == 0 {
+		return existing, nil
+	}
+
+	q := r.builder.Insert("orders").Columns(orderColumns...)
+	for _, ordr := range orders {
+		values, err := insertValues(ordr)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Values(values...)
+	}
+
+	query, args, err := q.Suffix("ON CONFLICT (number) DO NOTHING RETURNING number, user_id").ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []*order.Order
+	err = r.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		inserted, err := r.queryNumbers(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		saved = make([]*order.Order, 0, len(inserted))
+		transitions := make([]*order.Transition, 0, len(inserted))
+		conflicts := make([]string, 0)
+		for _, ordr := range orders {
+			if _, ok := inserted[ordr.Number]; ok {
+				saved = append(saved, ordr)
+				transitions = append(transitions, ordr.Transitions()...)
+			} else {
+				conflicts = append(conflicts, string(ordr.Number))
+			}
+		}
+
+		err = r.appendEvents(ctx, saved)
+		if err != nil {
+			return err
+		}
+
+		err = r.saveTransitions(ctx, transitions)
+		if err != nil {
+			return err
+		}
+
+		if len(conflicts) == 0 {
+			return nil
+		}
+
+		query, args, err := r.builder.Select("number", "user_id").
+			From("orders").
+			Where(squirrel.Eq{"number": conflicts}).
+			ToSql()
+		if err != nil {
+			return err
+		}
+
+		existing, err = r.queryNumbers(ctx, query, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ordr := range saved {
+		ordr.ClearChanges()
+	}
+
+	return existing, nil
 }
 
-func (r *PostgresRepository) Save(ctx context.Context, order *order.Order) error {
-	query, _, err := r.builder.Insert("orders").
-		Columns("id", "number", "user_id", "created_at", "state").
-		Values("?", "?", "?", "?", "?").
+// queryNumbers выполняет запрос, возвращающий пары (номер заказа, владелец)
+func (r *PostgresRepository) queryNumbers(ctx context.Context, query string, args ...interface{}) (map[order.Number]string, error) {
+	rows, err := transaction.ExecutorFrom(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[order.Number]string)
+	for rows.Next() {
+		var number, owner string
+		err = rows.Scan(&number, &owner)
+		if err != nil {
+			return nil, err
+		}
+		result[order.Number(number)] = owner
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgresRepository) insert(ctx context.Context, ordr *order.Order) error {
+	values, err := insertValues(ordr)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := r.builder.Insert("orders").
+		Columns(orderColumns...).
+		Values(values...).
+		Suffix("ON CONFLICT (number) DO NOTHING").
 		ToSql()
+	if err != nil {
+		return err
+	}
 
+	result, err := transaction.ExecutorFrom(ctx, r.db).ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
 
-	query = query + " ON CONFLICT (number) DO UPDATE SET state = $5"
-	_, err = r.db.ExecContext(ctx, query, order.ID, order.Number, order.CustomerID, order.CreatedAt, order.State)
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return order.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+// update обновляет проекцию, только если её версия совпадает с версией, от которой заказ изменён
+func (r *PostgresRepository) update(ctx context.Context, ordr *order.Order, expected int) error {
+	query, _, err := r.builder.Update("orders").
+		Set("state", "?").
+		Set("version", "?").
+		Where("id = ?").
+		Where("version = ?").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := transaction.ExecutorFrom(ctx, r.db).ExecContext(ctx, query, ordr.State, ordr.Version, ordr.ID, expected)
 	if err != nil {
 		return err
 	}
 
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return order.ErrVersionConflict
+	}
+
 	return nil
 }
 
-func (r *PostgresRepository) GetForCustomer(ctx context.Context, customerID string, limit uint64, offset uint64) ([]*order.Order, error) {
-	query, _, err := r.builder.Select("id", "number", "user_id", "created_at", "state").
-		From("orders").
-		Where("user_id = ?").
-		OrderBy("created_at desc").
-		Limit(limit).
-		Offset(offset).
+// appendEvents пишет несохранённые события заказов; хранилище событий не даст двум
+// конкурентным изменениям записать одну и ту же версию
+func (r *PostgresRepository) appendEvents(ctx context.Context, orders []*order.Order) error {
+	for _, ordr := range orders {
+		_, err := r.store.Append(ctx, ordr.ID, ordr.Version-len(ordr.Changes()), ordr.Changes())
+		if errors.Is(err, eventstore.ErrVersionConflict) {
+			return order.ErrVersionConflict
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) saveTransitions(ctx context.Context, transitions []*order.Transition) error {
+	if len(transitions) == 0 {
+		return nil
+	}
+
+	q := r.builder.Insert("order_state_transitions").
+		Columns("id", "order_id", "from_state", "to_state", "cause", "occurred_at")
+
+	for _, t := range transitions {
+		q = q.Values(t.ID, t.OrderID, string(t.From), string(t.To), t.Cause, t.OccurredAt)
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.ExecutorFrom(ctx, r.db).ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *PostgresRepository) GetTransitions(ctx context.Context, orderID string) ([]*order.Transition, error) {
+	query, _, err := r.builder.Select("id", "order_id", "from_state", "to_state", "cause", "occurred_at").
+		From("order_state_transitions").
+		Where("order_id = ?").
+		OrderBy("occurred_at ASC").
 		ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	orders := make([]*order.Order, 0)
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
 
-	result, err := r.db.QueryContext(ctx, query, customerID)
+	transitions := make([]*order.Transition, 0)
+	for rows.Next() {
+		t := &order.Transition{}
+		err := rows.Scan(&t.ID, &t.OrderID, &t.From, &t.To, &t.Cause, &t.OccurredAt)
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+
+	return transitions, nil
+}
+
+func (r *PostgresRepository) List(ctx context.Context, query order.ListQuery) ([]*order.Order, error) {
+	q := r.builder.Select(orderColumns...).
+		From("orders").
+		Where(squirrel.Eq{"user_id": query.CustomerID})
+
+	if len(query.States) > 0 {
+		st := make([]string, len(query.States))
+		for i, state := range query.States {
+			st[i] = string(state)
+		}
+		q = q.Where(squirrel.Eq{"state": st})
+	}
+
+	result, err := pagination.Apply(q, query.Page, "created_at", "id").
+		RunWith(r.db).
+		QueryContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -81,13 +368,12 @@ func (r *PostgresRepository) GetForCustomer(ctx context.Context, customerID stri
 		return nil, result.Err()
 	}
 
+	orders := make([]*order.Order, 0)
 	for result.Next() {
-		ordr := &order.Order{}
-		err := result.Scan(&ordr.ID, &ordr.Number, &ordr.CustomerID, &ordr.CreatedAt, &ordr.State)
+		ordr, err := scanOrder(result)
 		if err != nil {
 			return nil, err
 		}
-
 		orders = append(orders, ordr)
 	}
 
@@ -101,7 +387,7 @@ func (r *PostgresRepository) GetByStates(ctx context.Context, states []order.Sta
 	}
 
 	orders := make([]*order.Order, 0)
-	result, err := r.builder.Select("id", "number", "user_id", "created_at", "state").
+	result, err := r.builder.Select(orderColumns...).
 		From("orders").
 		Where(squirrel.Eq{"state": st}).
 		OrderBy("created_at desc").
@@ -120,8 +406,7 @@ func (r *PostgresRepository) GetByStates(ctx context.Context, states []order.Sta
 	}
 
 	for result.Next() {
-		ordr := &order.Order{}
-		err := result.Scan(&ordr.ID, &ordr.Number, &ordr.CustomerID, &ordr.CreatedAt, &ordr.State)
+		ordr, err := scanOrder(result)
 		if err != nil {
 			return nil, err
 		}
@@ -131,3 +416,93 @@ func (r *PostgresRepository) GetByStates(ctx context.Context, states []order.Sta
 
 	return orders, nil
 }
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// lineItem - позиция чека в колонке line_items
+type lineItem struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+// insertValues возвращает значения колонок orderColumns; метаданные пишутся как NULL, если их нет
+func insertValues(ordr *order.Order) ([]interface{}, error) {
+	values := []interface{}{ordr.ID, ordr.Number, ordr.CustomerID, ordr.CreatedAt, ordr.State, ordr.Version}
+
+	md := ordr.Metadata
+	if md == nil {
+		return append(values, nil, nil, nil, nil, nil), nil
+	}
+
+	var items interface{}
+	if len(md.Items) > 0 {
+		li := make([]lineItem, len(md.Items))
+		for i, item := range md.Items {
+			li[i] = lineItem{Name: item.Name, Price: item.Price}
+		}
+
+		data, err := json.Marshal(li)
+		if err != nil {
+			return nil, err
+		}
+		items = data
+	}
+
+	return append(values,
+		nullString(md.MerchantID),
+		sql.NullFloat64{Float64: md.PurchaseAmount, Valid: md.PurchaseAmount != 0},
+		nullString(md.Currency),
+		md.PurchasedAt,
+		items,
+	), nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func scanOrder(row rowScanner) (*order.Order, error) {
+	var (
+		ordr           = &order.Order{}
+		merchantID     sql.NullString
+		purchaseAmount sql.NullFloat64
+		currency       sql.NullString
+		purchasedAt    sql.NullTime
+		items          []byte
+	)
+
+	err := row.Scan(&ordr.ID, &ordr.Number, &ordr.CustomerID, &ordr.CreatedAt, &ordr.State, &ordr.Version,
+		&merchantID, &purchaseAmount, &currency, &purchasedAt, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	if !merchantID.Valid && !purchaseAmount.Valid && !currency.Valid && !purchasedAt.Valid && items == nil {
+		return ordr, nil
+	}
+
+	ordr.Metadata = &order.Metadata{
+		MerchantID:     merchantID.String,
+		PurchaseAmount: purchaseAmount.Float64,
+		Currency:       currency.String,
+	}
+	if purchasedAt.Valid {
+		ordr.Metadata.PurchasedAt = &purchasedAt.Time
+	}
+
+	if items != nil {
+		li := make([]lineItem, 0)
+		err = json.Unmarshal(items, &li)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range li {
+			ordr.Metadata.Items = append(ordr.Metadata.Items, order.LineItem{Name: item.Name, Price: item.Price})
+		}
+	}
+
+	return ordr, nil
+}
@@ -0,0 +1,242 @@
+package order
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/sviatilnik/gophermart/internal/domain/order"
+)
+
+func TestPostgresRepository_Save(t *testing.T) {
+	tests := []struct {
+		name        string
+		order       func() *order.Order
+		mockSetup   func(mock sqlmock.Sqlmock)
+		wantErr     bool
+		err         error
+		wantVersion int
+	}{
+		{
+			name: "insert new order",
+			order: func() *order.Order {
+				return order.NewOrder("79927398713", "customer", nil)
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("^INSERT INTO orders (.+) ON CONFLICT \\(number\\) DO NOTHING$").
+					WithArgs(sqlmock.AnyArg(), order.Number("79927398713"), "customer", sqlmock.AnyArg(), order.New, 1, nil, nil, nil, nil, nil).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("^SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM order_events WHERE aggregate_id = \\$1$").
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+				mock.ExpectQuery("^INSERT INTO order_events (.+) RETURNING position$").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "uploaded", sqlmock.AnyArg(), 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(1))
+				mock.ExpectExec("^INSERT INTO order_state_transitions (.+)$").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr:     false,
+			wantVersion: 1,
+		},
+		{
+			name: "insert order with metadata",
+			order: func() *order.Order {
+				o := order.NewOrder("79927398713", "customer", nil)
+				o.Metadata = &order.Metadata{
+					MerchantID:     "merchant-1",
+					PurchaseAmount: 1250.5,
+					Currency:       "RUB",
+					Items:          []order.LineItem{{Name: "Чайник", Price: 1250.5}},
+				}
+				return o
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("^INSERT INTO orders (.+)$").
+					WithArgs(sqlmock.AnyArg(), order.Number("79927398713"), "customer", sqlmock.AnyArg(), order.New, 1,
+						"merchant-1", 1250.5, "RUB", nil, []byte(`[{"name":"Чайник","price":1250.5}]`)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("^SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM order_events WHERE aggregate_id = \\$1$").
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+				mock.ExpectQuery("^INSERT INTO order_events (.+) RETURNING position$").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "uploaded", sqlmock.AnyArg(), 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(1))
+				mock.ExpectExec("^INSERT INTO order_state_transitions (.+)$").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr:     false,
+			wantVersion: 1,
+		},
+		{
+			name: "insert existing number",
+			order: func() *order.Order {
+				return order.NewOrder("79927398713", "other", nil)
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("^INSERT INTO orders (.+)$").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr:     true,
+			err:         order.ErrAlreadyExists,
+			wantVersion: 1,
+		},
+		{
+			name: "update current version",
+			order: func() *order.Order {
+				o := &order.Order{ID: "id", Number: "79927398713", CustomerID: "customer", CreatedAt: time.Now(), State: order.New, Version: 3}
+				_ = o.TransitionTo(order.Processed, order.CauseAccrualSystem)
+				return o
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("^UPDATE orders SET state = (.+), version = (.+) WHERE id = (.+) AND version = (.+)$").
+					WithArgs(order.Processed, 4, "id", 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("^SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM order_events").
+					WithArgs("id").
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+				mock.ExpectQuery("^INSERT INTO order_events").
+					WithArgs(sqlmock.AnyArg(), "id", "status_changed", sqlmock.AnyArg(), 4, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(7))
+				mock.ExpectExec("^INSERT INTO order_state_transitions (.+)$").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr:     false,
+			wantVersion: 4,
+		},
+		{
+			name: "update stale version",
+			order: func() *order.Order {
+				o := &order.Order{ID: "id", Number: "79927398713", CustomerID: "customer", CreatedAt: time.Now(), State: order.Processing, Version: 2}
+				_ = o.TransitionTo(order.Processed, order.CauseAccrualSystem)
+				return o
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("^UPDATE orders (.+)$").
+					WithArgs(order.Processed, 3, "id", 2).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr:     true,
+			err:         order.ErrVersionConflict,
+			wantVersion: 3,
+		},
+		{
+			name: "history ahead of projection",
+			order: func() *order.Order {
+				o := &order.Order{ID: "id", Number: "79927398713", CustomerID: "customer", CreatedAt: time.Now(), State: order.Processing, Version: 2}
+				_ = o.TransitionTo(order.Processed, order.CauseAccrualSystem)
+				return o
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("^UPDATE orders (.+)$").
+					WithArgs(order.Processed, 3, "id", 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("^SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM order_events").
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+				mock.ExpectRollback()
+			},
+			wantErr:     true,
+			err:         order.ErrVersionConflict,
+			wantVersion: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			o := tt.order()
+			r := NewOrderPostgresRepository(db)
+			err = r.Save(context.TODO(), o)
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tt.err)
+				assert.NotEmpty(t, o.Transitions())
+			} else {
+				assert.NoError(t, err)
+				assert.Empty(t, o.Transitions())
+			}
+			assert.Equal(t, tt.wantVersion, o.Version)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRepository_SaveBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	fresh := order.NewOrder("79927398713", "customer", nil)
+	taken := order.NewOrder("12345678903", "customer", nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("^INSERT INTO orders (.+) ON CONFLICT \\(number\\) DO NOTHING RETURNING number, user_id$").
+		WillReturnRows(sqlmock.NewRows([]string{"number", "user_id"}).AddRow("79927398713", "customer"))
+	mock.ExpectQuery("^SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM order_events").
+		WithArgs(fresh.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+	mock.ExpectQuery("^INSERT INTO order_events").
+		WithArgs(sqlmock.AnyArg(), fresh.ID, "uploaded", sqlmock.AnyArg(), 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(1))
+	mock.ExpectExec("^INSERT INTO order_state_transitions (.+)$").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("^SELECT number, user_id FROM orders WHERE number IN \\(\\$1\\)$").
+		WithArgs("12345678903").
+		WillReturnRows(sqlmock.NewRows([]string{"number", "user_id"}).AddRow("12345678903", "other"))
+	mock.ExpectCommit()
+
+	existing, err := NewOrderPostgresRepository(db).SaveBatch(context.TODO(), []*order.Order{fresh, taken})
+	assert.NoError(t, err)
+	assert.Equal(t, map[order.Number]string{"12345678903": "other"}, existing)
+	assert.Equal(t, 1, fresh.Version)
+	assert.Empty(t, fresh.Changes())
+	assert.NotEmpty(t, taken.Changes())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepository_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("^SELECT id FROM orders WHERE number = \\$1$").
+		WithArgs("79927398713").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("id"))
+	mock.ExpectQuery("^SELECT (.+) FROM order_events WHERE aggregate_id = \\$1 AND version > \\$2 ORDER BY version ASC$").
+		WithArgs("id", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"position", "event_id", "aggregate_id", "version", "event_type", "event_data", "metadata", "timestamp"}).
+			AddRow(1, "e1", "id", 1, "uploaded", []byte(`{"OrderID":"id","Number":"79927398713","CustomerID":"customer","OccurredAt":"2026-01-02T10:00:00Z"}`), []byte(`{}`), now).
+			AddRow(4, "e2", "id", 2, "status_changed", []byte(`{"From":"NEW","To":"PROCESSED","Cause":"accrual-system"}`), []byte(`{}`), now).
+			AddRow(9, "e3", "id", 3, "credited", []byte(`{"Amount":500}`), []byte(`{}`), now))
+
+	o, err := NewOrderPostgresRepository(db).Get(context.TODO(), "79927398713")
+	assert.NoError(t, err)
+	assert.Equal(t, "id", o.ID)
+	assert.Equal(t, "customer", o.CustomerID)
+	assert.Equal(t, order.Processed, o.State)
+	assert.Equal(t, 500.0, o.Credited)
+	assert.Equal(t, 3, o.Version)
+	assert.Empty(t, o.Changes())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,227 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/sviatilnik/gophermart/internal/domain/outbox"
+)
+
+var deadLetterColumns = []string{
+	"id", "event_id", "event_type", "aggregate_type", "aggregate_id", "payload", "headers",
+	"retries", "COALESCE(last_error, '')", "occurred_at", "dead_lettered_at",
+}
+
+type DeadLetterPostgresRepository struct {
+	db      *sql.DB
+	builder squirrel.StatementBuilderType
+}
+
+func NewDeadLetterPostgresRepository(db *sql.DB) *DeadLetterPostgresRepository {
+	return &DeadLetterPostgresRepository{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *DeadLetterPostgresRepository) List(ctx context.Context, filter outbox.Filter, limit uint64, offset uint64) ([]*outbox.DeadLetter, error) {
+	q := r.builder.Select(deadLetterColumns...).
+		From("outbox_events").
+		Where(deadLetters(filter)).
+		OrderBy("dead_lettered_at DESC", "id DESC").
+		Limit(limit).
+		Offset(offset)
+
+	rows, err := q.RunWith(r.db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*outbox.DeadLetter, 0)
+	for rows.Next() {
+		d, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *DeadLetterPostgresRepository) Get(ctx context.Context, id int64) (*outbox.DeadLetter, error) {
+	query, args, err := r.builder.Select(deadLetterColumns...).
+		From("outbox_events").
+		Where(deadLetters(outbox.Filter{IDs: []int64{id}})).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := scanDeadLetter(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, outbox.ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d.Failures, err = r.failures(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (r *DeadLetterPostgresRepository) failures(ctx context.Context, id int64) ([]*outbox.Failure, error) {
+	query, args, err := r.builder.Select("attempt", "error", "occurred_at").
+		From("outbox_event_errors").
+		Where(squirrel.Eq{"outbox_event_id": id}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failures := make([]*outbox.Failure, 0)
+	for rows.Next() {
+		f := &outbox.Failure{}
+		err = rows.Scan(&f.Attempt, &f.Error, &f.OccurredAt)
+		if err != nil {
+			return nil, err
+		}
+		failures = append(failures, f)
+	}
+
+	return failures, rows.Err()
+}
+
+func (r *DeadLetterPostgresRepository) UpdateHeaders(ctx context.Context, id int64, headers map[string]any) error {
+	headersBytes, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := r.builder.Update("outbox_events").
+		Set("headers", headersBytes).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(deadLetters(outbox.Filter{IDs: []int64{id}})).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return outbox.ErrDeadLetterNotFound
+	}
+
+	return nil
+}
+
+// Replay возвращает события в очередь с обнулённым счётчиком попыток.
+// Заголовки сохраняются, поэтому подписчики, уже обработавшие событие, повторно его не получат.
+func (r *DeadLetterPostgresRepository) Replay(ctx context.Context, filter outbox.Filter) (int64, error) {
+	return r.exec(ctx, r.builder.Update("outbox_events").
+		Set("status", outbox.StatusPending).
+		Set("retries", 0).
+		Set("process_after", squirrel.Expr("NOW()")).
+		Set("dead_lettered_at", nil).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(deadLetters(filter)))
+}
+
+func (r *DeadLetterPostgresRepository) Discard(ctx context.Context, filter outbox.Filter) (int64, error) {
+	return r.exec(ctx, r.builder.Update("outbox_events").
+		Set("status", outbox.StatusDiscarded).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(deadLetters(filter)))
+}
+
+func (r *DeadLetterPostgresRepository) exec(ctx context.Context, q squirrel.UpdateBuilder) (int64, error) {
+	query, args, err := q.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// deadLetters - условие отбора dead letter событий по фильтру
+func deadLetters(filter outbox.Filter) squirrel.And {
+	where := squirrel.And{squirrel.Eq{"status": outbox.StatusDeadLetter}}
+
+	if len(filter.IDs) > 0 {
+		where = append(where, squirrel.Eq{"id": filter.IDs})
+	}
+	if filter.EventType != "" {
+		where = append(where, squirrel.Eq{"event_type": filter.EventType})
+	}
+	if filter.From != nil {
+		where = append(where, squirrel.GtOrEq{"dead_lettered_at": *filter.From})
+	}
+	if filter.To != nil {
+		where = append(where, squirrel.Lt{"dead_lettered_at": *filter.To})
+	}
+	if filter.ErrorContains != "" {
+		// ищем по всей истории ошибок, а не только по последней
+		where = append(where, squirrel.Expr(
+			"EXISTS (SELECT 1 FROM outbox_event_errors e WHERE e.outbox_event_id = outbox_events.id AND e.error ILIKE '%' || ? || '%')",
+			filter.ErrorContains))
+	}
+
+	return where
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDeadLetter(row rowScanner) (*outbox.DeadLetter, error) {
+	d := &outbox.DeadLetter{}
+	var payload, headers []byte
+	var deadLetteredAt sql.NullTime
+
+	err := row.Scan(&d.ID, &d.EventID, &d.EventType, &d.AggregateType, &d.AggregateID, &payload, &headers,
+		&d.Retries, &d.LastError, &d.OccurredAt, &deadLetteredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Payload = payload
+	d.DeadLetteredAt = deadLetteredAt.Time
+
+	d.Headers = make(map[string]any)
+	if len(headers) > 0 {
+		err = json.Unmarshal(headers, &d.Headers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
@@ -0,0 +1,47 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/domain/outbox"
+)
+
+func TestDeadLetterPostgresRepository_Replay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("^UPDATE outbox_events SET status = \\$1, retries = \\$2, process_after = NOW\\(\\), dead_lettered_at = \\$3, updated_at = NOW\\(\\) "+
+		"WHERE \\(status = \\$4 AND event_type = \\$5 AND dead_lettered_at >= \\$6 AND EXISTS \\(.+ILIKE.+\\)\\)$").
+		WithArgs(outbox.StatusPending, 0, nil, outbox.StatusDeadLetter, "user.registered", from, "timeout").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	affected, err := NewDeadLetterPostgresRepository(db).Replay(context.Background(), outbox.Filter{
+		EventType:     "user.registered",
+		From:          &from,
+		ErrorContains: "timeout",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), affected)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeadLetterPostgresRepository_UpdateHeaders_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("^UPDATE outbox_events SET headers = (.+)$").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewDeadLetterPostgresRepository(db).UpdateHeaders(context.Background(), 42, map[string]any{"delivered": []int{0}})
+	assert.ErrorIs(t, err, outbox.ErrDeadLetterNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
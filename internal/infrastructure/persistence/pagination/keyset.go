@@ -0,0 +1,33 @@
+package pagination
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/sviatilnik/gophermart/internal/domain/pagination"
+)
+
+// Apply добавляет к запросу keyset-пагинацию по паре колонок (createdColumn, idColumn).
+// Для определения следующей страницы запрашивается на одну запись больше лимита.
+func Apply(q squirrel.SelectBuilder, page pagination.Page, createdColumn string, idColumn string) squirrel.SelectBuilder {
+	if page.From != nil {
+		q = q.Where(squirrel.GtOrEq{createdColumn: *page.From})
+	}
+
+	if page.To != nil {
+		q = q.Where(squirrel.Lt{createdColumn: *page.To})
+	}
+
+	op, direction := "<", "DESC"
+	if page.Direction == pagination.Asc {
+		op, direction = ">", "ASC"
+	}
+
+	if page.After != nil {
+		q = q.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", createdColumn, idColumn, op), page.After.CreatedAt, page.After.ID)
+	}
+
+	return q.
+		OrderBy(createdColumn+" "+direction, idColumn+" "+direction).
+		Limit(page.Limit + 1)
+}
@@ -0,0 +1,274 @@
+package reconciliation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/sviatilnik/gophermart/internal/domain/order"
+	"github.com/sviatilnik/gophermart/internal/domain/reconciliation"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/transaction"
+)
+
+var discrepancyColumns = []string{
+	"id", "kind", "status", "order_number", "customer_id", "order_state",
+	"expected_amount", "actual_amount", "deposits", "detected_at", "last_seen_at", "repaired_at",
+}
+
+type PostgresRepository struct {
+	db      *sql.DB
+	builder squirrel.StatementBuilderType
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// processedOrdersQuery собирает сведения об обработанных заказах.
+// Депозиты по заказу берутся из событий кошелька, номер заказа лежит в event_data.
+const processedOrdersQuery = `
+	WITH deposits AS (
+		SELECT event_data ->> 'OrderNumber' AS order_number,
+		       COUNT(*) AS cnt,
+		       SUM((event_data ->> 'Amount')::float) AS total
+		FROM wallet_events
+		WHERE event_type = 'deposited' AND COALESCE(event_data ->> 'OrderNumber', '') <> ''
+		GROUP BY 1
+	), unattributed AS (
+		SELECT aggregate_id AS customer_id, COUNT(*) AS cnt
+		FROM wallet_events
+		WHERE event_type = 'deposited' AND COALESCE(event_data ->> 'OrderNumber', '') = ''
+		GROUP BY 1
+	)
+	SELECT o.number, o.user_id::text, o.state, o.created_at,
+	       a.order_number IS NOT NULL, COALESCE(a.amount, 0),
+	       COALESCE(d.cnt, 0), COALESCE(d.total, 0), COALESCE(u.cnt, 0)
+	FROM orders o
+	LEFT JOIN accruals a ON a.order_number = o.number
+	LEFT JOIN deposits d ON d.order_number = o.number
+	LEFT JOIN unattributed u ON u.customer_id = o.user_id::text
+	WHERE o.state = $1`
+
+func (r *PostgresRepository) ProcessedOrders(ctx context.Context) ([]*reconciliation.OrderFacts, error) {
+	rows, err := transaction.ExecutorFrom(ctx, r.db).QueryContext(ctx, processedOrdersQuery, string(order.Processed))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*reconciliation.OrderFacts, 0)
+	for rows.Next() {
+		f, err := scanFacts(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgresRepository) OrderFacts(ctx context.Context, orderNumber string) (*reconciliation.OrderFacts, error) {
+	row := transaction.ExecutorFrom(ctx, r.db).
+		QueryRowContext(ctx, processedOrdersQuery+" AND o.number = $2", string(order.Processed), orderNumber)
+
+	f, err := scanFacts(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func scanFacts(row rowScanner) (*reconciliation.OrderFacts, error) {
+	f := &reconciliation.OrderFacts{}
+	err := row.Scan(&f.OrderNumber, &f.CustomerID, &f.OrderState, &f.CreatedAt,
+		&f.HasAccrual, &f.AccrualAmount, &f.Deposits, &f.DepositedAmount, &f.UnattributedDeposits)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (r *PostgresRepository) StuckOrders(ctx context.Context, createdBefore time.Time) ([]*reconciliation.OrderFacts, error) {
+	rows, err := r.builder.Select("number", "user_id::text", "state", "created_at").
+		From("orders").
+		Where(squirrel.Eq{"state": []string{string(order.New), string(order.Processing)}}).
+		Where(squirrel.Lt{"created_at": createdBefore}).
+		OrderBy("created_at ASC").
+		RunWith(r.db).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	result := make([]*reconciliation.OrderFacts, 0)
+	for rows.Next() {
+		f := &reconciliation.OrderFacts{}
+		err = rows.Scan(&f.OrderNumber, &f.CustomerID, &f.OrderState, &f.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+
+	return result, nil
+}
+
+func (r *PostgresRepository) Save(ctx context.Context, d *reconciliation.Discrepancy) error {
+	query, _, err := r.builder.Insert("reconciliation_discrepancies").
+		Columns(discrepancyColumns...).
+		Values("?", "?", "?", "?", "?", "?", "?", "?", "?", "?", "?", "?").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	// открытое расхождение по заказу одно, повторное обнаружение только обновляет его
+	query += ` ON CONFLICT (kind, order_number) WHERE status = 'OPEN' DO UPDATE SET
+		order_state = EXCLUDED.order_state,
+		expected_amount = EXCLUDED.expected_amount,
+		actual_amount = EXCLUDED.actual_amount,
+		deposits = EXCLUDED.deposits,
+		last_seen_at = EXCLUDED.last_seen_at
+		RETURNING id, detected_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		d.ID, d.Kind, d.Status, d.OrderNumber, d.CustomerID, d.OrderState,
+		d.ExpectedAmount, d.ActualAmount, d.Deposits, d.DetectedAt, d.LastSeenAt, d.RepairedAt,
+	).Scan(&d.ID, &d.DetectedAt)
+}
+
+func (r *PostgresRepository) UpdateStatus(ctx context.Context, d *reconciliation.Discrepancy) error {
+	query, _, err := r.builder.Update("reconciliation_discrepancies").
+		Set("status", "?").
+		Set("repaired_at", "?").
+		Where("id = ?").
+		Where("status = ?").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	// меняем только открытое расхождение, чтобы два прогона не исправили его дважды
+	result, err := transaction.ExecutorFrom(ctx, r.db).ExecContext(ctx, query, d.Status, d.RepairedAt, d.ID, reconciliation.Open)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return reconciliation.ErrNotOpen
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) Get(ctx context.Context, id string) (*reconciliation.Discrepancy, error) {
+	query, _, err := r.builder.Select(discrepancyColumns...).
+		From("reconciliation_discrepancies").
+		Where("id = ?").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := scanDiscrepancy(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, reconciliation.ErrDiscrepancyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (r *PostgresRepository) List(ctx context.Context, statuses []reconciliation.Status, limit uint64, offset uint64) ([]*reconciliation.Discrepancy, error) {
+	q := r.builder.Select(discrepancyColumns...).
+		From("reconciliation_discrepancies").
+		OrderBy("detected_at DESC").
+		Limit(limit).
+		Offset(offset)
+
+	if len(statuses) > 0 {
+		st := make([]string, len(statuses))
+		for i, s := range statuses {
+			st[i] = string(s)
+		}
+		q = q.Where(squirrel.Eq{"status": st})
+	}
+
+	rows, err := q.RunWith(r.db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	result := make([]*reconciliation.Discrepancy, 0)
+	for rows.Next() {
+		d, err := scanDiscrepancy(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+
+	return result, nil
+}
+
+func (r *PostgresRepository) ResolveNotSeenSince(ctx context.Context, since time.Time) error {
+	query, _, err := r.builder.Update("reconciliation_discrepancies").
+		Set("status", "?").
+		Where("status = ?").
+		Where("last_seen_at < ?").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query, reconciliation.Resolved, reconciliation.Open, since)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDiscrepancy(row rowScanner) (*reconciliation.Discrepancy, error) {
+	d := &reconciliation.Discrepancy{}
+	var repairedAt sql.NullTime
+
+	err := row.Scan(&d.ID, &d.Kind, &d.Status, &d.OrderNumber, &d.CustomerID, &d.OrderState,
+		&d.ExpectedAmount, &d.ActualAmount, &d.Deposits, &d.DetectedAt, &d.LastSeenAt, &repairedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if repairedAt.Valid {
+		d.RepairedAt = &repairedAt.Time
+	}
+
+	return d, nil
+}
@@ -0,0 +1,94 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+)
+
+type txKey struct{}
+
+// Executor - общие методы *sql.DB и *sql.Tx
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type PostgresTransactor struct {
+	db *sql.DB
+}
+
+func NewPostgresTransactor(db *sql.DB) *PostgresTransactor {
+	return &PostgresTransactor{db: db}
+}
+
+// WithinTransaction открывает транзакцию и кладёт её в контекст.
+// Вложенный вызов присоединяется к внешней транзакции.
+func (t *PostgresTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := FromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(context.WithValue(ctx, txKey{}, tx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func FromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// ExecutorFrom возвращает транзакцию из контекста, а без неё - сам db
+func ExecutorFrom(ctx context.Context, db *sql.DB) Executor {
+	if tx, ok := FromContext(ctx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// Tx - транзакция репозитория. Если в контексте уже есть транзакция, Tx работает в ней,
+// а Commit и Rollback оставляет её владельцу.
+type Tx struct {
+	*sql.Tx
+	owned bool
+}
+
+func Begin(ctx context.Context, db *sql.DB) (*Tx, error) {
+	if tx, ok := FromContext(ctx); ok {
+		return &Tx{Tx: tx}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{Tx: tx, owned: true}, nil
+}
+
+func (t *Tx) Commit() error {
+	if !t.owned {
+		return nil
+	}
+
+	return t.Tx.Commit()
+}
+
+func (t *Tx) Rollback() error {
+	if !t.owned {
+		return nil
+	}
+
+	return t.Tx.Rollback()
+}
@@ -0,0 +1,81 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresTransactor_WithinTransaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		fn        func(ctx context.Context, tx *Tx) error
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "nested writes share one transaction",
+			fn: func(ctx context.Context, tx *Tx) error {
+				_, err := tx.ExecContext(ctx, "INSERT INTO users")
+				if err != nil {
+					return err
+				}
+
+				return tx.Commit()
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO outbox_events").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "error rolls back everything",
+			fn: func(context.Context, *Tx) error {
+				return errors.New("save failed")
+			},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO outbox_events").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			tt.mockSetup(mock)
+
+			err = NewPostgresTransactor(db).WithinTransaction(context.Background(), func(ctx context.Context) error {
+				_, err := ExecutorFrom(ctx, db).ExecContext(ctx, "INSERT INTO outbox_events")
+				if err != nil {
+					return err
+				}
+
+				tx, err := Begin(ctx, db)
+				if err != nil {
+					return err
+				}
+				defer tx.Rollback()
+
+				return tt.fn(ctx, tx)
+			})
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
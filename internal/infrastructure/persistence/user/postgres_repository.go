@@ -6,6 +6,7 @@ import (
 	"errors"
 	"github.com/Masterminds/squirrel"
 	"github.com/sviatilnik/gophermart/internal/domain/user"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/transaction"
 )
 
 type PostgresUserRepository struct {
@@ -36,7 +37,7 @@ func (r *PostgresUserRepository) Save(ctx context.Context, usr *user.User) error
 		return err
 	}
 
-	_, err = r.db.ExecContext(ctx, query, usr.ID, usr.Login, usr.Password)
+	_, err = transaction.ExecutorFrom(ctx, r.db).ExecContext(ctx, query, usr.ID, usr.Login, usr.Password)
 	if err != nil {
 		return err
 	}
@@ -5,76 +5,65 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
 	"github.com/sviatilnik/gophermart/internal/domain/wallet"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/eventstore"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/pagination"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/transaction"
 )
 
-var errUnknownEvent = errors.New("unknown event")
-var errVersionConflict = errors.New("version conflict")
+// snapshotEvery - раз в сколько событий сохраняется снимок кошелька
+const snapshotEvery = 50
 
 type PostgresRepository struct {
 	db                 *sql.DB
-	eventsTableName    string
+	store              *eventstore.PostgresStore[wallet.Event]
+	transactor         *transaction.PostgresTransactor
 	withdrawsTableName string
 	builder            squirrel.StatementBuilderType
 }
 
 func NewWalletPostgresRepository(db *sql.DB) *PostgresRepository {
 	return &PostgresRepository{
-		db:                 db,
-		eventsTableName:    "wallet_events",
+		db: db,
+		store: eventstore.NewPostgresStore(db, eventstore.Tables{
+			Events:    "wallet_events",
+			Snapshots: "wallet_snapshots",
+		}, newCodec()),
+		transactor:         transaction.NewPostgresTransactor(db),
 		withdrawsTableName: "wallet_withdrawals",
 		builder:            squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
 	}
 }
 
-func (p *PostgresRepository) Load(ctx context.Context, customerID string) (*wallet.Wallet, error) {
-	query, _, err := p.builder.Select("event_type", "event_data").
-		From(p.eventsTableName).
-		Where("aggregate_id = ?").
-		OrderBy("version ASC").
-		ToSql()
-
-	if err != nil {
-		return nil, err
-	}
-
-	var events []wallet.Event
+// newCodec - кодек событий истории кошелька
+func newCodec() *eventstore.JSONCodec[wallet.Event] {
+	return eventstore.NewJSONCodec(wallet.Event.GetType).
+		Register("created", func() wallet.Event { return &wallet.Created{} }).
+		Register("deposited", func() wallet.Event { return &wallet.Deposited{} }).
+		Register("withdrawn", func() wallet.Event { return &wallet.Withdrawn{} }).
+		Register("reversed", func() wallet.Event { return &wallet.Reversed{} })
+}
 
-	rows, err := p.db.QueryContext(ctx, query, customerID)
+func (p *PostgresRepository) Load(ctx context.Context, customerID string) (*wallet.Wallet, error) {
+	stream, err := p.store.Load(ctx, customerID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	if rows.Err() != nil {
-		return nil, rows.Err()
-	}
-
-	for rows.Next() {
-		var (
-			eventType string
-			eventData []byte
-		)
 
-		if err := rows.Scan(&eventType, &eventData); err != nil {
-			return nil, err
-		}
-
-		event, err := p.unmarshalEventData(eventType, eventData)
-		if err != nil {
+	wlt := wallet.NewWallet(customerID)
+	if stream.Snapshot != nil {
+		var snapshot wallet.Snapshot
+		if err = json.Unmarshal(stream.Snapshot.State, &snapshot); err != nil {
 			return nil, err
 		}
-		events = append(events, event)
+		wlt = wallet.FromSnapshot(customerID, snapshot)
 	}
 
-	wlt := wallet.NewWallet(customerID)
-
-	for _, event := range events {
-		wlt.ApplyEvent(event)
+	for _, record := range stream.Events {
+		wlt.ApplyEvent(record.Event)
 	}
 
 	wlt.Reset()
@@ -83,97 +72,40 @@ func (p *PostgresRepository) Load(ctx context.Context, customerID string) (*wall
 }
 
 func (p *PostgresRepository) Store(ctx context.Context, wlt *wallet.Wallet) error {
-	tx, err := p.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	currentVersion, err := p.checkVersion(tx, wlt)
-	if err != nil {
-		return err
-	}
+	return p.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		expected := wlt.Version() - len(wlt.Events())
 
-	query, _, err := p.builder.Insert(p.eventsTableName).
-		Columns("event_id", "aggregate_id", "event_type", "event_data", "version", "timestamp").
-		Values("?", "?", "?", "?", "?", "?").
-		ToSql()
-	if err != nil {
-		return err
-	}
-
-	for i, event := range wlt.Events() {
-		eventVersion := currentVersion + i + 1
-
-		data, err := json.Marshal(event)
-		if err != nil {
-			return err
+		records, err := p.store.Append(ctx, wlt.CustomerID, expected, wlt.Events())
+		if errors.Is(err, eventstore.ErrVersionConflict) {
+			return wallet.ErrVersionConflict
 		}
-
-		eventID := uuid.New().String()
-
-		_, err = tx.ExecContext(
-			ctx,
-			query,
-			eventID,
-			wlt.CustomerID,
-			event.GetType(),
-			data,
-			eventVersion,
-			time.Now())
 		if err != nil {
 			return err
 		}
 
-		if withdrawn, ok := event.(*wallet.Withdrawn); ok {
-			err = p.saveWithdrawn(tx, eventID, withdrawn)
-			if err != nil {
-				return err
+		for _, record := range records {
+			if withdrawn, ok := record.Event.(*wallet.Withdrawn); ok {
+				err = p.saveWithdrawn(ctx, record.EventID, withdrawn)
+				if err != nil {
+					return err
+				}
 			}
 		}
 
-	}
-
-	// TODO add snapshots
-	/* if wlt.Version() % 50 == 0 {
-
-	} */
-
-	return tx.Commit()
-}
-
-func (p *PostgresRepository) checkVersion(tx *sql.Tx, wlt *wallet.Wallet) (int, error) {
-	var maxVersion sql.NullInt64
-	query, _, err := p.builder.Select("MAX(version)").
-		From(p.eventsTableName).
-		Where("aggregate_id = ?").
-		ToSql()
-	if err != nil {
-		return 0, err
-	}
-
-	err = tx.QueryRow(
-		query,
-		wlt.CustomerID,
-	).Scan(&maxVersion)
-
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return 0, err
-	}
-
-	m := 0
-	if maxVersion.Valid {
-		m = int(maxVersion.Int64)
-	}
+		if expected/snapshotEvery == wlt.Version()/snapshotEvery {
+			return nil
+		}
 
-	if m != wlt.Version()-len(wlt.Events()) {
-		return m, wallet.ErrVersionConflict
-	}
+		state, err := json.Marshal(wlt.Snapshot())
+		if err != nil {
+			return err
+		}
 
-	return m, nil
+		return p.store.SaveSnapshot(ctx, wlt.CustomerID, eventstore.Snapshot{Version: wlt.Version(), State: state})
+	})
 }
 
-func (p *PostgresRepository) saveWithdrawn(tx *sql.Tx, eventID string, withdrawn *wallet.Withdrawn) error {
+func (p *PostgresRepository) saveWithdrawn(ctx context.Context, eventID string, withdrawn *wallet.Withdrawn) error {
 	query, _, err := p.builder.Insert(p.withdrawsTableName).
 		Columns("id", "event_id", "customer_id", "amount", "order_number", "timestamp").
 		Values("?", "?", "?", "?", "?", "?").
@@ -183,7 +115,8 @@ func (p *PostgresRepository) saveWithdrawn(tx *sql.Tx, eventID string, withdrawn
 		return err
 	}
 
-	_, err = tx.Exec(
+	_, err = transaction.ExecutorFrom(ctx, p.db).ExecContext(
+		ctx,
 		query,
 		uuid.NewString(),
 		eventID,
@@ -197,63 +130,27 @@ func (p *PostgresRepository) saveWithdrawn(tx *sql.Tx, eventID string, withdrawn
 }
 
 func (p *PostgresRepository) Exists(ctx context.Context, customerID string) (bool, error) {
-	query, _, err := p.builder.Select("count(*)").
-		From(p.eventsTableName).
-		Where("aggregate_id = ?").
-		ToSql()
-
-	if err != nil {
-		return false, err
-	}
-
-	var count int
-	err = p.db.QueryRowContext(ctx, query, customerID).Scan(&count)
-	if err != nil {
-		return false, err
-	}
-
-	return count > 0, nil
-}
-
-func (p *PostgresRepository) unmarshalEventData(eventType string, data []byte) (wallet.Event, error) {
-	switch eventType {
-	case "created":
-		var event wallet.Created
-		err := json.Unmarshal(data, &event)
-		return &event, err
-	case "deposited":
-		var event wallet.Deposited
-		err := json.Unmarshal(data, &event)
-		return &event, err
-	case "withdrawn":
-		var event wallet.Withdrawn
-		err := json.Unmarshal(data, &event)
-		return &event, err
-	}
-
-	return nil, errUnknownEvent
+	return p.store.Exists(ctx, customerID)
 }
 
-func (p *PostgresRepository) Withdraws(ctx context.Context, customerID string) ([]*wallet.Withdraw, error) {
-	query, _, err := p.builder.Select("id", "customer_id", "amount", "order_number", "timestamp").
+func (p *PostgresRepository) Withdraws(ctx context.Context, query wallet.WithdrawsQuery) ([]*wallet.Withdraw, error) {
+	q := p.builder.Select("id", "customer_id", "amount", "order_number", "timestamp").
 		From(p.withdrawsTableName).
-		Where("customer_id = ?").
-		OrderBy("timestamp DESC").
-		ToSql()
+		Where(squirrel.Eq{"customer_id": query.CustomerID})
 
+	rows, err := pagination.Apply(q, query.Page, "timestamp", "id").
+		RunWith(p.db).
+		QueryContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	result := make([]*wallet.Withdraw, 0)
-	rows, err := p.db.QueryContext(ctx, query, customerID)
-	if err != nil {
-		return nil, err
-	}
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
 
+	result := make([]*wallet.Withdraw, 0)
 	for rows.Next() {
 		withdraw := &wallet.Withdraw{}
 
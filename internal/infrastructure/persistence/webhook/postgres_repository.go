@@ -0,0 +1,414 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/sviatilnik/gophermart/internal/domain/webhook"
+	"github.com/sviatilnik/gophermart/internal/infrastructure/persistence/transaction"
+)
+
+var subscriptionColumns = []string{
+	"id", "partner", "url", "event_types", "secret", "active", "consecutive_failures",
+	"disabled_at", "COALESCE(disabled_reason, '')", "created_at",
+}
+
+var deliveryColumns = []string{
+	"d.id", "d.subscription_id", "d.event_id", "d.event_type", "d.body", "d.status", "d.attempts",
+	"d.next_attempt_at", "COALESCE(d.last_error, '')", "d.created_at", "d.delivered_at",
+}
+
+type PostgresRepository struct {
+	db      *sql.DB
+	builder squirrel.StatementBuilderType
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *PostgresRepository) Create(ctx context.Context, s *webhook.Subscription) error {
+	eventTypes, err := json.Marshal(s.EventTypes)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := r.builder.Insert("webhook_subscriptions").
+		Columns("id", "partner", "url", "event_types", "secret", "active", "created_at").
+		Values(s.ID, s.Partner, s.URL, eventTypes, s.Secret, s.Active, s.CreatedAt).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *PostgresRepository) Get(ctx context.Context, id string) (*webhook.Subscription, error) {
+	query, args, err := r.builder.Select(subscriptionColumns...).
+		From("webhook_subscriptions").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := scanSubscription(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, webhook.ErrSubscriptionNotFound
+	}
+
+	return s, err
+}
+
+func (r *PostgresRepository) List(ctx context.Context) ([]*webhook.Subscription, error) {
+	return r.subscriptions(ctx, r.builder.Select(subscriptionColumns...).
+		From("webhook_subscriptions").
+		OrderBy("created_at", "id"))
+}
+
+func (r *PostgresRepository) Matching(ctx context.Context, eventType string) ([]*webhook.Subscription, error) {
+	return r.subscriptions(ctx, r.builder.Select(subscriptionColumns...).
+		From("webhook_subscriptions").
+		Where(squirrel.Eq{"active": true}).
+		Where("event_types @> jsonb_build_array(?::text)", eventType).
+		OrderBy("created_at", "id"))
+}
+
+func (r *PostgresRepository) subscriptions(ctx context.Context, q squirrel.SelectBuilder) ([]*webhook.Subscription, error) {
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := transaction.ExecutorFrom(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*webhook.Subscription, 0)
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
+	query, args, err := r.builder.Delete("webhook_subscriptions").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return r.execOne(ctx, query, args)
+}
+
+func (r *PostgresRepository) Enable(ctx context.Context, id string) error {
+	query, args, err := r.builder.Update("webhook_subscriptions").
+		Set("active", true).
+		Set("consecutive_failures", 0).
+		Set("disabled_at", nil).
+		Set("disabled_reason", nil).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return r.execOne(ctx, query, args)
+}
+
+func (r *PostgresRepository) execOne(ctx context.Context, query string, args []any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return webhook.ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) Enqueue(ctx context.Context, deliveries []*webhook.Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	q := r.builder.Insert("webhook_deliveries").
+		Columns("subscription_id", "event_id", "event_type", "body", "status", "next_attempt_at", "created_at").
+		Suffix("ON CONFLICT (subscription_id, event_id) DO NOTHING")
+	for _, d := range deliveries {
+		q = q.Values(d.SubscriptionID, d.EventID, d.EventType, []byte(d.Body), d.Status, d.NextAttemptAt, d.CreatedAt)
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.ExecutorFrom(ctx, r.db).ExecContext(ctx, query, args...)
+	return err
+}
+
+// Claim сдвигает next_attempt_at на время аренды: если процесс упадёт посреди запроса,
+// отправка вернётся в очередь, а SKIP LOCKED не даст двум проходам взять её одновременно.
+func (r *PostgresRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*webhook.Delivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = $1 AND d.next_attempt_at <= NOW() AND s.active
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $2
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = NOW() + $3 * INTERVAL '1 millisecond'
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING `+strings.Join(deliveryColumns, ", ")+`, s.url, s.secret`,
+		webhook.DeliveryPending, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*webhook.Delivery, 0)
+	for rows.Next() {
+		d := &webhook.Delivery{}
+		err = scanDelivery(rows, d, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgresRepository) RecordSuccess(ctx context.Context, d *webhook.Delivery, attempt *webhook.Attempt) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = r.insertAttempt(ctx, tx, attempt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = $1, attempts = $2, last_error = NULL, delivered_at = $3
+		WHERE id = $4`,
+		webhook.DeliveryDelivered, attempt.Number, attempt.OccurredAt, d.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = $1`, d.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresRepository) RecordFailure(ctx context.Context, d *webhook.Delivery, attempt *webhook.Attempt, retryAt *time.Time, disableAfter int) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = r.insertAttempt(ctx, tx, attempt)
+	if err != nil {
+		return false, err
+	}
+
+	status, nextAttemptAt := webhook.DeliveryFailed, attempt.OccurredAt
+	if retryAt != nil {
+		status, nextAttemptAt = webhook.DeliveryPending, *retryAt
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4
+		WHERE id = $5`,
+		status, attempt.Number, nextAttemptAt, attempt.Error, d.ID)
+	if err != nil {
+		return false, err
+	}
+
+	// подписка отключается ровно один раз - в момент, когда счётчик достигает порога
+	var disabled bool
+	err = tx.QueryRowContext(ctx, `
+		UPDATE webhook_subscriptions SET
+			consecutive_failures = consecutive_failures + 1,
+			active = active AND consecutive_failures + 1 < $1,
+			disabled_at = CASE WHEN active AND consecutive_failures + 1 >= $1 THEN NOW() ELSE disabled_at END,
+			disabled_reason = CASE WHEN active AND consecutive_failures + 1 >= $1 THEN $2 ELSE disabled_reason END
+		WHERE id = $3
+		RETURNING NOT active AND consecutive_failures = $1`,
+		disableAfter, attempt.Error, d.SubscriptionID).Scan(&disabled)
+	if err != nil {
+		return false, err
+	}
+
+	return disabled, tx.Commit()
+}
+
+func (r *PostgresRepository) insertAttempt(ctx context.Context, tx *sql.Tx, a *webhook.Attempt) error {
+	var responseStatus sql.NullInt64
+	if a.ResponseStatus != 0 {
+		responseStatus = sql.NullInt64{Int64: int64(a.ResponseStatus), Valid: true}
+	}
+
+	query, args, err := r.builder.Insert("webhook_delivery_attempts").
+		Columns("delivery_id", "attempt", "request", "response_status", "response", "error", "duration_ms", "occurred_at").
+		Values(a.DeliveryID, a.Number, a.RequestSnippet, responseStatus, a.ResponseSnippet, a.Error, a.Duration.Milliseconds(), a.OccurredAt).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *PostgresRepository) Deliveries(ctx context.Context, subscriptionID string, limit uint64) ([]*webhook.Delivery, error) {
+	query, args, err := r.builder.Select(deliveryColumns...).
+		From("webhook_deliveries d").
+		Where(squirrel.Eq{"d.subscription_id": subscriptionID}).
+		OrderBy("d.id DESC").
+		Limit(limit).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*webhook.Delivery, 0)
+	byID := make(map[int64]*webhook.Delivery)
+	for rows.Next() {
+		d := &webhook.Delivery{}
+		err = scanDelivery(rows, d)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+		byID[d.ID] = d
+	}
+	if err = rows.Err(); err != nil || len(result) == 0 {
+		return result, err
+	}
+
+	return result, r.attachLog(ctx, byID)
+}
+
+// attachLog дополняет отправки журналом попыток одним запросом
+func (r *PostgresRepository) attachLog(ctx context.Context, deliveries map[int64]*webhook.Delivery) error {
+	ids := make([]int64, 0, len(deliveries))
+	for id := range deliveries {
+		ids = append(ids, id)
+	}
+
+	query, args, err := r.builder.Select("delivery_id", "attempt", "request", "COALESCE(response_status, 0)",
+		"COALESCE(response, '')", "COALESCE(error, '')", "duration_ms", "occurred_at").
+		From("webhook_delivery_attempts").
+		Where(squirrel.Eq{"delivery_id": ids}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a := &webhook.Attempt{}
+		var durationMs int64
+		err = rows.Scan(&a.DeliveryID, &a.Number, &a.RequestSnippet, &a.ResponseStatus, &a.ResponseSnippet, &a.Error, &durationMs, &a.OccurredAt)
+		if err != nil {
+			return err
+		}
+		a.Duration = time.Duration(durationMs) * time.Millisecond
+
+		d := deliveries[a.DeliveryID]
+		d.Log = append(d.Log, a)
+	}
+
+	return rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSubscription(row rowScanner) (*webhook.Subscription, error) {
+	s := &webhook.Subscription{}
+	var eventTypes []byte
+	var disabledAt sql.NullTime
+
+	err := row.Scan(&s.ID, &s.Partner, &s.URL, &eventTypes, &s.Secret, &s.Active, &s.ConsecutiveFailures,
+		&disabledAt, &s.DisabledReason, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if disabledAt.Valid {
+		s.DisabledAt = &disabledAt.Time
+	}
+
+	return s, json.Unmarshal(eventTypes, &s.EventTypes)
+}
+
+func scanDelivery(row rowScanner, d *webhook.Delivery, extra ...any) error {
+	var body []byte
+	var status string
+	var deliveredAt sql.NullTime
+
+	dest := []any{&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &body, &status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastError, &d.CreatedAt, &deliveredAt}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return err
+	}
+
+	d.Body = body
+	d.Status = webhook.DeliveryStatus(status)
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package accrual
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+var (
+	ErrInvalidCACertificate = errors.New("no certificates found in CA file")
+	ErrTLSRequiresHTTPS     = errors.New("accrual TLS certificates are configured, but the accrual system address is not https")
+)
+
+// ClientConfig - настройки аутентификации исходящих запросов к системе начислений.
+// Пустые поля отключают соответствующий способ.
+type ClientConfig struct {
+	// BaseURL - адрес системы начислений, нужен для проверки, что сертификаты действительно будут использованы
+	BaseURL      string
+	Timeout      time.Duration
+	APIKeyHeader string
+	APIKey       string
+	HMACKeyID    string
+	HMACSecret   string
+	CertFile     string
+	KeyFile      string
+	CAFile       string
+}
+
+// NewClient собирает http.Client с mTLS и аутентификаторами согласно конфигурации
+func NewClient(conf ClientConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if conf.CertFile != "" || conf.CAFile != "" {
+		// по http сертификаты молча не отправляются, такую конфигурацию лучше не запускать вовсе
+		u, err := url.Parse(conf.BaseURL)
+		if err != nil || u.Scheme != "https" {
+			return nil, ErrTLSRequiresHTTPS
+		}
+
+		tlsConfig, err := NewTLSConfig(conf.CertFile, conf.KeyFile, conf.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	authenticators := make([]RequestAuthenticator, 0)
+	if conf.APIKey != "" {
+		authenticators = append(authenticators, NewAPIKeyAuthenticator(conf.APIKeyHeader, conf.APIKey))
+	}
+	if conf.HMACSecret != "" {
+		authenticators = append(authenticators, NewHMACAuthenticator(conf.HMACKeyID, conf.HMACSecret))
+	}
+
+	timeout := conf.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: NewAuthTransport(transport, authenticators...),
+	}, nil
+}
+
+// NewTLSConfig загружает клиентский сертификат и CA для проверки сервера
+func NewTLSConfig(certFile string, keyFile string, caFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, ErrInvalidCACertificate
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,219 @@
+package accrual
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sviatilnik/gophermart/internal/domain/accrual"
+)
+
+func accrualStub(t *testing.T, check func(r *http.Request) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !check(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		number := strings.TrimPrefix(r.URL.Path, "/api/orders/")
+		w.Header().Set("Content-Type", "application/json")
+		_, err := io.WriteString(w, `{"order":"`+number+`","status":"PROCESSED","accrual":500}`)
+		assert.NoError(t, err)
+	}
+}
+
+// checkOrder запрашивает статус заказа так же, как сервис начислений
+func checkOrder(client *http.Client, baseURL string, number string) (*accrual.Accrual, error) {
+	resp, err := client.Get(baseURL + "/api/orders/" + number)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	body := struct {
+		Order   string  `json:"order"`
+		Status  string  `json:"status"`
+		Accrual float64 `json:"accrual"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return &accrual.Accrual{OrderNumber: body.Order, State: accrual.State(body.Status), Amount: body.Accrual}, nil
+}
+
+func TestClient_APIKey(t *testing.T) {
+	server := httptest.NewServer(accrualStub(t, func(r *http.Request) bool {
+		return r.Header.Get("X-Partner-Key") == "secret-key"
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientConfig{APIKeyHeader: "X-Partner-Key", APIKey: "secret-key"})
+	require.NoError(t, err)
+
+	acc, err := checkOrder(client, server.URL, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, accrual.Processed, acc.State)
+
+	_, err = checkOrder(server.Client(), server.URL, "12345678903")
+	assert.Error(t, err)
+}
+
+func TestClient_HMAC(t *testing.T) {
+	secret := []byte("hmac-secret")
+
+	server := httptest.NewServer(accrualStub(t, func(r *http.Request) bool {
+		timestamp := r.Header.Get(TimestampHeader)
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+			return false
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		expected := Sign(secret, r.Method, r.URL.RequestURI(), timestamp, body)
+
+		return r.Header.Get(KeyIDHeader) == "partner-1" && r.Header.Get(SignatureHeader) == expected
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientConfig{HMACKeyID: "partner-1", HMACSecret: string(secret)})
+	require.NoError(t, err)
+
+	acc, err := checkOrder(client, server.URL, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, 500.0, acc.Amount)
+
+	wrong, err := NewClient(ClientConfig{HMACKeyID: "partner-1", HMACSecret: "other"})
+	require.NoError(t, err)
+
+	_, err = checkOrder(wrong, server.URL, "12345678903")
+	assert.Error(t, err)
+}
+
+func TestClient_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	caCert, caKey := newCertificate(t, nil, nil, "test-ca", true)
+	serverCert, serverKey := newCertificate(t, caCert, caKey, "127.0.0.1", false)
+	clientCert, clientKey := newCertificate(t, caCert, caKey, "gophermart", false)
+
+	caFile := writePEM(t, dir, "ca.pem", "CERTIFICATE", caCert.Raw)
+	certFile := writePEM(t, dir, "client.pem", "CERTIFICATE", clientCert.Raw)
+	keyFile := writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", marshalKey(t, clientKey))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+
+	server := httptest.NewUnstartedServer(accrualStub(t, func(r *http.Request) bool {
+		return r.TLS != nil && len(r.TLS.PeerCertificates) > 0 && r.TLS.PeerCertificates[0].Subject.CommonName == "gophermart"
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client, err := NewClient(ClientConfig{BaseURL: server.URL, CertFile: certFile, KeyFile: keyFile, CAFile: caFile})
+	require.NoError(t, err)
+
+	acc, err := checkOrder(client, server.URL, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, "12345678903", acc.OrderNumber)
+
+	withoutCert, err := NewClient(ClientConfig{BaseURL: server.URL, CAFile: caFile})
+	require.NoError(t, err)
+
+	_, err = checkOrder(withoutCert, server.URL, "12345678903")
+	assert.Error(t, err)
+
+	_, err = NewClient(ClientConfig{BaseURL: "http://accrual:8080", CertFile: certFile, KeyFile: keyFile, CAFile: caFile})
+	assert.ErrorIs(t, err, ErrTLSRequiresHTTPS)
+}
+
+func TestNewTLSConfig_InvalidCA(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+
+	_, err := NewTLSConfig("", "", caFile)
+	assert.ErrorIs(t, err, ErrInvalidCACertificate)
+}
+
+func newCertificate(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, name string, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, key
+}
+
+func marshalKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return der
+}
+
+func writePEM(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+
+	return path
+}